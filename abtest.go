@@ -0,0 +1,172 @@
+package spaserver
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// abControl is the A/B test group served from the primary filesystem.
+const abControl = "control"
+
+// abCookieMaxAge is how long a client stays in its assigned A/B group.
+const abCookieMaxAge = 30 * 24 * time.Hour
+
+// WithABVariant serves an A/B test. The cookie cookieName holds the group of
+// each client: a key of variants, served from that filesystem, or
+// "control", served from the primary filesystem like any unknown value.
+// Clients without the cookie are assigned a group at random, with equal
+// weights unless WithABWeights is used, and the request that sets the
+// cookie is already served from the assigned group. Responses carry
+// Vary: Cookie. Multiple calls add variants.
+func WithABVariant(cookieName string, variants map[string]fs.FS) Option {
+	return func(c *Config) {
+		c.ABCookie = cookieName
+		if c.ABVariants == nil {
+			c.ABVariants = make(map[string]fs.FS, len(variants))
+		}
+		for name, fsys := range variants {
+			c.ABVariants[name] = fsys
+		}
+	}
+}
+
+// WithABWeights sets the relative weights of the A/B groups assigned to new
+// clients, keyed by variant name or "control". Groups without a weight are
+// never assigned. Multiple calls accumulate.
+func WithABWeights(weights map[string]int) Option {
+	return func(c *Config) {
+		if c.ABWeights == nil {
+			c.ABWeights = make(map[string]int, len(weights))
+		}
+		for group, weight := range weights {
+			c.ABWeights[group] = weight
+		}
+	}
+}
+
+// abTest assigns clients to groups and holds the sites of the variants.
+type abTest struct {
+	cookie  string
+	groups  []string // in assignment order
+	weights []int    // weights of groups
+	total   int
+	sites   map[string]*site
+}
+
+// newABTest returns the A/B test of cfg, or nil if none is configured.
+func newABTest(cfg Config) *abTest {
+	if cfg.ABCookie == "" || len(cfg.ABVariants) == 0 {
+		return nil
+	}
+
+	ab := &abTest{cookie: cfg.ABCookie, sites: make(map[string]*site, len(cfg.ABVariants))}
+	groups := []string{abControl}
+	for name := range cfg.ABVariants {
+		groups = append(groups, name)
+	}
+	slices.Sort(groups[1:])
+	for _, group := range groups {
+		weight := 1
+		if cfg.ABWeights != nil {
+			weight = max(cfg.ABWeights[group], 0)
+		}
+		if weight > 0 {
+			ab.groups = append(ab.groups, group)
+			ab.weights = append(ab.weights, weight)
+			ab.total += weight
+		}
+	}
+	return ab
+}
+
+// load loads the site of each variant with h.
+func (ab *abTest) load(h *Handler) error {
+	var errs []error
+	for name, fsys := range h.cfg.ABVariants {
+		s, err := h.load(fsys)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("a/b variant %q: %w", name, err))
+		}
+		ab.sites[name] = s
+	}
+	return errors.Join(errs...)
+}
+
+// assign returns a random group according to the weights.
+func (ab *abTest) assign() string {
+	if ab.total == 0 {
+		return abControl
+	}
+	n := rand.IntN(ab.total)
+	for i, weight := range ab.weights {
+		if n < weight {
+			return ab.groups[i]
+		}
+		n -= weight
+	}
+	return abControl
+}
+
+// site returns the site of the client's group, assigning a group and
+// setting the cookie for new clients. primary serves the control group.
+func (ab *abTest) site(w http.ResponseWriter, r *http.Request, primary *site) *site {
+	w.Header().Add("Vary", "Cookie")
+
+	var group string
+	if c, err := r.Cookie(ab.cookie); err == nil {
+		group = c.Value
+	} else {
+		group = ab.assign()
+		http.SetCookie(w, &http.Cookie{
+			Name:     ab.cookie,
+			Value:    group,
+			Path:     "/",
+			MaxAge:   int(abCookieMaxAge / time.Second),
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	if s, ok := ab.sites[group]; ok {
+		return s
+	}
+	return primary
+}
+
+// validateABTest reports an A/B test configuration that cannot be served.
+func validateABTest(cfg Config) []error {
+	var errs []error
+	if len(cfg.ABVariants) > 0 && cfg.ABCookie == "" {
+		errs = append(errs, errors.New("ab_cookie: required by a/b variants"))
+	}
+	if cfg.ABCookie != "" && !validHeaderName(cfg.ABCookie) {
+		errs = append(errs, fmt.Errorf("ab_cookie: invalid cookie name %q", cfg.ABCookie))
+	}
+	for name, fsys := range cfg.ABVariants {
+		if name == abControl || name == "" || !validHeaderName(name) {
+			errs = append(errs, fmt.Errorf("ab_variants: invalid variant name %q", name))
+		}
+		if fsys == nil {
+			errs = append(errs, fmt.Errorf("ab_variants: %q: nil filesystem", name))
+		}
+	}
+	total := 0
+	for group, weight := range cfg.ABWeights {
+		if _, ok := cfg.ABVariants[group]; !ok && group != abControl {
+			errs = append(errs, fmt.Errorf("ab_weights: unknown group %q", group))
+		}
+		if weight < 0 {
+			errs = append(errs, fmt.Errorf("ab_weights: %q: must not be negative", group))
+		}
+		total += max(weight, 0)
+	}
+	if cfg.ABWeights != nil && total == 0 {
+		errs = append(errs, errors.New("ab_weights: no group has a positive weight"))
+	}
+	return errs
+}
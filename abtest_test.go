@@ -0,0 +1,121 @@
+package spaserver
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeWithABVariant(t *testing.T) {
+	primary := fstest.MapFS{
+		"index.html": {Data: []byte("index a")},
+		"js/app.js":  {Data: []byte("app a")},
+	}
+	variants := map[string]fs.FS{
+		"b": fstest.MapFS{
+			"index.html": {Data: []byte("index b")},
+			"js/app.js":  {Data: []byte("app b")},
+		},
+	}
+
+	tt := []struct {
+		name      string
+		opts      []Option
+		cookie    string
+		path      string
+		body      string
+		setCookie string
+	}{
+		{name: "variant cookie", cookie: "b", path: "/app/route", body: "index b"},
+		{name: "variant cookie static", cookie: "b", path: "/js/app.js", body: "app b"},
+		{name: "control cookie", cookie: "control", path: "/js/app.js", body: "app a"},
+		{name: "unknown cookie", cookie: "z", path: "/", body: "index a"},
+		{name: "assigned variant", opts: []Option{WithABWeights(map[string]int{"b": 1})}, path: "/", body: "index b", setCookie: "b"},
+		{name: "assigned control", opts: []Option{WithABWeights(map[string]int{"control": 1, "b": 0})}, path: "/js/app.js", body: "app a", setCookie: "control"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := New(primary, append([]Option{WithABVariant("ab", variants)}, tc.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+tc.path, nil)
+			if tc.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: "ab", Value: tc.cookie})
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Body.String(); got != tc.body {
+				t.Errorf("body expected: %q, got: %q", tc.body, got)
+			}
+			if got := w.Header().Get("Vary"); !strings.Contains(got, "Cookie") {
+				t.Errorf("Vary expected to contain Cookie, got: %q", got)
+			}
+
+			cookies := w.Result().Cookies()
+			if tc.setCookie == "" {
+				if len(cookies) != 0 {
+					t.Errorf("cookies expected none, got: %v", cookies)
+				}
+				return
+			}
+			if len(cookies) != 1 || cookies[0].Name != "ab" || cookies[0].Value != tc.setCookie {
+				t.Fatalf("cookie expected: ab=%s, got: %v", tc.setCookie, cookies)
+			}
+			if cookies[0].Path != "/" || cookies[0].MaxAge <= 0 || cookies[0].SameSite != http.SameSiteLaxMode {
+				t.Errorf("cookie attributes unexpected: %v", cookies[0])
+			}
+		})
+	}
+}
+
+func TestABTestAssignWeights(t *testing.T) {
+	ab := newABTest(Config{
+		ABCookie:   "ab",
+		ABVariants: map[string]fs.FS{"b": fstest.MapFS{}, "c": fstest.MapFS{}},
+		ABWeights:  map[string]int{"control": 1, "b": 3},
+	})
+
+	counts := make(map[string]int)
+	for range 4000 {
+		counts[ab.assign()]++
+	}
+	if counts["c"] != 0 {
+		t.Errorf("group c expected never assigned, got: %d", counts["c"])
+	}
+	if counts["control"] < 700 || counts["control"] > 1300 || counts["b"] < 2700 || counts["b"] > 3300 {
+		t.Errorf("assignments expected about 1000 control and 3000 b, got: %v", counts)
+	}
+}
+
+func TestABTestValidation(t *testing.T) {
+	fsys := fstest.MapFS{"index.html": {Data: []byte("index.html")}}
+
+	tt := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{name: "control variant", opts: []Option{WithABVariant("ab", map[string]fs.FS{"control": fsys})}, want: `ab_variants: invalid variant name "control"`},
+		{name: "nil filesystem", opts: []Option{WithABVariant("ab", map[string]fs.FS{"b": nil})}, want: `ab_variants: "b": nil filesystem`},
+		{name: "invalid cookie", opts: []Option{WithABVariant("a b", map[string]fs.FS{"b": fsys})}, want: `ab_cookie: invalid cookie name "a b"`},
+		{name: "unknown weight", opts: []Option{WithABVariant("ab", map[string]fs.FS{"b": fsys}), WithABWeights(map[string]int{"c": 1})}, want: `ab_weights: unknown group "c"`},
+		{name: "zero weights", opts: []Option{WithABVariant("ab", map[string]fs.FS{"b": fsys}), WithABWeights(map[string]int{"b": 0})}, want: "ab_weights: no group has a positive weight"},
+		{name: "variant without index", opts: []Option{WithABVariant("ab", map[string]fs.FS{"b": fstest.MapFS{}})}, want: `a/b variant "b": index.html not found`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(fsys, tc.opts...)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("error expected to contain: %q, got: %v", tc.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package spaserver
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// defaultBlockedExtensions are blocked by WithBlockedExtensions without
+// arguments: sources, source maps and secrets that do not belong in a
+// production build.
+var defaultBlockedExtensions = []string{".ts", ".map", ".env", ".secret", ".key", ".pem"}
+
+// WithBlockedExtensions answers requests for files with any of exts with
+// 403 Forbidden, whether or not the file exists, so that the response
+// does not reveal which files are present. An extension matches the end
+// of the file name case-insensitively, so ".js.map" is allowed as well as
+// ".map". Without arguments, .ts, .map, .env, .secret, .key and .pem are
+// blocked. Multiple calls accumulate.
+func WithBlockedExtensions(exts ...string) Option {
+	if len(exts) == 0 {
+		exts = defaultBlockedExtensions
+	}
+	return func(c *Config) {
+		c.BlockedExtensions = append(c.BlockedExtensions, exts...)
+	}
+}
+
+// WithSourceMaps controls whether JavaScript and CSS source maps (.js.map
+// and .css.map files) are served. When enabled is false, as recommended
+// for production, they are answered with 403 Forbidden like
+// WithBlockedExtensions; when true they are served, even if
+// WithBlockedExtensions blocks .map files. Source maps are served by
+// default.
+func WithSourceMaps(enabled bool) Option {
+	return func(c *Config) {
+		c.SourceMaps = &enabled
+	}
+}
+
+// sourceMapExts are the extensions controlled by WithSourceMaps.
+var sourceMapExts = []string{".js.map", ".css.map"}
+
+// blocked reports whether the file name must not be served.
+func blocked(cfg Config, name string) bool {
+	lower := strings.ToLower(name)
+	if cfg.SourceMaps != nil {
+		for _, ext := range sourceMapExts {
+			if strings.HasSuffix(lower, ext) {
+				return !*cfg.SourceMaps
+			}
+		}
+	}
+	for _, ext := range cfg.BlockedExtensions {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultMaxPathLength is the URL path length limit of WithMaxPathLength.
+const defaultMaxPathLength = 4096
+
+// WithMaxPathLength answers requests whose URL path is longer than n bytes
+// with 400 Bad Request before the path is cleaned or matched, so that
+// oversized paths, such as thousands of ../ segments, cannot make the
+// handler do work proportional to their length. The default is 4096.
+func WithMaxPathLength(n int) Option {
+	return func(c *Config) {
+		c.MaxPathLength = n
+	}
+}
+
+// WithBlockHiddenFiles answers requests for paths with any segment
+// starting with a dot, such as /.env, /.git/config or
+// /css/.hidden/main.css, with 404 Not Found instead of serving the file or
+// the index page. Hidden files are rarely intended as SPA assets and are
+// often configuration files included in the build output by mistake.
+// /.well-known/ paths (RFC 8615) are exempt, but hidden files below them
+// are not.
+func WithBlockHiddenFiles() Option {
+	return func(c *Config) {
+		c.BlockHiddenFiles = true
+	}
+}
+
+// WithForbidDirectoryAccess answers requests for directories in the file
+// system, such as /css/, with 403 Forbidden instead of the index page, so
+// clients cannot probe which directories exist. The root path / still
+// serves the index page.
+func WithForbidDirectoryAccess() Option {
+	return func(c *Config) {
+		c.ForbidDirectoryAccess = true
+	}
+}
+
+// wellKnownPath is the RFC 8615 prefix of well-known URIs.
+const wellKnownPath = "/.well-known/"
+
+// WithWellKnownPassthrough answers requests under /.well-known/ (RFC 8615)
+// that do not match a file with a plain 404 Not Found, instead of the index
+// page or the WithNotFoundPage page. Security scanners and app link
+// verification, e.g. /.well-known/assetlinks.json, otherwise mistake the
+// HTML page for the resource. Files that exist are served as usual.
+func WithWellKnownPassthrough() Option {
+	return func(c *Config) {
+		c.WellKnownPassthrough = true
+	}
+}
+
+// wellKnown reports whether the clean URL path upath is /.well-known or
+// below it.
+func wellKnown(upath string) bool {
+	return upath+"/" == wellKnownPath || strings.HasPrefix(upath, wellKnownPath)
+}
+
+// hidden reports whether the clean URL path upath has a segment starting
+// with a dot, other than a leading .well-known segment.
+func hidden(upath string) bool {
+	if wellKnown(upath) {
+		upath = strings.TrimPrefix(upath, wellKnownPath[:len(wellKnownPath)-1])
+	}
+	for _, seg := range strings.Split(upath, "/") {
+		if strings.HasPrefix(seg, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// WithContainSymlinks answers requests for files in an os.DirFS
+// filesystem whose symlinks resolve outside the filesystem's root
+// directory with 403 Forbidden, instead of following them. Other
+// filesystems, such as embed.FS, cannot contain symlinks and are served
+// unchanged.
+func WithContainSymlinks() Option {
+	return func(c *Config) {
+		c.ContainSymlinks = true
+	}
+}
+
+// dirFSRoot returns the directory of fsys if it was created by os.DirFS.
+func dirFSRoot(fsys fs.FS) (string, bool) {
+	v := reflect.ValueOf(fsys)
+	if v.Kind() != reflect.String || v.Type() != reflect.TypeOf(os.DirFS("")) {
+		return "", false
+	}
+	return v.String(), true
+}
+
+// resolveRoot returns the absolute path of dir with symlinks resolved.
+func resolveRoot(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
+
+// contained reports whether the file name resolves to a path within the
+// site's root directory. It always does when the root is unknown, and for
+// files that do not exist, so that opening them reports the missing file.
+func (s *site) contained(name string) bool {
+	if s.root == "" {
+		return true
+	}
+	resolved, err := filepath.EvalSymlinks(filepath.Join(s.root, filepath.FromSlash(name)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return true
+	}
+	if err != nil {
+		return false
+	}
+	prefix := s.root
+	if !strings.HasSuffix(prefix, string(filepath.Separator)) {
+		prefix += string(filepath.Separator)
+	}
+	return resolved == s.root || strings.HasPrefix(resolved, prefix)
+}
+
+// checkContained returns an error wrapping fs.ErrPermission if the file
+// name resolves outside the site's root directory. Every read of a file
+// from the site's filesystem, including index and not-found pages, checks
+// it first.
+func (s *site) checkContained(op, name string) error {
+	if !s.contained(name) {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrPermission}
+	}
+	return nil
+}
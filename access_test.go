@@ -0,0 +1,328 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestServeWithBlockedExtensions(t *testing.T) {
+	tt := []struct {
+		name   string
+		opts   []Option
+		url    string
+		status int
+	}{
+		{name: "existing ts file", opts: []Option{WithBlockedExtensions()}, url: "/js/app.ts", status: http.StatusForbidden},
+		{name: "missing ts file", opts: []Option{WithBlockedExtensions()}, url: "/js/missing.ts", status: http.StatusForbidden},
+		{name: "missing env file", opts: []Option{WithBlockedExtensions()}, url: "/.env", status: http.StatusForbidden},
+		{name: "uppercase extension", opts: []Option{WithBlockedExtensions()}, url: "/KEYS/server.PEM", status: http.StatusForbidden},
+		{name: "compound extension", opts: []Option{WithBlockedExtensions(".CSS.map")}, url: "/css/main.css.map", status: http.StatusForbidden},
+		{name: "custom list allows others", opts: []Option{WithBlockedExtensions("sql")}, url: "/js/app.ts", status: http.StatusOK},
+		{name: "not blocked", opts: []Option{WithBlockedExtensions()}, url: "/css/main.css", status: http.StatusOK},
+		{name: "no option", url: "/js/app.ts", status: http.StatusOK},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Serve(os.DirFS("testdata"), tc.opts...)
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+tc.url, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("status expected: %d, got: %d", tc.status, w.Code)
+			}
+		})
+	}
+}
+
+func TestServeWithSourceMaps(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":       {Data: []byte("index.html")},
+		"js/app.js.map":    {Data: []byte("{}")},
+		"css/main.css.map": {Data: []byte("{}")},
+		"data/world.map":   {Data: []byte("map")},
+	}
+
+	tt := []struct {
+		name   string
+		opts   []Option
+		url    string
+		status int
+	}{
+		{name: "disabled js", opts: []Option{WithSourceMaps(false)}, url: "/js/app.js.map", status: http.StatusForbidden},
+		{name: "disabled css", opts: []Option{WithSourceMaps(false)}, url: "/css/main.css.map", status: http.StatusForbidden},
+		{name: "disabled missing", opts: []Option{WithSourceMaps(false)}, url: "/js/missing.js.map", status: http.StatusForbidden},
+		{name: "disabled other map", opts: []Option{WithSourceMaps(false)}, url: "/data/world.map", status: http.StatusOK},
+		{name: "enabled", opts: []Option{WithSourceMaps(true)}, url: "/js/app.js.map", status: http.StatusOK},
+		{name: "enabled over blocked extensions", opts: []Option{WithBlockedExtensions(), WithSourceMaps(true)}, url: "/js/app.js.map", status: http.StatusOK},
+		{name: "blocked extensions still apply", opts: []Option{WithBlockedExtensions(), WithSourceMaps(true)}, url: "/data/world.map", status: http.StatusForbidden},
+		{name: "default", url: "/css/main.css.map", status: http.StatusOK},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Serve(fsys, tc.opts...)
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+tc.url, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("status expected: %d, got: %d", tc.status, w.Code)
+			}
+		})
+	}
+}
+
+func TestServeWithBlockHiddenFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":                   {Data: []byte("index.html")},
+		".env":                         {Data: []byte("SECRET=1")},
+		".git/config":                  {Data: []byte("[core]")},
+		"css/.hidden/main.css":         {Data: []byte("body{}")},
+		"css/main.css":                 {Data: []byte("body{}")},
+		".well-known/security.txt":     {Data: []byte("Contact: mailto:security@example.com")},
+		".well-known/.secret/file.txt": {Data: []byte("secret")},
+	}
+
+	tt := []struct {
+		name   string
+		opts   []Option
+		url    string
+		status int
+		body   string
+	}{
+		{name: "dotfile", opts: []Option{WithBlockHiddenFiles()}, url: "/.env", status: http.StatusNotFound, body: "404 Page Not Found\n"},
+		{name: "hidden directory", opts: []Option{WithBlockHiddenFiles()}, url: "/.git/config", status: http.StatusNotFound, body: "404 Page Not Found\n"},
+		{name: "nested hidden directory", opts: []Option{WithBlockHiddenFiles()}, url: "/css/.hidden/main.css", status: http.StatusNotFound, body: "404 Page Not Found\n"},
+		{name: "missing dotfile", opts: []Option{WithBlockHiddenFiles()}, url: "/.htpasswd", status: http.StatusNotFound, body: "404 Page Not Found\n"},
+		{name: "missing hidden route", opts: []Option{WithBlockHiddenFiles()}, url: "/app/.cache", status: http.StatusNotFound, body: "404 Page Not Found\n"},
+		{name: "visible file", opts: []Option{WithBlockHiddenFiles()}, url: "/css/main.css", status: http.StatusOK, body: "body{}"},
+		{name: "dot inside name", opts: []Option{WithBlockHiddenFiles()}, url: "/app/v1.2", status: http.StatusOK, body: "index.html"},
+		{name: "well-known", opts: []Option{WithBlockHiddenFiles()}, url: "/.well-known/security.txt", status: http.StatusOK, body: "Contact: mailto:security@example.com"},
+		{name: "hidden below well-known", opts: []Option{WithBlockHiddenFiles()}, url: "/.well-known/.secret/file.txt", status: http.StatusNotFound, body: "404 Page Not Found\n"},
+		{name: "well-known lookalike", opts: []Option{WithBlockHiddenFiles()}, url: "/.well-knownx", status: http.StatusNotFound, body: "404 Page Not Found\n"},
+		{name: "no option", url: "/.git/config", status: http.StatusOK, body: "[core]"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Serve(fsys, tc.opts...)
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+tc.url, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("status expected: %d, got: %d", tc.status, w.Code)
+			}
+			if body := w.Body.String(); body != tc.body {
+				t.Errorf("body expected: %q, got: %q", tc.body, body)
+			}
+		})
+	}
+}
+
+func TestServeWithForbidDirectoryAccess(t *testing.T) {
+	tt := []struct {
+		name   string
+		opts   []Option
+		url    string
+		status int
+	}{
+		{name: "directory", opts: []Option{WithForbidDirectoryAccess()}, url: "/css/", status: http.StatusForbidden},
+		{name: "directory without slash", opts: []Option{WithForbidDirectoryAccess()}, url: "/css", status: http.StatusForbidden},
+		{name: "root", opts: []Option{WithForbidDirectoryAccess()}, url: "/", status: http.StatusOK},
+		{name: "file", opts: []Option{WithForbidDirectoryAccess()}, url: "/css/main.css", status: http.StatusOK},
+		{name: "route", opts: []Option{WithForbidDirectoryAccess()}, url: "/app/settings", status: http.StatusOK},
+		{name: "no option", url: "/css/", status: http.StatusOK},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Serve(os.DirFS("testdata"), tc.opts...)
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+tc.url, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("status expected: %d, got: %d", tc.status, w.Code)
+			}
+		})
+	}
+}
+
+func TestServeWithWellKnownPassthrough(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":                  {Data: []byte("index.html")},
+		"404.html":                    {Data: []byte("404.html")},
+		".well-known/assetlinks.json": {Data: []byte("[]")},
+	}
+
+	tt := []struct {
+		name   string
+		opts   []Option
+		url    string
+		status int
+		body   string
+	}{
+		{name: "existing file", opts: []Option{WithWellKnownPassthrough()}, url: "/.well-known/assetlinks.json", status: http.StatusOK, body: "[]"},
+		{name: "missing file", opts: []Option{WithWellKnownPassthrough()}, url: "/.well-known/security.txt", status: http.StatusNotFound, body: "404 Page Not Found\n"},
+		{name: "missing nested file", opts: []Option{WithWellKnownPassthrough()}, url: "/.well-known/acme-challenge/token", status: http.StatusNotFound, body: "404 Page Not Found\n"},
+		{name: "directory", opts: []Option{WithWellKnownPassthrough()}, url: "/.well-known/", status: http.StatusNotFound, body: "404 Page Not Found\n"},
+		{name: "not found page skipped", opts: []Option{WithWellKnownPassthrough(), WithNotFoundPage("404.html")}, url: "/.well-known/security.txt", status: http.StatusNotFound, body: "404 Page Not Found\n"},
+		{name: "lookalike route", opts: []Option{WithWellKnownPassthrough()}, url: "/.well-knownx", status: http.StatusOK, body: "index.html"},
+		{name: "other route", opts: []Option{WithWellKnownPassthrough()}, url: "/some/route", status: http.StatusOK, body: "index.html"},
+		{name: "no option", url: "/.well-known/security.txt", status: http.StatusOK, body: "index.html"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Serve(fsys, tc.opts...)
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+tc.url, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("status expected: %d, got: %d", tc.status, w.Code)
+			}
+			if body := w.Body.String(); body != tc.body {
+				t.Errorf("body expected: %q, got: %q", tc.body, body)
+			}
+		})
+	}
+}
+
+func TestServeWithContainSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "dist")
+	outside := filepath.Join(dir, "outside")
+	for _, d := range []string{root, outside, filepath.Join(root, "docs")} {
+		if err := os.Mkdir(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		filepath.Join(root, "index.html"):    "index.html",
+		filepath.Join(root, "app.js"):        "app",
+		filepath.Join(outside, "secret.txt"): "secret",
+		filepath.Join(outside, "nested.txt"): "nested",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(name, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"secret.txt":      filepath.Join("..", "outside", "secret.txt"),
+		"linked":          outside,
+		"alias.js":        "app.js",
+		"docs/index.html": filepath.Join(outside, "secret.txt"),
+		"404.html":        filepath.Join(outside, "secret.txt"),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+
+	tt := []struct {
+		name   string
+		opts   []Option
+		url    string
+		status int
+		body   string
+	}{
+		{name: "escaping file", opts: []Option{WithContainSymlinks()}, url: "/secret.txt", status: http.StatusForbidden},
+		{name: "escaping directory", opts: []Option{WithContainSymlinks()}, url: "/linked/nested.txt", status: http.StatusForbidden},
+		{name: "link within root", opts: []Option{WithContainSymlinks()}, url: "/alias.js", status: http.StatusOK, body: "app"},
+		{name: "regular file", opts: []Option{WithContainSymlinks()}, url: "/app.js", status: http.StatusOK, body: "app"},
+		{name: "missing file", opts: []Option{WithContainSymlinks()}, url: "/missing", status: http.StatusOK, body: "index.html"},
+		{name: "no option", url: "/secret.txt", status: http.StatusOK, body: "secret"},
+		{name: "escaping directory index", opts: []Option{WithContainSymlinks(), WithMPAMode()}, url: "/docs/", status: http.StatusForbidden},
+		{name: "escaping not found page", opts: []Option{WithContainSymlinks(), WithNotFoundPage("404.html")}, url: "/missing", status: http.StatusOK, body: "index.html"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Serve(os.DirFS(root), tc.opts...)
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+tc.url, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("status expected: %d, got: %d", tc.status, w.Code)
+			}
+			if tc.body != "" && w.Body.String() != tc.body {
+				t.Errorf("body expected: %q, got: %q", tc.body, w.Body.String())
+			}
+			if tc.body != "secret" && strings.Contains(w.Body.String(), "secret") {
+				t.Errorf("body expected not to contain the escaping file, got: %q", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestServeWithContainSymlinksOtherFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("index.html")},
+		"app.js":     {Data: []byte("app")},
+	}
+	h := Serve(fsys, WithContainSymlinks())
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/app.js", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("status expected: %d, got: %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestServeWithMaxPathLength(t *testing.T) {
+	tt := []struct {
+		name   string
+		opts   []Option
+		path   string
+		status int
+	}{
+		{name: "default limit", path: "/" + strings.Repeat("a", defaultMaxPathLength-1), status: http.StatusOK},
+		{name: "over default limit", path: "/" + strings.Repeat("a", defaultMaxPathLength), status: http.StatusBadRequest},
+		{name: "custom limit", opts: []Option{WithMaxPathLength(8)}, path: "/js/app.js", status: http.StatusBadRequest},
+		{name: "within custom limit", opts: []Option{WithMaxPathLength(8)}, path: "/app", status: http.StatusOK},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Serve(fstest.MapFS{"index.html": {Data: []byte("index.html")}}, tc.opts...)
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+			r.URL.Path = tc.path
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("status expected: %d, got: %d", tc.status, w.Code)
+			}
+		})
+	}
+}
+
+func TestServeWithMaxPathLengthLongPath(t *testing.T) {
+	h := Serve(os.DirFS("testdata"))
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+	r.URL.Path = "/" + strings.Repeat("a/../", 100*1024/5)
+	w := httptest.NewRecorder()
+
+	start := time.Now()
+	h.ServeHTTP(w, r)
+	if d := time.Since(start); d > 100*time.Millisecond {
+		t.Errorf("expected rejection within 100ms, took %v", d)
+	}
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status expected: %d, got: %d", http.StatusBadRequest, w.Code)
+	}
+}
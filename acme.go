@@ -0,0 +1,33 @@
+package spaserver
+
+import (
+	"net/http"
+	"strings"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// acmeChallengePath is the path prefix of ACME HTTP-01 challenges
+// (RFC 8555, section 8.3).
+const acmeChallengePath = "/.well-known/acme-challenge/"
+
+// WithACMEChallenge answers ACME HTTP-01 challenges under
+// /.well-known/acme-challenge/ with manager, so certificates can be issued
+// by Let's Encrypt while the handler serves plain HTTP. Without it, the
+// challenge request falls back to index.html and validation fails.
+// Challenges bypass IP filtering and basic auth, which would otherwise
+// block the certificate authority's validation servers.
+func WithACMEChallenge(manager *autocert.Manager) Option {
+	return func(c *Config) {
+		c.ACMEManager = manager
+	}
+}
+
+// acmeHandler returns the handler of an ACME challenge request, or nil if
+// r is not one.
+func acmeHandler(cfg Config, r *http.Request) http.Handler {
+	if cfg.ACMEManager == nil || !strings.HasPrefix(r.URL.Path, acmeChallengePath) {
+		return nil
+	}
+	return cfg.ACMEManager.HTTPHandler(nil)
+}
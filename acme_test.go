@@ -0,0 +1,63 @@
+package spaserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+func TestServeWithACMEChallenge(t *testing.T) {
+	cache := autocert.DirCache(t.TempDir())
+	if err := cache.Put(context.Background(), "token123+http-01", []byte("token123.keyauth")); err != nil {
+		t.Fatal(err)
+	}
+	manager := &autocert.Manager{Prompt: autocert.AcceptTOS, Cache: cache}
+
+	fsys := fstest.MapFS{"index.html": {Data: []byte("index.html")}}
+	h := Serve(fsys,
+		WithACMEChallenge(manager),
+		WithIPAllowlist("10.0.0.0/8"),
+		WithBasicAuthFunc("admin", func(user, pass string) (bool, error) { return false, nil }),
+	)
+
+	tt := []struct {
+		name   string
+		url    string
+		status int
+		body   string
+	}{
+		{name: "known token", url: "/.well-known/acme-challenge/token123", status: http.StatusOK, body: "token123.keyauth"},
+		{name: "unknown token", url: "/.well-known/acme-challenge/missing", status: http.StatusNotFound},
+		{name: "other path", url: "/some/route", status: http.StatusForbidden},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+tc.url, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("status expected: %d, got: %d", tc.status, w.Code)
+			}
+			if body := w.Body.String(); body == "index.html" || tc.body != "" && body != tc.body {
+				t.Errorf("body expected: %q, got: %q", tc.body, body)
+			}
+		})
+	}
+}
+
+func TestServeWithoutACMEChallenge(t *testing.T) {
+	h := Serve(fstest.MapFS{"index.html": {Data: []byte("index.html")}})
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/.well-known/acme-challenge/token123", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if body := w.Body.String(); body != "index.html" {
+		t.Errorf("body expected to fall back to index.html, got: %q", body)
+	}
+}
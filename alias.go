@@ -0,0 +1,97 @@
+package spaserver
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Alias serves requests for the URL path From as if they were for To.
+type Alias struct {
+	From string `json:"from" yaml:"from"`
+	To   string `json:"to" yaml:"to"`
+}
+
+// WithAlias serves requests for the path from with the file at the path
+// to, e.g. WithAlias("/favicon.ico", "/assets/icons/favicon.ico"), without
+// a redirect: the URL in the browser stays the same. Both paths must be
+// clean and absolute. Aliases are evaluated in the order given, and the
+// first alias of a path wins; an alias whose target is itself aliased is
+// followed, and circular aliases are reported by New. Everything after
+// the rewrite, including WithBlockHiddenFiles, WithBlockedExtensions and
+// redirects, sees the target path. Multiple calls accumulate.
+func WithAlias(from, to string) Option {
+	return func(c *Config) {
+		c.Aliases = append(c.Aliases, Alias{From: from, To: to})
+	}
+}
+
+// newAliases maps the source path of each alias to its target, keeping
+// the first alias of each path.
+func newAliases(aliases []Alias) map[string]string {
+	if len(aliases) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(aliases))
+	for _, a := range aliases {
+		if _, ok := m[a.From]; !ok {
+			m[a.From] = a.To
+		}
+	}
+	return m
+}
+
+// resolveAlias returns the path the clean URL path upath is served from.
+// Chains are followed for at most as many steps as there are aliases, so
+// a circular configuration cannot loop forever.
+func resolveAlias(aliases map[string]string, upath string) string {
+	for range len(aliases) {
+		to, ok := aliases[upath]
+		if !ok {
+			break
+		}
+		upath = to
+	}
+	return upath
+}
+
+// validateAliases reports paths that are not clean and absolute, and
+// circular aliases.
+func validateAliases(aliases []Alias) []error {
+	var errs []error
+	for _, a := range aliases {
+		for _, p := range []string{a.From, a.To} {
+			if !strings.HasPrefix(p, "/") || path.Clean(p) != p {
+				errs = append(errs, fmt.Errorf("aliases: %q must be a clean absolute path", p))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	m := newAliases(aliases)
+	reported := make(map[string]bool)
+	for _, a := range aliases {
+		walk := []string{a.From}
+		for p := m[a.From]; ; p = m[p] {
+			if i := slices.Index(walk, p); i >= 0 {
+				loop := append(walk[i:], p)
+				if !reported[loop[0]] {
+					for _, q := range loop {
+						reported[q] = true
+					}
+					errs = append(errs, errors.New("aliases: loop: "+strings.Join(loop, " -> ")))
+				}
+				break
+			}
+			if _, ok := m[p]; !ok {
+				break
+			}
+			walk = append(walk, p)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,109 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeWithAlias(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":               {Data: []byte("index.html")},
+		"assets/icons/favicon.ico": {Data: []byte("favicon")},
+		"assets/icons/touch.png":   {Data: []byte("touch")},
+		"config/.env":              {Data: []byte("SECRET=1")},
+		"favicon.ico":              {Data: []byte("shadowed")},
+	}
+	h, err := New(fsys,
+		WithAlias("/favicon.ico", "/assets/icons/favicon.ico"),
+		WithAlias("/apple-touch-icon.png", "/icon.png"),
+		WithAlias("/icon.png", "/assets/icons/touch.png"),
+		WithAlias("/icon.png", "/assets/icons/ignored.png"),
+		WithAlias("/env", "/config/.env"),
+		WithAlias("/missing", "/assets/missing.js"),
+		WithBlockHiddenFiles(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		name   string
+		url    string
+		status int
+		body   string
+	}{
+		{name: "alias", url: "/favicon.ico", status: http.StatusOK, body: "favicon"},
+		{name: "alias query", url: "/favicon.ico?v=2", status: http.StatusOK, body: "favicon"},
+		{name: "unclean path", url: "/assets/../favicon.ico", status: http.StatusOK, body: "favicon"},
+		{name: "chained alias", url: "/apple-touch-icon.png", status: http.StatusOK, body: "touch"},
+		{name: "first alias wins", url: "/icon.png", status: http.StatusOK, body: "touch"},
+		{name: "blocked target", url: "/env", status: http.StatusNotFound},
+		{name: "missing target", url: "/missing", status: http.StatusOK, body: "index.html"},
+		{name: "target served directly", url: "/assets/icons/favicon.ico", status: http.StatusOK, body: "favicon"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+tc.url, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("status expected: %d, got: %d", tc.status, w.Code)
+			}
+			if tc.body != "" && w.Body.String() != tc.body {
+				t.Errorf("body expected: %q, got: %q", tc.body, w.Body.String())
+			}
+			if loc := w.Header().Get("Location"); loc != "" {
+				t.Errorf("expected no redirect, got Location: %q", loc)
+			}
+		})
+	}
+}
+
+func TestServeWithAliasValidation(t *testing.T) {
+	tt := []struct {
+		name string
+		opts []Option
+		err  string
+	}{
+		{name: "valid", opts: []Option{WithAlias("/a", "/b"), WithAlias("/b", "/c")}},
+		{name: "relative source", opts: []Option{WithAlias("a", "/b")}, err: `aliases: "a" must be a clean absolute path`},
+		{name: "unclean target", opts: []Option{WithAlias("/a", "/b/../c")}, err: `aliases: "/b/../c" must be a clean absolute path`},
+		{name: "self loop", opts: []Option{WithAlias("/a", "/a")}, err: "aliases: loop: /a -> /a"},
+		{name: "loop", opts: []Option{WithAlias("/a", "/b"), WithAlias("/b", "/a")}, err: "aliases: loop: /a -> /b -> /a"},
+		{name: "loop after chain", opts: []Option{WithAlias("/a", "/b"), WithAlias("/b", "/c"), WithAlias("/c", "/b")}, err: "aliases: loop: /b -> /c -> /b"},
+		{name: "shadowed alias", opts: []Option{WithAlias("/a", "/b"), WithAlias("/a", "/a")}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(fstest.MapFS{"index.html": {Data: []byte("index.html")}}, tc.opts...)
+			if tc.err == "" {
+				if err != nil {
+					t.Errorf("expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("error expected to contain %q, got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestServeWithAliasLoopUnvalidated(t *testing.T) {
+	h := ServeWithConfig(fstest.MapFS{"index.html": {Data: []byte("index.html")}}, Config{
+		Aliases: []Alias{{From: "/a", To: "/b"}, {From: "/b", To: "/a"}},
+	})
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/a", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("status expected: %d, got: %d", http.StatusOK, w.Code)
+	}
+}
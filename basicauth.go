@@ -0,0 +1,78 @@
+package spaserver
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// WithBasicAuth requires HTTP Basic authentication against credentials, a
+// map of user names to bcrypt password hashes. Unauthenticated requests
+// receive 401 Unauthorized with a WWW-Authenticate challenge for realm.
+// Health probes are not authenticated.
+func WithBasicAuth(realm string, credentials map[string]string) Option {
+	return func(c *Config) {
+		c.BasicAuthRealm = realm
+		c.BasicAuthCredentials = credentials
+		c.BasicAuthFunc = nil
+	}
+}
+
+// WithBasicAuthFunc is like WithBasicAuth but checks credentials with fn,
+// for example against a database. fn should compare secrets in constant
+// time. A non-nil error is logged and the request is rejected.
+func WithBasicAuthFunc(realm string, fn func(user, pass string) (bool, error)) Option {
+	return func(c *Config) {
+		c.BasicAuthRealm = realm
+		c.BasicAuthFunc = fn
+		c.BasicAuthCredentials = nil
+	}
+}
+
+// dummyHash returns the hash compared against when the user is unknown, so
+// a lookup miss takes as long as a wrong password and does not reveal
+// valid user names. It is computed on first use rather than at package
+// initialization, which would slow every program importing the package.
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("spaserver"), bcrypt.DefaultCost)
+	return hash
+})
+
+// basicAuthEnabled reports whether Basic authentication is configured.
+func (cfg Config) basicAuthEnabled() bool {
+	return cfg.BasicAuthFunc != nil || cfg.BasicAuthCredentials != nil
+}
+
+// authorize reports whether r carries valid Basic credentials.
+func (h *Handler) authorize(r *http.Request) bool {
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+
+	if h.cfg.BasicAuthFunc != nil {
+		valid, err := h.cfg.BasicAuthFunc(user, pass)
+		if err != nil {
+			h.cfg.Logger.Error("spaserver: basic auth check failed", "user", user, "error", err)
+			return false
+		}
+		return valid
+	}
+
+	hash, known := h.cfg.BasicAuthCredentials[user]
+	if !known {
+		hash = string(dummyHash())
+	}
+	// bcrypt compares in constant time, and the hash is always compared
+	match := bcrypt.CompareHashAndPassword([]byte(hash), []byte(pass)) == nil
+	return known && match
+}
+
+// serveUnauthorized responds 401 with a Basic challenge for the realm.
+func serveUnauthorized(cfg Config, w http.ResponseWriter) {
+	realm := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(cfg.BasicAuthRealm)
+	w.Header().Set("WWW-Authenticate", `Basic realm="`+realm+`", charset="UTF-8"`)
+	serveError(w, "401 Unauthorized", http.StatusUnauthorized)
+}
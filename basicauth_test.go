@@ -0,0 +1,96 @@
+package spaserver
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestServeWithBasicAuth(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{"index.html": {Data: []byte("index.html")}}
+	h, err := New(fsys,
+		WithBasicAuth(`Staging "preview"`, map[string]string{"alice": string(hash)}),
+		WithKubeProbes("/livez", "", ""),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		name       string
+		url        string
+		user, pass string
+		noAuth     bool
+		statusCode int
+	}{
+		{name: "valid credentials", url: "http://www.example.com/", user: "alice", pass: "s3cret", statusCode: 200},
+		{name: "wrong password", url: "http://www.example.com/", user: "alice", pass: "wrong", statusCode: 401},
+		{name: "unknown user", url: "http://www.example.com/", user: "mallory", pass: "s3cret", statusCode: 401},
+		{name: "no credentials", url: "http://www.example.com/", noAuth: true, statusCode: 401},
+		{name: "probe bypasses auth", url: "http://www.example.com/livez", noAuth: true, statusCode: 200},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			if !tc.noAuth {
+				r.SetBasicAuth(tc.user, tc.pass)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.statusCode {
+				t.Errorf("statusCode expected: %d, got: %d", tc.statusCode, w.Code)
+			}
+			wantChallenge := ""
+			if tc.statusCode == 401 {
+				wantChallenge = `Basic realm="Staging \"preview\"", charset="UTF-8"`
+			}
+			if got := w.Header().Get("WWW-Authenticate"); got != wantChallenge {
+				t.Errorf("WWW-Authenticate expected: %q, got: %q", wantChallenge, got)
+			}
+		})
+	}
+
+	if _, err := New(fsys, WithBasicAuth("x", map[string]string{"bob": "plaintext"})); err == nil {
+		t.Error("expected error for credentials that are not bcrypt hashes")
+	}
+}
+
+func TestServeWithBasicAuthFunc(t *testing.T) {
+	fsys := fstest.MapFS{"index.html": {Data: []byte("index.html")}}
+	h := Serve(fsys, WithBasicAuthFunc("app", func(user, pass string) (bool, error) {
+		if user == "db-down" {
+			return false, errors.New("connection refused")
+		}
+		return user == "alice" && pass == "s3cret", nil
+	}))
+
+	tt := []struct {
+		user, pass string
+		statusCode int
+	}{
+		{user: "alice", pass: "s3cret", statusCode: 200},
+		{user: "alice", pass: "wrong", statusCode: 401},
+		{user: "db-down", pass: "x", statusCode: 401},
+	}
+
+	for _, tc := range tt {
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+		r.SetBasicAuth(tc.user, tc.pass)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != tc.statusCode {
+			t.Errorf("%s:%s statusCode expected: %d, got: %d", tc.user, tc.pass, tc.statusCode, w.Code)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package spaserver
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// PerformanceBudget limits the total size of the files served, by
+// category. A zero limit is not enforced.
+type PerformanceBudget struct {
+	// MaxHTMLBytes limits the .html and .htm files.
+	MaxHTMLBytes int64 `json:"max_html_bytes,omitempty" yaml:"max_html_bytes,omitempty"`
+	// MaxJSBytes limits the .js, .mjs and .cjs files.
+	MaxJSBytes int64 `json:"max_js_bytes,omitempty" yaml:"max_js_bytes,omitempty"`
+	// MaxCSSBytes limits the .css files.
+	MaxCSSBytes int64 `json:"max_css_bytes,omitempty" yaml:"max_css_bytes,omitempty"`
+	// MaxTotalBytes limits all files.
+	MaxTotalBytes int64 `json:"max_total_bytes,omitempty" yaml:"max_total_bytes,omitempty"`
+	// SoftLimit logs a warning when the budget is exceeded instead of
+	// failing.
+	SoftLimit bool `json:"soft_limit,omitempty" yaml:"soft_limit,omitempty"`
+}
+
+// WithPerformanceBudget checks the size of the files in the filesystem
+// against budget when it is loaded, so that a deploy pipeline can reject a
+// build that grew too large, e.g.
+//
+//	WithPerformanceBudget(PerformanceBudget{MaxJSBytes: 500 << 10, MaxTotalBytes: 2 << 20})
+//
+// Sizes are summed over every file the handler would serve, uncompressed;
+// files refused by WithBlockedExtensions or WithSourceMaps are not
+// counted. When the budget is exceeded, New and Reload return an error,
+// or, with SoftLimit, a warning is logged and the filesystem is served.
+func WithPerformanceBudget(budget PerformanceBudget) Option {
+	return func(c *Config) {
+		c.PerformanceBudget = &budget
+	}
+}
+
+// budgetCategories maps file extensions to their budget category.
+var budgetCategories = map[string]string{
+	".html": "html",
+	".htm":  "html",
+	".js":   "js",
+	".mjs":  "js",
+	".cjs":  "js",
+	".css":  "css",
+}
+
+// checkPerformanceBudget reports the categories of fsys whose size
+// exceeds the budget.
+func checkPerformanceBudget(fsys fs.FS, cfg Config) error {
+	sizes := make(map[string]int64)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || blocked(cfg, name) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		sizes[budgetCategories[strings.ToLower(path.Ext(name))]] += info.Size()
+		sizes["total"] += info.Size()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("performance budget not checked: %w", err)
+	}
+
+	b := cfg.PerformanceBudget
+	var errs []error
+	for _, limit := range []struct {
+		category string
+		max      int64
+	}{
+		{"html", b.MaxHTMLBytes},
+		{"js", b.MaxJSBytes},
+		{"css", b.MaxCSSBytes},
+		{"total", b.MaxTotalBytes},
+	} {
+		if limit.max > 0 && sizes[limit.category] > limit.max {
+			errs = append(errs, fmt.Errorf("performance budget exceeded: %s is %d bytes, over the limit of %d", limit.category, sizes[limit.category], limit.max))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validatePerformanceBudget reports negative limits.
+func validatePerformanceBudget(b *PerformanceBudget) []error {
+	if b == nil {
+		return nil
+	}
+	var errs []error
+	for _, limit := range []struct {
+		field string
+		max   int64
+	}{
+		{"max_html_bytes", b.MaxHTMLBytes},
+		{"max_js_bytes", b.MaxJSBytes},
+		{"max_css_bytes", b.MaxCSSBytes},
+		{"max_total_bytes", b.MaxTotalBytes},
+	} {
+		if limit.max < 0 {
+			errs = append(errs, fmt.Errorf("performance_budget: %s must not be negative", limit.field))
+		}
+	}
+	return errs
+}
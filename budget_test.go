@@ -0,0 +1,73 @@
+package spaserver
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestNewWithPerformanceBudget(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":       {Data: bytes.Repeat([]byte("h"), 100)},
+		"about.htm":        {Data: bytes.Repeat([]byte("h"), 50)},
+		"js/app.js":        {Data: bytes.Repeat([]byte("j"), 300)},
+		"js/vendor.MJS":    {Data: bytes.Repeat([]byte("j"), 200)},
+		"js/app.js.map":    {Data: bytes.Repeat([]byte("m"), 5000)},
+		"css/main.css":     {Data: bytes.Repeat([]byte("c"), 80)},
+		"img/logo.png":     {Data: bytes.Repeat([]byte("p"), 400)},
+		"fonts/inter.woff": {Data: bytes.Repeat([]byte("f"), 250)},
+	}
+	// html 150, js 500, css 80, total 1380 without the source map
+
+	tt := []struct {
+		name string
+		opts []Option
+		err  []string
+		logs string
+	}{
+		{name: "within budget", opts: []Option{WithPerformanceBudget(PerformanceBudget{MaxHTMLBytes: 150, MaxJSBytes: 500, MaxCSSBytes: 80, MaxTotalBytes: 6380})}},
+		{name: "no limits", opts: []Option{WithPerformanceBudget(PerformanceBudget{})}},
+		{name: "html", opts: []Option{WithPerformanceBudget(PerformanceBudget{MaxHTMLBytes: 149})}, err: []string{"html is 150 bytes, over the limit of 149"}},
+		{name: "js", opts: []Option{WithPerformanceBudget(PerformanceBudget{MaxJSBytes: 499})}, err: []string{"js is 500 bytes, over the limit of 499"}},
+		{name: "css", opts: []Option{WithPerformanceBudget(PerformanceBudget{MaxCSSBytes: 79})}, err: []string{"css is 80 bytes, over the limit of 79"}},
+		{name: "total", opts: []Option{WithPerformanceBudget(PerformanceBudget{MaxTotalBytes: 1000})}, err: []string{"total is 6380 bytes, over the limit of 1000"}},
+		{name: "blocked files not counted", opts: []Option{WithPerformanceBudget(PerformanceBudget{MaxTotalBytes: 1380}), WithSourceMaps(false)}},
+		{name: "several limits", opts: []Option{WithPerformanceBudget(PerformanceBudget{MaxJSBytes: 100, MaxCSSBytes: 10})}, err: []string{"js is 500 bytes", "css is 80 bytes"}},
+		{name: "soft limit", opts: []Option{WithPerformanceBudget(PerformanceBudget{MaxJSBytes: 100, SoftLimit: true})}, logs: "js is 500 bytes, over the limit of 100"},
+		{name: "negative limit", opts: []Option{WithPerformanceBudget(PerformanceBudget{MaxCSSBytes: -1})}, err: []string{"performance_budget: max_css_bytes must not be negative"}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			opts := append([]Option{WithLogger(slog.New(slog.NewTextHandler(&logs, nil)))}, tc.opts...)
+			_, err := New(fsys, opts...)
+			if len(tc.err) == 0 && err != nil {
+				t.Errorf("expected no error, got: %v", err)
+			}
+			for _, want := range tc.err {
+				if err == nil || !strings.Contains(err.Error(), want) {
+					t.Errorf("error expected to contain %q, got: %v", want, err)
+				}
+			}
+			if !strings.Contains(logs.String(), tc.logs) {
+				t.Errorf("logs expected to contain %q, got: %q", tc.logs, logs.String())
+			}
+		})
+	}
+}
+
+func TestReloadWithPerformanceBudget(t *testing.T) {
+	small := fstest.MapFS{"index.html": {Data: []byte("index")}}
+	large := fstest.MapFS{"index.html": {Data: bytes.Repeat([]byte("h"), 1000)}}
+
+	h, err := New(small, WithPerformanceBudget(PerformanceBudget{MaxTotalBytes: 100}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Reload(large); err == nil || !strings.Contains(err.Error(), "performance budget exceeded") {
+		t.Errorf("error expected to contain %q, got: %v", "performance budget exceeded", err)
+	}
+}
@@ -0,0 +1,75 @@
+package spaserver
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+)
+
+// WithStaticCacheMaxAge sends Cache-Control: public, max-age=<seconds>
+// with static file responses. index.html is always sent with no-cache
+// headers, and content-hashed assets with an immutable Cache-Control.
+func WithStaticCacheMaxAge(d time.Duration) Option {
+	return func(c *Config) {
+		c.StaticCacheMaxAge = Duration(d)
+	}
+}
+
+// WithStaleWhileRevalidate appends stale-while-revalidate=<seconds> to the
+// Cache-Control of static file responses, allowing CDNs and browsers to
+// serve a stale copy while revalidating it in the background. Combine it
+// with WithStaticCacheMaxAge. It is never applied to index.html, which must
+// always be fresh.
+func WithStaleWhileRevalidate(d time.Duration) Option {
+	return func(c *Config) {
+		c.StaleWhileRevalidate = Duration(d)
+	}
+}
+
+// WithImmutablePattern sends Cache-Control: public, max-age=31536000,
+// immutable with static files whose base name matches the path.Match glob,
+// e.g. "*.????????????????????.*" for 20 character content hashes or
+// "*-[0-9a-f][0-9a-f][0-9a-f][0-9a-f][0-9a-f][0-9a-f][0-9a-f][0-9a-f].*".
+// Multiple calls accumulate.
+func WithImmutablePattern(glob string) Option {
+	return func(c *Config) {
+		c.ImmutablePatterns = append(c.ImmutablePatterns, glob)
+	}
+}
+
+// matchAny reports whether name matches any of the path.Match patterns.
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// setStaticCacheControl sets the Cache-Control header of a static file
+// response for name. immutable reports whether the file is known to be
+// content-hashed.
+func setStaticCacheControl(cfg Config, w http.ResponseWriter, name string, immutable bool) {
+	immutable = immutable || matchAny(cfg.ImmutablePatterns, path.Base(name))
+
+	cc := ""
+	switch {
+	case immutable:
+		cc = immutableCacheControl
+	case cfg.StaticCacheMaxAge > 0:
+		cc = "public, max-age=" + strconv.FormatInt(int64(time.Duration(cfg.StaticCacheMaxAge)/time.Second), 10)
+	}
+	if cfg.StaleWhileRevalidate > 0 {
+		swr := "stale-while-revalidate=" + strconv.FormatInt(int64(time.Duration(cfg.StaleWhileRevalidate)/time.Second), 10)
+		if cc == "" {
+			cc = swr
+		} else {
+			cc += ", " + swr
+		}
+	}
+	if cc != "" {
+		w.Header().Set("Cache-Control", cc)
+	}
+}
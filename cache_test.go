@@ -0,0 +1,88 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestServeWithStaleWhileRevalidate(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":          {Data: []byte("index.html")},
+		"css/main.css":        {Data: []byte("body {}")},
+		"js/main.abc123.js":   {Data: []byte("main")},
+		"asset-manifest.json": {Data: []byte(`{"js/main.js": "js/main.abc123.js"}`)},
+	}
+
+	tt := []struct {
+		name string
+		opts []Option
+		url  string
+		want string
+	}{
+		{name: "max age", opts: []Option{WithStaticCacheMaxAge(time.Hour)}, url: "/css/main.css", want: "public, max-age=3600"},
+		{name: "max age and swr", opts: []Option{WithStaticCacheMaxAge(time.Hour), WithStaleWhileRevalidate(24 * time.Hour)}, url: "/css/main.css", want: "public, max-age=3600, stale-while-revalidate=86400"},
+		{name: "swr only", opts: []Option{WithStaleWhileRevalidate(time.Minute)}, url: "/css/main.css", want: "stale-while-revalidate=60"},
+		{name: "immutable asset", opts: []Option{WithWebpackManifest("asset-manifest.json"), WithStaleWhileRevalidate(time.Minute)}, url: "/js/main.js", want: immutableCacheControl + ", stale-while-revalidate=60"},
+		{name: "index stays fresh", opts: []Option{WithStaticCacheMaxAge(time.Hour), WithStaleWhileRevalidate(time.Minute)}, url: "/", want: noCacheHeaders["Cache-Control"]},
+		{name: "spa fallback stays fresh", opts: []Option{WithStaticCacheMaxAge(time.Hour), WithStaleWhileRevalidate(time.Minute)}, url: "/settings", want: noCacheHeaders["Cache-Control"]},
+		{name: "not configured", url: "/css/main.css"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Serve(fsys, tc.opts...)
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+tc.url, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Cache-Control"); got != tc.want {
+				t.Errorf("Cache-Control expected: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestServeWithImmutablePattern(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":            {Data: []byte("index.html")},
+		"js/main.js":            {Data: []byte("main")},
+		"js/main.a1b2c3d4.js":   {Data: []byte("main")},
+		"js/vendor-0123abcd.js": {Data: []byte("vendor")},
+		"a1b2c3d4.dir/main.js":  {Data: []byte("main")},
+	}
+	h := Serve(fsys,
+		WithImmutablePattern("*.[0-9a-f][0-9a-f][0-9a-f][0-9a-f][0-9a-f][0-9a-f][0-9a-f][0-9a-f].*"),
+		WithImmutablePattern("*-[0-9a-f][0-9a-f][0-9a-f][0-9a-f][0-9a-f][0-9a-f][0-9a-f][0-9a-f].*"),
+	)
+
+	tt := []struct {
+		url  string
+		want string
+	}{
+		{url: "/js/main.a1b2c3d4.js", want: immutableCacheControl},
+		{url: "/js/vendor-0123abcd.js", want: immutableCacheControl},
+		{url: "/js/main.js"},
+		{url: "/a1b2c3d4.dir/main.js"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.url, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+tc.url, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Cache-Control"); got != tc.want {
+				t.Errorf("Cache-Control expected: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNewWithImmutablePatternInvalid(t *testing.T) {
+	if _, err := New(headerTestFS, WithImmutablePattern("*.[")); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
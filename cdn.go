@@ -0,0 +1,52 @@
+package spaserver
+
+import "net/http"
+
+// WithSurrogateControl sends a Surrogate-Control header with file and
+// index responses, e.g. "max-age=86400". CDNs such as Fastly and Varnish
+// honor it independently of Cache-Control and strip it before responding to
+// clients, so edge caching can differ from browser caching.
+func WithSurrogateControl(value string) Option {
+	return func(c *Config) {
+		c.SurrogateControl = value
+	}
+}
+
+// WithCDNTag tags each static file response with fn(name), where name is
+// the file's path relative to the filesystem root. The tag is sent as both
+// Surrogate-Key (Fastly) and Cache-Tag (Cloudflare) so that deploys can
+// purge only the changed assets by tag. Each CDN strips its own header
+// before responding to clients. An empty tag omits the headers.
+func WithCDNTag(fn func(name string) string) Option {
+	return func(c *Config) {
+		c.CDNTagFunc = fn
+	}
+}
+
+// WithSurrogateKeyFn is WithCDNTag under the name used by Fastly: each
+// static file response is tagged with fn(name) in both the Surrogate-Key
+// and Cache-Tag headers.
+func WithSurrogateKeyFn(fn func(name string) string) Option {
+	return WithCDNTag(fn)
+}
+
+// WithSurrogateKeyAll tags every static file response with key, typically
+// the deployment's Git SHA, so that purging the tag invalidates exactly
+// that deployment's assets.
+func WithSurrogateKeyAll(key string) Option {
+	return WithCDNTag(func(string) string { return key })
+}
+
+// setCDNHeaders sets the CDN-only headers for a response serving name.
+// Tags are only set for static files, identified by a non-empty name.
+func setCDNHeaders(cfg Config, w http.ResponseWriter, name string) {
+	if cfg.SurrogateControl != "" {
+		w.Header().Set("Surrogate-Control", cfg.SurrogateControl)
+	}
+	if cfg.CDNTagFunc != nil && name != "" {
+		if tag := cfg.CDNTagFunc(name); tag != "" {
+			w.Header().Set("Surrogate-Key", tag)
+			w.Header().Set("Cache-Tag", tag)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+)
+
+func TestServeWithSurrogateControlAndCDNTag(t *testing.T) {
+	h := Serve(headerTestFS,
+		WithSurrogateControl("max-age=86400"),
+		WithCDNTag(func(name string) string {
+			return "deploy-abc123 " + path.Dir(name)
+		}),
+	)
+
+	tt := []struct {
+		name             string
+		url              string
+		surrogateControl string
+		tag              string
+	}{
+		{name: "static file tagged", url: "http://www.example.com/css/main.css", surrogateControl: "max-age=86400", tag: "deploy-abc123 css"},
+		{name: "index not tagged", url: "http://www.example.com/", surrogateControl: "max-age=86400"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Surrogate-Control"); got != tc.surrogateControl {
+				t.Errorf("Surrogate-Control expected: %q, got: %q", tc.surrogateControl, got)
+			}
+			for _, header := range []string{"Surrogate-Key", "Cache-Tag"} {
+				if got := w.Header().Get(header); got != tc.tag {
+					t.Errorf("%s expected: %q, got: %q", header, tc.tag, got)
+				}
+			}
+		})
+	}
+}
+
+func TestServeWithCDNTagEmpty(t *testing.T) {
+	h := Serve(headerTestFS, WithCDNTag(func(string) string { return "" }))
+
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/css/main.css", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if _, ok := w.Header()["Surrogate-Key"]; ok {
+		t.Error("expected no Surrogate-Key header for an empty tag")
+	}
+}
+
+func TestServeWithSurrogateKey(t *testing.T) {
+	tt := []struct {
+		name string
+		opt  Option
+		url  string
+		tag  string
+	}{
+		{name: "fn", opt: WithSurrogateKeyFn(path.Ext), url: "http://www.example.com/css/main.css", tag: ".css"},
+		{name: "all", opt: WithSurrogateKeyAll("deploy-abc123"), url: "http://www.example.com/css/main.css", tag: "deploy-abc123"},
+		{name: "all index not tagged", opt: WithSurrogateKeyAll("deploy-abc123"), url: "http://www.example.com/"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Serve(headerTestFS, tc.opt)
+			r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			for _, header := range []string{"Surrogate-Key", "Cache-Tag"} {
+				if got := w.Header().Get(header); got != tc.tag {
+					t.Errorf("%s expected: %q, got: %q", header, tc.tag, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+// Package check inspects the responses of an http.Handler for missing
+// security headers, in the manner of securityheaders.com but offline, so
+// the checks can run in a project's tests.
+package check
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// Severity ranks a SecurityIssue.
+type Severity string
+
+const (
+	// Critical issues leave the page open to common attacks.
+	Critical Severity = "critical"
+	// Warning issues weaken defenses in depth.
+	Warning Severity = "warning"
+)
+
+// SecurityIssue is a failed security header check.
+type SecurityIssue struct {
+	Header      string
+	Severity    Severity
+	Message     string
+	Remediation string
+}
+
+func (i SecurityIssue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.Severity, i.Header, i.Message)
+}
+
+// rule checks one header of a response.
+type rule struct {
+	header      string
+	severity    Severity
+	httpsOnly   bool
+	ok          func(h http.Header) bool
+	message     string
+	remediation string
+}
+
+func present(name string) func(http.Header) bool {
+	return func(h http.Header) bool { return h.Get(name) != "" }
+}
+
+var rules = []rule{
+	{
+		header:      "Strict-Transport-Security",
+		severity:    Critical,
+		httpsOnly:   true,
+		ok:          present("Strict-Transport-Security"),
+		message:     "missing; browsers may connect over plain HTTP",
+		remediation: `send "Strict-Transport-Security: max-age=63072000; includeSubDomains"`,
+	},
+	{
+		header:      "Content-Security-Policy",
+		severity:    Critical,
+		ok:          present("Content-Security-Policy"),
+		message:     "missing; injected scripts are not restricted",
+		remediation: `send a policy such as "default-src 'self'" with spaserver.WithCSP`,
+	},
+	{
+		header:      "X-Frame-Options",
+		severity:    Warning,
+		ok:          present("X-Frame-Options"),
+		message:     "missing; the page can be framed for clickjacking",
+		remediation: `send "X-Frame-Options: DENY" or a CSP frame-ancestors directive`,
+	},
+	{
+		header:   "X-Content-Type-Options",
+		severity: Warning,
+		ok: func(h http.Header) bool {
+			return strings.EqualFold(strings.TrimSpace(h.Get("X-Content-Type-Options")), "nosniff")
+		},
+		message:     "not nosniff; responses may be MIME sniffed",
+		remediation: `send "X-Content-Type-Options: nosniff"`,
+	},
+	{
+		header:      "Referrer-Policy",
+		severity:    Warning,
+		ok:          present("Referrer-Policy"),
+		message:     "missing; full URLs may leak to other origins",
+		remediation: `send "Referrer-Policy: strict-origin-when-cross-origin"`,
+	},
+}
+
+// CheckSecurityHeaders sends a GET request for url to h and returns an
+// issue for each security header check the response fails, or nil if it
+// passes them all. Strict-Transport-Security is only required when url
+// is an https URL. The error is non-nil only if url is invalid.
+func CheckSecurityHeaders(h http.Handler, url string) ([]SecurityIssue, error) {
+	r, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	r.RemoteAddr = "192.0.2.1:1234"
+	if r.URL.Scheme == "https" {
+		r.TLS = &tls.ConnectionState{Version: tls.VersionTLS12, HandshakeComplete: true, ServerName: r.URL.Hostname()}
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	header := w.Result().Header
+
+	var issues []SecurityIssue
+	for _, rule := range rules {
+		if rule.httpsOnly && r.URL.Scheme != "https" {
+			continue
+		}
+		if !rule.ok(header) {
+			issues = append(issues, SecurityIssue{
+				Header:      rule.header,
+				Severity:    rule.severity,
+				Message:     rule.message,
+				Remediation: rule.remediation,
+			})
+		}
+	}
+	return issues, nil
+}
@@ -0,0 +1,69 @@
+package check
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/eriklott/spaserver"
+)
+
+var testFS = fstest.MapFS{
+	"index.html": {Data: []byte("index.html")},
+}
+
+func TestCheckSecurityHeaders(t *testing.T) {
+	hardened := spaserver.Serve(testFS, spaserver.WithHeaders("/*", http.Header{
+		"Strict-Transport-Security": {"max-age=63072000"},
+		"Referrer-Policy":           {"strict-origin-when-cross-origin"},
+	}))
+	bare := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Content-Type-Options", "sniff")
+	})
+
+	tt := []struct {
+		name    string
+		handler http.Handler
+		url     string
+		want    []string
+	}{
+		{name: "defaults over http", handler: spaserver.Serve(testFS), url: "http://www.example.com/", want: []string{"Referrer-Policy"}},
+		{name: "defaults over https", handler: spaserver.Serve(testFS), url: "https://www.example.com/", want: []string{"Strict-Transport-Security", "Referrer-Policy"}},
+		{name: "hardened", handler: hardened, url: "https://www.example.com/"},
+		{
+			name:    "bare handler",
+			handler: bare,
+			url:     "https://www.example.com/",
+			want:    []string{"Strict-Transport-Security", "Content-Security-Policy", "X-Frame-Options", "X-Content-Type-Options", "Referrer-Policy"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			issues, err := CheckSecurityHeaders(tc.handler, tc.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, issue := range issues {
+				got = append(got, issue.Header)
+				if issue.Severity != Critical && issue.Severity != Warning {
+					t.Errorf("%s: unexpected severity %q", issue.Header, issue.Severity)
+				}
+				if issue.Remediation == "" {
+					t.Errorf("%s: expected a remediation hint", issue.Header)
+				}
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("issues expected: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCheckSecurityHeadersInvalidURL(t *testing.T) {
+	if _, err := CheckSecurityHeaders(spaserver.Serve(testFS), "http://[::1"); err == nil {
+		t.Error("expected error for an invalid URL")
+	}
+}
@@ -0,0 +1,43 @@
+package spaserver
+
+import (
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+// colorSchemeHint is the client hint carrying the user's preferred color
+// scheme.
+const colorSchemeHint = "Sec-CH-Prefers-Color-Scheme"
+
+// WithDarkModeVariant serves darkIndexFile, e.g. "index.dark.html", in
+// place of index.html to clients that send Sec-CH-Prefers-Color-Scheme:
+// dark. Responses without the hint carry Accept-CH to request it on later
+// navigations, and every index response carries Vary on the hint so caches
+// keep the variants apart. index.html is served when darkIndexFile does not
+// exist. A localized index file selected by WithLocales takes precedence.
+func WithDarkModeVariant(darkIndexFile string) Option {
+	return func(c *Config) {
+		c.DarkModeIndex = darkIndexFile
+	}
+}
+
+// colorSchemeIndexPage returns the index file to serve for the request's
+// preferred color scheme, falling back to name.
+func colorSchemeIndexPage(fsys fs.FS, cfg Config, w http.ResponseWriter, r *http.Request, name string) string {
+	addVary(w.Header(), colorSchemeHint)
+
+	hint := r.Header.Get(colorSchemeHint)
+	if hint == "" {
+		w.Header().Set("Accept-CH", colorSchemeHint)
+		return name
+	}
+	// The hint is a structured field string, but accept a bare token too
+	if strings.Trim(strings.TrimSpace(hint), `"`) != "dark" || name != indexPage {
+		return name
+	}
+	if _, err := fs.Stat(fsys, cfg.DarkModeIndex); err != nil {
+		return name
+	}
+	return cfg.DarkModeIndex
+}
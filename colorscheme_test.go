@@ -0,0 +1,74 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeWithDarkModeVariant(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":      {Data: []byte("index.html")},
+		"index.dark.html": {Data: []byte("index.dark.html")},
+		"index.fr.html":   {Data: []byte("index.fr.html")},
+	}
+
+	tt := []struct {
+		name     string
+		opts     []Option
+		headers  map[string]string
+		body     string
+		acceptCH string
+	}{
+		{name: "no hint", body: "index.html", acceptCH: "Sec-CH-Prefers-Color-Scheme"},
+		{name: "dark", headers: map[string]string{"Sec-CH-Prefers-Color-Scheme": `"dark"`}, body: "index.dark.html"},
+		{name: "dark token", headers: map[string]string{"Sec-CH-Prefers-Color-Scheme": "dark"}, body: "index.dark.html"},
+		{name: "light", headers: map[string]string{"Sec-CH-Prefers-Color-Scheme": `"light"`}, body: "index.html"},
+		{
+			name:    "missing variant",
+			opts:    []Option{WithDarkModeVariant("index.midnight.html")},
+			headers: map[string]string{"Sec-CH-Prefers-Color-Scheme": `"dark"`},
+			body:    "index.html",
+		},
+		{
+			name:    "locale takes precedence",
+			opts:    []Option{WithLocales([]string{"en", "fr"}, "en")},
+			headers: map[string]string{"Sec-CH-Prefers-Color-Scheme": `"dark"`, "Accept-Language": "fr"},
+			body:    "index.fr.html",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Serve(fsys, append([]Option{WithDarkModeVariant("index.dark.html")}, tc.opts...)...)
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com/some/route", nil)
+			for k, v := range tc.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if body := w.Body.String(); body != tc.body {
+				t.Errorf("body expected: %q, got: %q", tc.body, body)
+			}
+			if got := w.Header().Get("Accept-CH"); got != tc.acceptCH {
+				t.Errorf("Accept-CH expected: %q, got: %q", tc.acceptCH, got)
+			}
+			if !headerContainsToken(w.Header(), "Vary", "Sec-CH-Prefers-Color-Scheme") {
+				t.Errorf("Vary expected to contain Sec-CH-Prefers-Color-Scheme, got: %q", w.Header().Values("Vary"))
+			}
+		})
+	}
+}
+
+func TestServeWithoutDarkModeVariant(t *testing.T) {
+	h := Serve(fstest.MapFS{"index.html": {Data: []byte("index.html")}})
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if got := w.Header().Get("Accept-CH"); got != "" {
+		t.Errorf("Accept-CH expected to be absent, got: %q", got)
+	}
+}
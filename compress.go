@@ -0,0 +1,295 @@
+package spaserver
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// WithGzip compresses successful responses with gzip for clients that send
+// Accept-Encoding: gzip. Responses that could be compressed carry
+// Vary: Accept-Encoding, whether or not the client accepted gzip, so that
+// caches store the encoded and identity variants separately.
+func WithGzip() Option {
+	return func(c *Config) {
+		c.Gzip = true
+	}
+}
+
+// WithZstd compresses successful responses with Zstandard for clients that
+// send Accept-Encoding: zstd. When several enabled encodings are accepted
+// with the same quality, zstd is preferred over gzip.
+func WithZstd() Option {
+	return func(c *Config) {
+		c.Zstd = true
+	}
+}
+
+// WithZstdLevel enables Zstandard compression at level. Defaults to
+// zstd.SpeedDefault.
+func WithZstdLevel(level zstd.EncoderLevel) Option {
+	return func(c *Config) {
+		c.Zstd = true
+		c.ZstdLevel = level
+	}
+}
+
+// defaultCompressionMinSize is the smallest response body compressed by
+// default. Below it, encoding overhead can exceed the savings.
+const defaultCompressionMinSize = 1024
+
+// WithCompressionMinSize serves responses smaller than bytes uncompressed,
+// whatever the encoding. Responses of unknown length are always compressed.
+// Defaults to 1024 bytes.
+func WithCompressionMinSize(bytes int64) Option {
+	return func(c *Config) {
+		c.CompressionMinSize = &bytes
+	}
+}
+
+// defaultCompressionTypes are the media types compressed by default.
+// Images, video, archives and WebAssembly are already compressed or gain
+// little.
+var defaultCompressionTypes = []string{
+	"text/*",
+	"application/javascript",
+	"application/json",
+	"application/xml",
+	"image/svg+xml",
+}
+
+// WithCompressionTypes sets the media types eligible for compression,
+// replacing the defaults: text/*, application/javascript,
+// application/json, application/xml and image/svg+xml. A type ending in
+// "/*" matches every subtype. Multiple calls accumulate.
+func WithCompressionTypes(mimeTypes ...string) Option {
+	return func(c *Config) {
+		c.CompressionTypes = append(c.CompressionTypes, mimeTypes...)
+	}
+}
+
+// WithCompressionExcludeTypes excludes media types from compression, e.g.
+// "text/csv" from the default text/*. Multiple calls accumulate.
+func WithCompressionExcludeTypes(mimeTypes ...string) Option {
+	return func(c *Config) {
+		c.CompressionExcludeTypes = append(c.CompressionExcludeTypes, mimeTypes...)
+	}
+}
+
+// matchMediaType reports whether the Content-Type ctype matches any of
+// patterns.
+func matchMediaType(patterns []string, ctype string) bool {
+	mediaType, _, _ := strings.Cut(ctype, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	for _, p := range patterns {
+		p = strings.ToLower(p)
+		if prefix, ok := strings.CutSuffix(p, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+		} else if mediaType == p {
+			return true
+		}
+	}
+	return false
+}
+
+// encodings returns the enabled content codings in order of preference.
+func (cfg Config) encodings() []string {
+	var encodings []string
+	if cfg.Zstd {
+		encodings = append(encodings, "zstd")
+	}
+	if cfg.Gzip {
+		encodings = append(encodings, "gzip")
+	}
+	return encodings
+}
+
+// negotiateEncoding returns the entry of supported, ordered by preference,
+// with the highest quality in the Accept-Encoding header, or "" for
+// identity. A coding not listed in the header takes the quality of "*".
+func negotiateEncoding(header string, supported []string) string {
+	qs := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "" {
+			qs[coding] = parseQuality(params)
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, enc := range supported {
+		q, ok := qs[enc]
+		if !ok {
+			q = qs["*"]
+		}
+		if q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+// encoder is a reusable streaming compressor.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+var (
+	gzipPool  = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+	zstdPools sync.Map // zstd.EncoderLevel to *sync.Pool
+)
+
+// zstdPool returns the encoder pool for level.
+func zstdPool(level zstd.EncoderLevel) *sync.Pool {
+	if p, ok := zstdPools.Load(level); ok {
+		return p.(*sync.Pool)
+	}
+	p, _ := zstdPools.LoadOrStore(level, &sync.Pool{New: func() any {
+		enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(level), zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			panic(err)
+		}
+		return enc
+	}})
+	return p.(*sync.Pool)
+}
+
+// encoderPool returns the encoder pool for encoding.
+func encoderPool(encoding string, level zstd.EncoderLevel) *sync.Pool {
+	if encoding == "zstd" {
+		return zstdPool(level)
+	}
+	return &gzipPool
+}
+
+// addVary adds field to the Vary header unless it is already listed.
+func addVary(h http.Header, field string) {
+	for _, v := range h.Values("Vary") {
+		for _, f := range strings.Split(v, ",") {
+			f = strings.TrimSpace(f)
+			if f == "*" || strings.EqualFold(f, field) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", field)
+}
+
+// compressWriter compresses the response body with the negotiated encoding.
+// The decision is made when the header is written: only 200 responses that
+// are not already encoded and have an eligible media type and size are
+// compressed.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	zstdLevel   zstd.EncoderLevel
+	minSize     int64
+	types       []string
+	excludes    []string
+	head        bool
+	enc         encoder
+	wroteHeader bool
+}
+
+// newCompressWriter wraps w for the request r.
+func newCompressWriter(cfg Config, w http.ResponseWriter, r *http.Request) *compressWriter {
+	return &compressWriter{
+		ResponseWriter: w,
+		encoding:       negotiateEncoding(r.Header.Get("Accept-Encoding"), cfg.encodings()),
+		zstdLevel:      cfg.ZstdLevel,
+		minSize:        *cfg.CompressionMinSize,
+		types:          cfg.CompressionTypes,
+		excludes:       cfg.CompressionExcludeTypes,
+		head:           r.Method == http.MethodHead,
+	}
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+
+	h := cw.Header()
+	if code == http.StatusOK && h.Get("Content-Encoding") == "" && cw.eligible() {
+		addVary(h, "Accept-Encoding")
+		if cw.encoding != "" {
+			h.Set("Content-Encoding", cw.encoding)
+			// The length, byte ranges and digest of the encoded body
+			// differ from those of the file
+			h.Del("Content-Length")
+			h.Del("Accept-Ranges")
+			h.Del("Content-Digest")
+			// A strong ETag identifies the exact bytes of the identity
+			// representation
+			if etag := h.Get("Etag"); strings.HasPrefix(etag, `"`) {
+				h.Set("Etag", "W/"+etag)
+			}
+			if !cw.head {
+				cw.enc = encoderPool(cw.encoding, cw.zstdLevel).Get().(encoder)
+				cw.enc.Reset(cw.ResponseWriter)
+			}
+		}
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+// eligible reports whether the response has a compressible media type and
+// is at least the minimum size for compression, judging by its
+// Content-Length.
+func (cw *compressWriter) eligible() bool {
+	ctype := cw.Header().Get("Content-Type")
+	if !matchMediaType(cw.types, ctype) || matchMediaType(cw.excludes, ctype) {
+		return false
+	}
+	n, err := strconv.ParseInt(cw.Header().Get("Content-Length"), 10, 64)
+	return err != nil || n >= cw.minSize
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.enc != nil {
+		return cw.enc.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush flushes buffered compressed data to the client.
+func (cw *compressWriter) Flush() {
+	if cw.enc != nil {
+		cw.enc.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the wrapped ResponseWriter for http.ResponseController.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Close finishes the compressed stream, if any, and returns its encoder to
+// the pool.
+func (cw *compressWriter) Close() error {
+	if cw.enc == nil {
+		return nil
+	}
+	err := cw.enc.Close()
+	cw.enc.Reset(nil)
+	encoderPool(cw.encoding, cw.zstdLevel).Put(cw.enc)
+	cw.enc = nil
+	return err
+}
@@ -0,0 +1,317 @@
+package spaserver
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestServeWithGzip(t *testing.T) {
+	h := Serve(headerTestFS, WithGzip(), WithCompressionMinSize(0))
+
+	tt := []struct {
+		name           string
+		acceptEncoding string
+		encoding       string
+	}{
+		{name: "gzip accepted", acceptEncoding: "gzip, deflate, br", encoding: "gzip"},
+		{name: "wildcard accepted", acceptEncoding: "*", encoding: "gzip"},
+		{name: "gzip refused", acceptEncoding: "gzip;q=0, identity"},
+		{name: "no accept-encoding"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com/css/main.css", nil)
+			if tc.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Content-Encoding"); got != tc.encoding {
+				t.Fatalf("Content-Encoding expected: %q, got: %q", tc.encoding, got)
+			}
+			if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary expected: %q, got: %q", "Accept-Encoding", got)
+			}
+
+			var body io.Reader = w.Body
+			if tc.encoding == "gzip" {
+				if got := w.Header().Get("Content-Length"); got != "" {
+					t.Errorf("Content-Length expected to be absent, got: %q", got)
+				}
+				zr, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body = zr
+			}
+			b, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := string(headerTestFS["css/main.css"].Data)
+			if string(b) != want {
+				t.Errorf("body expected: %q, got: %q", want, string(b))
+			}
+		})
+	}
+}
+
+func TestServeWithGzipAppendsVary(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":    {Data: []byte("<html>en</html>")},
+		"index.fr.html": {Data: []byte("<html>fr</html>")},
+	}
+	h := Serve(fsys, WithGzip(), WithCompressionMinSize(0), WithLocales([]string{"en", "fr"}, "en"))
+
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	r.Header.Set("Accept-Language", "fr")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding expected: %q, got: %q", "gzip", got)
+	}
+	want := "Accept-Language, Accept-Encoding"
+	if got := strings.Join(w.Header().Values("Vary"), ", "); got != want {
+		t.Errorf("Vary expected: %q, got: %q", want, got)
+	}
+}
+
+func TestServeWithoutGzip(t *testing.T) {
+	h := Serve(headerTestFS)
+
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/css/main.css", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	for _, header := range []string{"Content-Encoding", "Vary"} {
+		if got := w.Header().Get(header); got != "" {
+			t.Errorf("%s expected to be absent, got: %q", header, got)
+		}
+	}
+}
+
+func TestServeWithGzipSkipsErrors(t *testing.T) {
+	h := Serve(headerTestFS, WithGzip(), WithBasicAuthFunc("test", func(user, pass string) (bool, error) {
+		return false, nil
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/css/main.css", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status expected: %d, got: %d", http.StatusUnauthorized, w.Code)
+	}
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding expected to be absent, got: %q", got)
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tt := []struct {
+		header    string
+		supported []string
+		want      string
+	}{
+		{header: "gzip, deflate, br, zstd", supported: []string{"zstd", "gzip"}, want: "zstd"},
+		{header: "gzip, zstd", supported: []string{"gzip"}, want: "gzip"},
+		{header: "gzip;q=1.0, zstd;q=0.5", supported: []string{"zstd", "gzip"}, want: "gzip"},
+		{header: "zstd;q=0, *", supported: []string{"zstd", "gzip"}, want: "gzip"},
+		{header: "*", supported: []string{"zstd", "gzip"}, want: "zstd"},
+		{header: "identity", supported: []string{"zstd", "gzip"}, want: ""},
+		{header: "", supported: []string{"zstd", "gzip"}, want: ""},
+	}
+
+	for _, tc := range tt {
+		if got := negotiateEncoding(tc.header, tc.supported); got != tc.want {
+			t.Errorf("negotiateEncoding(%q, %q) expected: %q, got: %q", tc.header, tc.supported, tc.want, got)
+		}
+	}
+}
+
+func TestServeWithZstd(t *testing.T) {
+	tt := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "default level", opts: []Option{WithZstd(), WithGzip(), WithCompressionMinSize(0)}},
+		{name: "best compression", opts: []Option{WithZstdLevel(zstd.SpeedBestCompression), WithCompressionMinSize(0)}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Serve(headerTestFS, tc.opts...)
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com/css/main.css", nil)
+			r.Header.Set("Accept-Encoding", "gzip, deflate, br, zstd")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Content-Encoding"); got != "zstd" {
+				t.Fatalf("Content-Encoding expected: %q, got: %q", "zstd", got)
+			}
+			if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary expected: %q, got: %q", "Accept-Encoding", got)
+			}
+
+			zr, err := zstd.NewReader(w.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer zr.Close()
+			b, err := io.ReadAll(zr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := string(headerTestFS["css/main.css"].Data); string(b) != want {
+				t.Errorf("body expected: %q, got: %q", want, string(b))
+			}
+		})
+	}
+}
+
+func TestNewWithZstdLevelInvalid(t *testing.T) {
+	if _, err := New(headerTestFS, WithZstdLevel(zstd.EncoderLevel(42))); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+// benchmarkBundle returns a JavaScript bundle of about size bytes.
+func benchmarkBundle(size int) []byte {
+	var b strings.Builder
+	for i := 0; b.Len() < size; i++ {
+		fmt.Fprintf(&b, "function component%d(props){const state%d=useState(%d);return h(\"div\",{className:\"c%d\"},props.children,state%d[0]);}\n", i, i, i*7, i%13, i)
+	}
+	return []byte(b.String())
+}
+
+func benchmarkCompression(b *testing.B, encoding string, opts ...Option) {
+	fsys := fstest.MapFS{
+		"index.html":   {Data: []byte("index.html")},
+		"js/bundle.js": {Data: benchmarkBundle(200 << 10)},
+	}
+	h := Serve(fsys, opts...)
+
+	b.SetBytes(int64(len(fsys["js/bundle.js"].Data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com/js/bundle.js", nil)
+		r.Header.Set("Accept-Encoding", encoding)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if got := w.Header().Get("Content-Encoding"); got != encoding {
+			b.Fatalf("Content-Encoding expected: %q, got: %q", encoding, got)
+		}
+	}
+}
+
+func BenchmarkCompressGzip(b *testing.B) {
+	benchmarkCompression(b, "gzip", WithGzip())
+}
+
+func BenchmarkCompressZstd(b *testing.B) {
+	benchmarkCompression(b, "zstd", WithZstd())
+}
+
+func TestServeWithCompressionMinSize(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":   {Data: []byte("index.html")},
+		"css/tiny.css": {Data: []byte(strings.Repeat("a", 200))},
+		"css/main.css": {Data: []byte(strings.Repeat("a", 2048))},
+	}
+
+	tt := []struct {
+		name     string
+		opts     []Option
+		url      string
+		encoding string
+	}{
+		{name: "below default", opts: []Option{WithGzip()}, url: "/css/tiny.css"},
+		{name: "above default", opts: []Option{WithGzip()}, url: "/css/main.css", encoding: "gzip"},
+		{name: "below zstd", opts: []Option{WithZstd()}, url: "/css/tiny.css"},
+		{name: "custom threshold", opts: []Option{WithGzip(), WithCompressionMinSize(4096)}, url: "/css/main.css"},
+		{name: "no threshold", opts: []Option{WithGzip(), WithCompressionMinSize(0)}, url: "/css/tiny.css", encoding: "gzip"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Serve(fsys, tc.opts...)
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+tc.url, nil)
+			r.Header.Set("Accept-Encoding", "gzip, zstd")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Content-Encoding"); got != tc.encoding {
+				t.Errorf("Content-Encoding expected: %q, got: %q", tc.encoding, got)
+			}
+			if tc.encoding == "" && w.Body.Len() != len(fsys[strings.TrimPrefix(tc.url, "/")].Data) {
+				t.Errorf("body expected uncompressed, got %d bytes", w.Body.Len())
+			}
+		})
+	}
+}
+
+func TestServeWithCompressionTypes(t *testing.T) {
+	large := func(prefix string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(prefix + strings.Repeat("a", 2048))}
+	}
+	fsys := fstest.MapFS{
+		"index.html":      large("<html>"),
+		"js/main.js":      large("var a;"),
+		"data/report.csv": large("a,b\n"),
+		"img/logo.svg":    large("<svg>"),
+		"img/photo.png":   large("\x89PNG\r\n\x1a\n"),
+		"app.wasm":        large("\x00asm\x01\x00\x00\x00"),
+	}
+
+	tt := []struct {
+		name     string
+		opts     []Option
+		url      string
+		encoding string
+	}{
+		{name: "html", url: "/", encoding: "gzip"},
+		{name: "javascript", url: "/js/main.js", encoding: "gzip"},
+		{name: "svg", url: "/img/logo.svg", encoding: "gzip"},
+		{name: "png", url: "/img/photo.png"},
+		{name: "wasm", url: "/app.wasm"},
+		{name: "excluded type", opts: []Option{WithCompressionExcludeTypes("text/csv")}, url: "/data/report.csv"},
+		{name: "excluded type keeps others", opts: []Option{WithCompressionExcludeTypes("text/csv")}, url: "/js/main.js", encoding: "gzip"},
+		{name: "custom types", opts: []Option{WithCompressionTypes("application/wasm")}, url: "/app.wasm", encoding: "gzip"},
+		{name: "custom types replace defaults", opts: []Option{WithCompressionTypes("application/wasm")}, url: "/js/main.js"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Serve(fsys, append(tc.opts, WithGzip())...)
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+tc.url, nil)
+			r.Header.Set("Accept-Encoding", "gzip")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Content-Encoding"); got != tc.encoding {
+				t.Errorf("Content-Encoding expected: %q, got: %q (Content-Type %q)", tc.encoding, got, w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestNewWithCompressionTypesInvalid(t *testing.T) {
+	if _, err := New(headerTestFS, WithGzip(), WithCompressionTypes("text/")); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
@@ -0,0 +1,770 @@
+package spaserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes the handler's configurable behavior as structured data.
+// It is an alternative to functional options (see ServeWithConfig) and can
+// be loaded from a deployment configuration file with ServeFromConfig.
+// Zero-valued fields leave the corresponding default in place.
+type Config struct {
+	// CSP overrides the Content-Security-Policy header sent with index.html
+	// responses. An empty string omits the header; nil keeps the default.
+	CSP *string `json:"csp,omitempty" yaml:"csp,omitempty"`
+
+	// MIMETypes maps file extensions to Content-Type values.
+	MIMETypes map[string]string `json:"mime_types,omitempty" yaml:"mime_types,omitempty"`
+
+	// ContentTypes maps path.Match globs to Content-Type values, taking
+	// precedence over MIMETypes.
+	ContentTypes map[string]string `json:"content_types,omitempty" yaml:"content_types,omitempty"`
+
+	// Locales lists the supported locales for localized index files.
+	Locales []string `json:"locales,omitempty" yaml:"locales,omitempty"`
+
+	// DefaultLocale is used when no supported locale matches the request.
+	DefaultLocale string `json:"default_locale,omitempty" yaml:"default_locale,omitempty"`
+
+	// DarkModeIndex is the index file served to clients preferring a dark
+	// color scheme.
+	DarkModeIndex string `json:"dark_mode_index,omitempty" yaml:"dark_mode_index,omitempty"`
+
+	// LanguageRedirects maps languages to the paths requests for / are
+	// redirected to.
+	LanguageRedirects map[string]string `json:"language_redirects,omitempty" yaml:"language_redirects,omitempty"`
+
+	// WebpackManifest is the path of a webpack-manifest-plugin manifest
+	// within the served filesystem.
+	WebpackManifest string `json:"webpack_manifest,omitempty" yaml:"webpack_manifest,omitempty"`
+
+	// IndexPreload reads index.html once when the handler is created and on
+	// each Reload, instead of on every request.
+	IndexPreload bool `json:"index_preload,omitempty" yaml:"index_preload,omitempty"`
+
+	// IndexLastModified sends the modification time of index.html as the
+	// Last-Modified header of index responses.
+	IndexLastModified bool `json:"index_last_modified,omitempty" yaml:"index_last_modified,omitempty"`
+
+	// LivenessPath, ReadinessPath and StartupPath are the request paths of
+	// the Kubernetes probes. Empty paths disable the probe.
+	LivenessPath  string `json:"liveness_path,omitempty" yaml:"liveness_path,omitempty"`
+	ReadinessPath string `json:"readiness_path,omitempty" yaml:"readiness_path,omitempty"`
+	StartupPath   string `json:"startup_path,omitempty" yaml:"startup_path,omitempty"`
+
+	// AutoCSPHashes lists the hash algorithms used to allow the inline
+	// scripts and styles of index.html in the Content-Security-Policy.
+	AutoCSPHashes []string `json:"auto_csp_hashes,omitempty" yaml:"auto_csp_hashes,omitempty"`
+
+	// TrustedTypes, when non-nil, enables Trusted Types enforcement with the
+	// listed policy names.
+	TrustedTypes []string `json:"trusted_types,omitempty" yaml:"trusted_types,omitempty"`
+
+	// ReportTo defines the reporting endpoint groups of the Report-To
+	// header, and NEL the Network Error Logging policy using them.
+	ReportTo []ReportToGroup `json:"report_to,omitempty" yaml:"report_to,omitempty"`
+	NEL      *NELPolicy      `json:"nel,omitempty" yaml:"nel,omitempty"`
+
+	// DocumentPolicy is sent as the Document-Policy header of HTML pages.
+	DocumentPolicy string `json:"document_policy,omitempty" yaml:"document_policy,omitempty"`
+
+	// ServerTiming sends a Server-Timing header with the time spent on
+	// each stage of serving the response.
+	ServerTiming bool `json:"server_timing,omitempty" yaml:"server_timing,omitempty"`
+
+	// MaxPathLength is the longest URL path served, in bytes; longer paths
+	// get 400 Bad Request. Zero means the default of 4096.
+	MaxPathLength int `json:"max_path_length,omitempty" yaml:"max_path_length,omitempty"`
+
+	// MaxConnections limits the requests in flight; zero means no limit.
+	MaxConnections int `json:"max_connections,omitempty" yaml:"max_connections,omitempty"`
+	// MaxConnectionsGauge receives the number of requests in flight.
+	MaxConnectionsGauge Gauge `json:"-" yaml:"-"`
+
+	// DeploymentVersion is sent as the X-Deployment-Version header of
+	// every response.
+	DeploymentVersion string `json:"deployment_version,omitempty" yaml:"deployment_version,omitempty"`
+
+	// CanonicalBaseURL is the base of the canonical URL sent in the Link
+	// header of index responses.
+	CanonicalBaseURL string `json:"canonical_base_url,omitempty" yaml:"canonical_base_url,omitempty"`
+
+	// XRobotsTag is sent as the X-Robots-Tag header of HTML pages.
+	XRobotsTag string `json:"x_robots_tag,omitempty" yaml:"x_robots_tag,omitempty"`
+
+	// OriginAgentCluster requests an origin-keyed agent cluster for HTML
+	// pages.
+	OriginAgentCluster bool `json:"origin_agent_cluster,omitempty" yaml:"origin_agent_cluster,omitempty"`
+
+	// BlockHiddenFiles answers requests for dot-prefixed path segments
+	// with 404 Not Found.
+	BlockHiddenFiles bool `json:"block_hidden_files,omitempty" yaml:"block_hidden_files,omitempty"`
+
+	// ContainSymlinks answers requests for files in an os.DirFS filesystem
+	// that resolve outside its root directory with 403 Forbidden.
+	ContainSymlinks bool `json:"contain_symlinks,omitempty" yaml:"contain_symlinks,omitempty"`
+
+	// WellKnownPassthrough answers requests for missing /.well-known/
+	// resources with 404 Not Found instead of an HTML page.
+	WellKnownPassthrough bool `json:"well_known_passthrough,omitempty" yaml:"well_known_passthrough,omitempty"`
+
+	// ForbidDirectoryAccess answers requests for directories other than
+	// the root with 403 Forbidden.
+	ForbidDirectoryAccess bool `json:"forbid_directory_access,omitempty" yaml:"forbid_directory_access,omitempty"`
+
+	// DownloadPatterns are globs of static files served as attachments.
+	DownloadPatterns []string `json:"download_patterns,omitempty" yaml:"download_patterns,omitempty"`
+
+	// BlockedExtensions are file name endings answered with 403 Forbidden.
+	BlockedExtensions []string `json:"blocked_extensions,omitempty" yaml:"blocked_extensions,omitempty"`
+
+	// SourceMaps, when non-nil, controls whether .js.map and .css.map files
+	// are served.
+	SourceMaps *bool `json:"source_maps,omitempty" yaml:"source_maps,omitempty"`
+
+	// ClearSiteData maps request paths to the data types of the
+	// Clear-Site-Data header sent with their responses.
+	ClearSiteData map[string][]string `json:"clear_site_data,omitempty" yaml:"clear_site_data,omitempty"`
+
+	// DNSPrefetchControl allows DNS prefetching of links in HTML pages.
+	// Nil and false send X-DNS-Prefetch-Control: off.
+	DNSPrefetchControl *bool `json:"dns_prefetch_control,omitempty" yaml:"dns_prefetch_control,omitempty"`
+
+	// Headers adds custom headers to responses by URL path pattern.
+	Headers []HeaderRule `json:"headers,omitempty" yaml:"headers,omitempty"`
+
+	// HideServerHeaders removes Server, X-Powered-By and X-AspNet-Version
+	// from responses.
+	HideServerHeaders bool `json:"hide_server_headers,omitempty" yaml:"hide_server_headers,omitempty"`
+
+	// IPAllowlist and IPBlocklist restrict access by client IP address. Both
+	// accept CIDRs and bare addresses.
+	IPAllowlist []string `json:"ip_allowlist,omitempty" yaml:"ip_allowlist,omitempty"`
+	IPBlocklist []string `json:"ip_blocklist,omitempty" yaml:"ip_blocklist,omitempty"`
+
+	// TrustedProxies are the CIDRs of proxies whose forwarding headers
+	// report the client IP address.
+	TrustedProxies []string `json:"trusted_proxies,omitempty" yaml:"trusted_proxies,omitempty"`
+
+	// BasicAuthRealm and BasicAuthCredentials (user name to bcrypt hash)
+	// enable HTTP Basic authentication. BasicAuthFunc, when set, checks
+	// credentials instead of BasicAuthCredentials.
+	BasicAuthRealm       string                                `json:"basic_auth_realm,omitempty" yaml:"basic_auth_realm,omitempty"`
+	BasicAuthCredentials map[string]string                     `json:"basic_auth_credentials,omitempty" yaml:"basic_auth_credentials,omitempty"`
+	BasicAuthFunc        func(user, pass string) (bool, error) `json:"-" yaml:"-"`
+
+	// StaticCacheMaxAge and StaleWhileRevalidate set the Cache-Control
+	// directives of static file responses.
+	StaticCacheMaxAge    Duration `json:"static_cache_max_age,omitempty" yaml:"static_cache_max_age,omitempty"`
+	StaleWhileRevalidate Duration `json:"stale_while_revalidate,omitempty" yaml:"stale_while_revalidate,omitempty"`
+
+	// ContentHashETags sends the SHA-256 of static files as their ETag.
+	ContentHashETags bool `json:"content_hash_etags,omitempty" yaml:"content_hash_etags,omitempty"`
+
+	// ContentDigest is the algorithm of the Content-Digest header sent
+	// with files and pages, "sha-256" or "sha-512"; empty disables it.
+	ContentDigest string `json:"content_digest,omitempty" yaml:"content_digest,omitempty"`
+
+	// FileCacheTTL expires entries of the per-file caches after they were
+	// stored; zero keeps them until the file's modification time or size
+	// changes.
+	FileCacheTTL Duration `json:"file_cache_ttl,omitempty" yaml:"file_cache_ttl,omitempty"`
+
+	// ImmutablePatterns are path.Match globs of content-hashed file base
+	// names, which are sent with an immutable Cache-Control.
+	ImmutablePatterns []string `json:"immutable_patterns,omitempty" yaml:"immutable_patterns,omitempty"`
+
+	// SurrogateControl is sent as the Surrogate-Control header, and
+	// CDNTagFunc computes the CDN purge tag of each static file.
+	SurrogateControl string                   `json:"surrogate_control,omitempty" yaml:"surrogate_control,omitempty"`
+	CDNTagFunc       func(name string) string `json:"-" yaml:"-"`
+
+	// Gzip and Zstd compress responses for clients that accept the
+	// encoding. ZstdLevel defaults to zstd.SpeedDefault.
+	Gzip      bool              `json:"gzip,omitempty" yaml:"gzip,omitempty"`
+	Zstd      bool              `json:"zstd,omitempty" yaml:"zstd,omitempty"`
+	ZstdLevel zstd.EncoderLevel `json:"zstd_level,omitempty" yaml:"zstd_level,omitempty"`
+
+	// PreencodeGzip compresses static files with gzip in the background
+	// when a site is loaded, serving them from memory.
+	PreencodeGzip bool `json:"preencode_gzip,omitempty" yaml:"preencode_gzip,omitempty"`
+	// PreencodeWorkers is the number of goroutines used by PreencodeGzip.
+	PreencodeWorkers int `json:"preencode_workers,omitempty" yaml:"preencode_workers,omitempty"`
+
+	// CompressionMinSize is the smallest response body, in bytes, that is
+	// compressed. Defaults to 1024.
+	CompressionMinSize *int64 `json:"compression_min_size,omitempty" yaml:"compression_min_size,omitempty"`
+
+	// CompressionTypes are the media types eligible for compression,
+	// defaulting to common text formats, less CompressionExcludeTypes.
+	CompressionTypes        []string `json:"compression_types,omitempty" yaml:"compression_types,omitempty"`
+	CompressionExcludeTypes []string `json:"compression_exclude_types,omitempty" yaml:"compression_exclude_types,omitempty"`
+
+	// CSPReportPath is the request path of the CSP violation report
+	// endpoint, and CSPReportHandler receives its reports. CSPReportFilter,
+	// if set, selects the reports passed to the handler.
+	CSPReportPath    string                         `json:"csp_report_path,omitempty" yaml:"csp_report_path,omitempty"`
+	CSPReportHandler func(*http.Request, CSPReport) `json:"-" yaml:"-"`
+	CSPReportFilter  func(CSPReport) bool           `json:"-" yaml:"-"`
+
+	// MetricsPath is the request path of the Prometheus metrics endpoint.
+	// Empty disables the endpoint.
+	MetricsPath string `json:"metrics_path,omitempty" yaml:"metrics_path,omitempty"`
+
+	// MetricsUser and MetricsPasswordHash, a bcrypt hash, are the Basic
+	// credentials required by the metrics endpoint.
+	MetricsUser         string `json:"metrics_user,omitempty" yaml:"metrics_user,omitempty"`
+	MetricsPasswordHash string `json:"metrics_password_hash,omitempty" yaml:"metrics_password_hash,omitempty"`
+
+	// ABCookie is the cookie holding the A/B test group of a client.
+	ABCookie string `json:"ab_cookie,omitempty" yaml:"ab_cookie,omitempty"`
+
+	// ABVariants maps A/B test variant names to their filesystems.
+	ABVariants map[string]fs.FS `json:"-" yaml:"-"`
+
+	// ABWeights are the relative weights of the A/B test groups assigned
+	// to new clients, keyed by variant name or "control". Nil assigns all
+	// groups equally.
+	ABWeights map[string]int `json:"ab_weights,omitempty" yaml:"ab_weights,omitempty"`
+
+	// TenantResolver selects the filesystem served for each request.
+	TenantResolver TenantResolver `json:"-" yaml:"-"`
+
+	// SingleFlight coalesces concurrent reads of the same file.
+	SingleFlight bool `json:"single_flight,omitempty" yaml:"single_flight,omitempty"`
+
+	// AllowedMethods are listed in the Allow header of OPTIONS responses.
+	// Defaults to GET, HEAD and OPTIONS.
+	AllowedMethods []string `json:"allowed_methods,omitempty" yaml:"allowed_methods,omitempty"`
+
+	// MethodHandlers receive requests with their method instead of the SPA.
+	MethodHandlers map[string]http.Handler `json:"-" yaml:"-"`
+
+	// SecurityTxt, when set, is served as /.well-known/security.txt.
+	SecurityTxt *SecurityTxtOptions `json:"security_txt,omitempty" yaml:"security_txt,omitempty"`
+
+	// RobotsTxt, when set, is served for /robots.txt instead of the file.
+	RobotsTxt string `json:"robots_txt,omitempty" yaml:"robots_txt,omitempty"`
+
+	// ServiceWorkerAllowed is sent as the Service-Worker-Allowed header
+	// with files whose base name matches ServiceWorkerPattern (default
+	// "*service-worker*.js").
+	ServiceWorkerAllowed string `json:"service_worker_allowed,omitempty" yaml:"service_worker_allowed,omitempty"`
+	ServiceWorkerPattern string `json:"service_worker_pattern,omitempty" yaml:"service_worker_pattern,omitempty"`
+
+	// HeadInjections and BodyInjections are inserted before </head> and
+	// </body> in index responses.
+	HeadInjections []string `json:"head_injections,omitempty" yaml:"head_injections,omitempty"`
+	BodyInjections []string `json:"body_injections,omitempty" yaml:"body_injections,omitempty"`
+
+	// EnvVarPrefix and EnvVars define window.__ENV__ in index responses.
+	EnvVarPrefix string         `json:"env_var_prefix,omitempty" yaml:"env_var_prefix,omitempty"`
+	EnvVars      map[string]any `json:"env_vars,omitempty" yaml:"env_vars,omitempty"`
+
+	// ImportMap maps bare ES module specifiers to URLs in an import map
+	// injected into index responses.
+	ImportMap map[string]string `json:"import_map,omitempty" yaml:"import_map,omitempty"`
+
+	// SpeculationRules are injected into index responses.
+	SpeculationRules *SpeculationRules `json:"speculation_rules,omitempty" yaml:"speculation_rules,omitempty"`
+
+	// PWA injects Progressive Web App tags into the index page.
+	PWA *PWAConfig `json:"pwa,omitempty" yaml:"pwa,omitempty"`
+
+	// PerformanceBudget limits the size of the files served, checked when
+	// the filesystem is loaded.
+	PerformanceBudget *PerformanceBudget `json:"performance_budget,omitempty" yaml:"performance_budget,omitempty"`
+
+	// MPAMode serves a multi-page site: unknown paths get 404 Not Found
+	// and directories their own index.html.
+	MPAMode bool `json:"mpa_mode,omitempty" yaml:"mpa_mode,omitempty"`
+
+	// NotFoundPage is served with a 404 status for paths that do not exist,
+	// instead of index.html.
+	NotFoundPage string `json:"not_found_page,omitempty" yaml:"not_found_page,omitempty"`
+
+	// ShutdownTimeout is how long ListenAndServe waits for in-flight
+	// requests on shutdown. Defaults to 30s.
+	ShutdownTimeout Duration `json:"shutdown_timeout,omitempty" yaml:"shutdown_timeout,omitempty"`
+
+	// WebSocketHandler receives WebSocket upgrade requests.
+	WebSocketHandler http.Handler `json:"-" yaml:"-"`
+
+	// Redirects are evaluated in order before the filesystem is accessed.
+	Redirects []RedirectRule `json:"redirects,omitempty" yaml:"redirects,omitempty"`
+
+	// Aliases serve requests for one path from another without a
+	// redirect, evaluated in order.
+	Aliases []Alias `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+
+	// ACMEManager answers ACME HTTP-01 challenges.
+	ACMEManager *autocert.Manager `json:"-" yaml:"-"`
+
+	// Proxies reverse-proxy matching requests to other servers.
+	Proxies []ProxyConfig `json:"-" yaml:"-"`
+
+	// GRPCWebHandler receives gRPC-Web requests.
+	GRPCWebHandler http.Handler `json:"-" yaml:"-"`
+
+	// SSERoutes receive Server-Sent Events requests.
+	SSERoutes []SSERoute `json:"-" yaml:"-"`
+
+	// ContextEnricher replaces the request context before each request is
+	// served.
+	ContextEnricher func(ctx context.Context, r *http.Request) context.Context `json:"-" yaml:"-"`
+
+	// Logger reports non-fatal problems. Defaults to slog.Default().
+	Logger *slog.Logger `json:"-" yaml:"-"`
+
+	// devReload enables the DevMode live-reload endpoint and script.
+	devReload bool
+}
+
+// withDefaults returns a copy of cfg with defaults filled in and MIME type
+// extensions normalised.
+func (cfg Config) withDefaults() Config {
+	if cfg.CSP == nil {
+		csp := defaultCSP
+		cfg.CSP = &csp
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	// Pre-encoded files are only served to clients negotiating gzip
+	if cfg.PreencodeGzip {
+		cfg.Gzip = true
+	}
+	if cfg.CompressionMinSize == nil {
+		n := int64(defaultCompressionMinSize)
+		cfg.CompressionMinSize = &n
+	}
+	if cfg.CompressionTypes == nil {
+		cfg.CompressionTypes = defaultCompressionTypes
+	}
+	if cfg.ZstdLevel == 0 {
+		cfg.ZstdLevel = zstd.SpeedDefault
+	}
+	if cfg.AllowedMethods == nil {
+		cfg.AllowedMethods = allowedMethods(cfg.MethodHandlers)
+	}
+	if cfg.MaxPathLength == 0 {
+		cfg.MaxPathLength = defaultMaxPathLength
+	}
+	if cfg.ServiceWorkerPattern == "" {
+		cfg.ServiceWorkerPattern = defaultServiceWorkerPattern
+	}
+	if cfg.ShutdownTimeout == 0 {
+		cfg.ShutdownTimeout = Duration(defaultShutdownTimeout)
+	}
+	if cfg.BlockedExtensions != nil {
+		exts := make([]string, len(cfg.BlockedExtensions))
+		for i, ext := range cfg.BlockedExtensions {
+			exts[i] = normalizeExt(ext)
+		}
+		cfg.BlockedExtensions = exts
+	}
+	if cfg.MIMETypes != nil {
+		mimeTypes := make(map[string]string, len(cfg.MIMETypes))
+		for ext, mimeType := range cfg.MIMETypes {
+			mimeTypes[normalizeExt(ext)] = mimeType
+		}
+		cfg.MIMETypes = mimeTypes
+	}
+	return cfg
+}
+
+// Validate reports whether cfg describes a usable handler configuration,
+// including options that are set but have no effect without another.
+func (cfg Config) Validate() error {
+	var errs []error
+
+	if cfg.CSP != nil && strings.ContainsAny(*cfg.CSP, "\r\n") {
+		errs = append(errs, errors.New("csp: must not contain CR or LF characters"))
+	}
+
+	for ext, mimeType := range cfg.MIMETypes {
+		if strings.TrimPrefix(ext, ".") == "" {
+			errs = append(errs, errors.New("mime_types: empty extension"))
+		}
+		if _, _, err := mime.ParseMediaType(mimeType); err != nil {
+			errs = append(errs, fmt.Errorf("mime_types: %q: %w", ext, err))
+		}
+	}
+
+	for pattern, mimeType := range cfg.ContentTypes {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("content_types: invalid pattern %q: %w", pattern, err))
+		}
+		if _, _, err := mime.ParseMediaType(mimeType); err != nil {
+			errs = append(errs, fmt.Errorf("content_types: %q: %w", pattern, err))
+		}
+	}
+
+	for _, locale := range cfg.Locales {
+		if locale == "" || strings.ContainsAny(locale, `/\`) {
+			errs = append(errs, fmt.Errorf("locales: invalid locale %q", locale))
+		}
+	}
+	for locale, target := range cfg.LanguageRedirects {
+		if locale == "" || locale == "*" {
+			errs = append(errs, fmt.Errorf("language_redirects: invalid language %q", locale))
+		}
+		if !strings.HasPrefix(target, "/") {
+			errs = append(errs, fmt.Errorf("language_redirects: %q: path %q must start with /", locale, target))
+		}
+	}
+
+	if cfg.DarkModeIndex != "" && !fs.ValidPath(cfg.DarkModeIndex) {
+		errs = append(errs, fmt.Errorf("dark_mode_index: invalid path %q", cfg.DarkModeIndex))
+	}
+	if cfg.DefaultLocale != "" && len(cfg.Locales) == 0 {
+		errs = append(errs, errors.New("default_locale: requires locales"))
+	}
+
+	if cfg.WebpackManifest != "" && !fs.ValidPath(strings.TrimPrefix(cfg.WebpackManifest, "/")) {
+		errs = append(errs, fmt.Errorf("webpack_manifest: invalid path %q", cfg.WebpackManifest))
+	}
+
+	for _, algo := range cfg.AutoCSPHashes {
+		if _, ok := sriHashes[strings.ToLower(algo)]; !ok {
+			errs = append(errs, fmt.Errorf("auto_csp_hashes: unsupported algorithm %q", algo))
+		}
+	}
+
+	for _, name := range cfg.TrustedTypes {
+		if !trustedTypesName.MatchString(name) && name != "'allow-duplicates'" {
+			errs = append(errs, fmt.Errorf("trusted_types: invalid policy name %q", name))
+		}
+	}
+
+	if strings.ContainsAny(cfg.SurrogateControl, "\r\n") {
+		errs = append(errs, errors.New("surrogate_control: must not contain CR or LF characters"))
+	}
+
+	if strings.ContainsAny(cfg.DocumentPolicy, "\r\n") {
+		errs = append(errs, errors.New("document_policy: must not contain CR or LF characters"))
+	}
+
+	if cfg.PreencodeWorkers < 0 {
+		errs = append(errs, errors.New("preencode_workers: must not be negative"))
+	}
+	if cfg.PreencodeWorkers > 0 && !cfg.PreencodeGzip {
+		errs = append(errs, errors.New("preencode_workers: requires preencode_gzip"))
+	}
+
+	if cfg.MaxPathLength < 0 {
+		errs = append(errs, errors.New("max_path_length: must not be negative"))
+	}
+
+	if cfg.MaxConnections < 0 {
+		errs = append(errs, errors.New("max_connections: must not be negative"))
+	}
+	if cfg.MaxConnectionsGauge != nil && cfg.MaxConnections == 0 {
+		errs = append(errs, errors.New("max_connections: required by the connections gauge"))
+	}
+
+	if strings.ContainsAny(cfg.DeploymentVersion, "\r\n") {
+		errs = append(errs, errors.New("deployment_version: must not contain CR or LF characters"))
+	}
+
+	if cfg.CanonicalBaseURL != "" {
+		if u, err := url.Parse(cfg.CanonicalBaseURL); err != nil || u.Scheme == "" || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+			errs = append(errs, fmt.Errorf("canonical_base_url: %q must be an absolute URL without query or fragment", cfg.CanonicalBaseURL))
+		}
+	}
+
+	if strings.ContainsAny(cfg.XRobotsTag, "\r\n") {
+		errs = append(errs, errors.New("x_robots_tag: must not contain CR or LF characters"))
+	}
+
+	errs = append(errs, validateHeaderRules(cfg.Headers)...)
+	errs = append(errs, validateClearSiteData(cfg.ClearSiteData)...)
+	errs = append(errs, validateProxies(cfg.Proxies)...)
+	errs = append(errs, validateRedirects(cfg.Redirects)...)
+	errs = append(errs, validateAliases(cfg.Aliases)...)
+	errs = append(errs, validateSecurityTxt(cfg.SecurityTxt)...)
+	errs = append(errs, validateContentDigest(cfg.ContentDigest)...)
+	errs = append(errs, validatePerformanceBudget(cfg.PerformanceBudget)...)
+
+	for _, route := range cfg.SSERoutes {
+		if _, err := path.Match(route.Pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("sse_routes: invalid pattern %q: %w", route.Pattern, err))
+		}
+		if route.Handler == nil {
+			errs = append(errs, fmt.Errorf("sse_routes: %q: nil handler", route.Pattern))
+		}
+	}
+	errs = append(errs, validateABTest(cfg)...)
+
+	for _, method := range cfg.AllowedMethods {
+		if !validHeaderName(method) {
+			errs = append(errs, fmt.Errorf("allowed_methods: invalid method %q", method))
+		}
+	}
+
+	for method, handler := range cfg.MethodHandlers {
+		if !validHeaderName(method) {
+			errs = append(errs, fmt.Errorf("method_handlers: invalid method %q", method))
+		}
+		if handler == nil {
+			errs = append(errs, fmt.Errorf("method_handlers: %s: nil handler", method))
+		}
+	}
+
+	if _, err := parsePrefixes(cfg.IPAllowlist); err != nil {
+		errs = append(errs, fmt.Errorf("ip_allowlist: %w", err))
+	}
+	if _, err := parsePrefixes(cfg.IPBlocklist); err != nil {
+		errs = append(errs, fmt.Errorf("ip_blocklist: %w", err))
+	}
+
+	if _, err := parsePrefixes(cfg.TrustedProxies); err != nil {
+		errs = append(errs, fmt.Errorf("trusted_proxies: %w", err))
+	}
+
+	for user, hash := range cfg.BasicAuthCredentials {
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			errs = append(errs, fmt.Errorf("basic_auth_credentials: %q: %w", user, err))
+		}
+		if strings.Contains(user, ":") {
+			errs = append(errs, fmt.Errorf("basic_auth_credentials: user name %q must not contain a colon", user))
+		}
+	}
+	if cfg.CSPReportPath != "" && cfg.CSPReportHandler == nil {
+		errs = append(errs, errors.New("csp_report_path: requires a report handler"))
+	}
+	if cfg.CSPReportPath == "" && cfg.CSPReportHandler != nil {
+		errs = append(errs, errors.New("csp_report_path: required by the report handler"))
+	}
+	if cfg.MetricsUser != "" || cfg.MetricsPasswordHash != "" {
+		if cfg.MetricsPath == "" {
+			errs = append(errs, errors.New("metrics_user: requires metrics_path"))
+		}
+		if cfg.MetricsUser == "" || strings.Contains(cfg.MetricsUser, ":") {
+			errs = append(errs, fmt.Errorf("metrics_user: invalid user name %q", cfg.MetricsUser))
+		}
+		if _, err := bcrypt.Cost([]byte(cfg.MetricsPasswordHash)); err != nil {
+			errs = append(errs, fmt.Errorf("metrics_password_hash: %w", err))
+		}
+	}
+	if strings.ContainsAny(cfg.BasicAuthRealm, "\r\n") {
+		errs = append(errs, errors.New("basic_auth_realm: must not contain CR or LF characters"))
+	}
+
+	errs = append(errs, validateReporting(cfg.ReportTo, cfg.NEL)...)
+
+	if strings.ContainsAny(cfg.ServiceWorkerAllowed, "\r\n") {
+		errs = append(errs, errors.New("service_worker_allowed: must not contain CR or LF characters"))
+	}
+	if _, err := path.Match(cfg.ServiceWorkerPattern, ""); err != nil {
+		errs = append(errs, fmt.Errorf("service_worker_pattern: invalid pattern %q: %w", cfg.ServiceWorkerPattern, err))
+	}
+
+	if cfg.PWA != nil {
+		errs = append(errs, validatePWA(cfg.PWA)...)
+	}
+	if cfg.SpeculationRules != nil {
+		errs = append(errs, validateSpeculationRules(*cfg.SpeculationRules)...)
+	}
+
+	if cfg.NotFoundPage != "" && !fs.ValidPath(strings.TrimPrefix(cfg.NotFoundPage, "/")) {
+		errs = append(errs, fmt.Errorf("not_found_page: invalid path %q", cfg.NotFoundPage))
+	}
+
+	if cfg.ZstdLevel != 0 && (cfg.ZstdLevel < zstd.SpeedFastest || cfg.ZstdLevel > zstd.SpeedBestCompression) {
+		errs = append(errs, fmt.Errorf("zstd_level: invalid level %d", cfg.ZstdLevel))
+	}
+
+	for _, mimeType := range cfg.CompressionTypes {
+		if _, _, err := mime.ParseMediaType(mimeType); err != nil {
+			errs = append(errs, fmt.Errorf("compression_types: %q: %w", mimeType, err))
+		}
+	}
+	for _, mimeType := range cfg.CompressionExcludeTypes {
+		if _, _, err := mime.ParseMediaType(mimeType); err != nil {
+			errs = append(errs, fmt.Errorf("compression_exclude_types: %q: %w", mimeType, err))
+		}
+	}
+	if cfg.CompressionMinSize != nil && *cfg.CompressionMinSize < 0 {
+		errs = append(errs, errors.New("compression_min_size: must not be negative"))
+	}
+
+	if cfg.StaticCacheMaxAge < 0 {
+		errs = append(errs, errors.New("static_cache_max_age: must not be negative"))
+	}
+	if cfg.StaleWhileRevalidate < 0 {
+		errs = append(errs, errors.New("stale_while_revalidate: must not be negative"))
+	}
+
+	for _, p := range cfg.DownloadPatterns {
+		if _, err := path.Match(p, ""); err != nil {
+			errs = append(errs, fmt.Errorf("download_patterns: invalid pattern %q: %w", p, err))
+		}
+	}
+	for _, p := range cfg.ImmutablePatterns {
+		if _, err := path.Match(p, ""); err != nil {
+			errs = append(errs, fmt.Errorf("immutable_patterns: invalid pattern %q: %w", p, err))
+		}
+	}
+
+	if cfg.FileCacheTTL < 0 {
+		errs = append(errs, errors.New("file_cache_ttl: must not be negative"))
+	}
+	if cfg.ShutdownTimeout < 0 {
+		errs = append(errs, errors.New("shutdown_timeout: must not be negative"))
+	}
+
+	probes := map[string]string{}
+	for _, probe := range []struct{ name, path string }{
+		{"liveness_path", cfg.LivenessPath},
+		{"readiness_path", cfg.ReadinessPath},
+		{"startup_path", cfg.StartupPath},
+		{"metrics_path", cfg.MetricsPath},
+		{"csp_report_path", cfg.CSPReportPath},
+	} {
+		name, p := probe.name, probe.path
+		if p == "" {
+			continue
+		}
+		if !strings.HasPrefix(p, "/") {
+			errs = append(errs, fmt.Errorf("%s: %q must begin with /", name, p))
+		}
+		if other, ok := probes[p]; ok {
+			errs = append(errs, fmt.Errorf("%s: %q is also used by %s", name, p, other))
+		}
+		probes[p] = name
+	}
+
+	return errors.Join(errs...)
+}
+
+// ValidateConfig reports whether cfg describes a usable handler
+// configuration. It is intended for checking configuration files in CI
+// pipelines before deployment.
+func ValidateConfig(cfg Config) error {
+	return cfg.Validate()
+}
+
+// LoadConfig reads a Config from the file at configPath. The format is
+// detected from the extension: .yaml, .yml or .json. Unknown keys are
+// reported as errors.
+func LoadConfig(configPath string) (Config, error) {
+	var cfg Config
+
+	b, err := os.ReadFile(configPath)
+	if err != nil {
+		return cfg, fmt.Errorf("read config: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(configPath)); ext {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&cfg); err != nil {
+			return cfg, fmt.Errorf("parse config %s: %w", configPath, err)
+		}
+		if _, err := dec.Token(); err != io.EOF {
+			return cfg, fmt.Errorf("parse config %s: unexpected data after top-level value", configPath)
+		}
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		if err := dec.Decode(&cfg); err != nil && err != io.EOF {
+			return cfg, fmt.Errorf("parse config %s: %w", configPath, err)
+		}
+	default:
+		return cfg, fmt.Errorf("config %s: unsupported extension %q", configPath, ext)
+	}
+
+	return cfg, nil
+}
+
+// ServeFromConfig loads the configuration file at configPath (see
+// LoadConfig), validates it and returns a handler serving fsys.
+func ServeFromConfig(configPath string, fsys fs.FS) (http.Handler, error) {
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", configPath, err)
+	}
+	return ServeWithConfig(fsys, cfg), nil
+}
+
+// Duration is a time.Duration that is read from and written to
+// configuration files as a string such as "1h30m", see time.ParseDuration.
+// A plain number is read as seconds.
+type Duration time.Duration
+
+// String returns the duration formatted like time.Duration.
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	return d.set(v)
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (d Duration) MarshalYAML() (any, error) {
+	return d.String(), nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	var v any
+	if err := node.Decode(&v); err != nil {
+		return err
+	}
+	return d.set(v)
+}
+
+// set sets d from a decoded duration string or number of seconds.
+func (d *Duration) set(v any) error {
+	switch v := v.(type) {
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+	case float64:
+		*d = Duration(v * float64(time.Second))
+	case int:
+		*d = Duration(time.Duration(v) * time.Second)
+	default:
+		return fmt.Errorf("invalid duration %v", v)
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package spaserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestServeFromConfig(t *testing.T) {
+	tt := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{
+			name:    "json",
+			file:    "spaserver.json",
+			content: `{"csp": "default-src 'none'", "mime_types": {".wasm": "application/wasm"}}`,
+		},
+		{
+			name:    "yaml",
+			file:    "spaserver.yaml",
+			content: "csp: default-src 'none'\nmime_types:\n  .wasm: application/wasm\n",
+		},
+		{
+			name:    "yml",
+			file:    "spaserver.yml",
+			content: "csp: default-src 'none'\n",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := ServeFromConfig(writeConfig(t, tc.file, tc.content), os.DirFS("testdata"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := http.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Content-Security-Policy"); got != "default-src 'none'" {
+				t.Errorf("Content-Security-Policy expected: %q, got: %q", "default-src 'none'", got)
+			}
+		})
+	}
+}
+
+func TestServeFromConfigErrors(t *testing.T) {
+	tt := []struct {
+		name    string
+		file    string
+		content string
+		err     string
+	}{
+		{
+			name:    "unknown json key",
+			file:    "spaserver.json",
+			content: `{"csp": "default-src 'self'", "cors_origins": ["*"]}`,
+			err:     "unknown field",
+		},
+		{
+			name:    "unknown yaml key",
+			file:    "spaserver.yaml",
+			content: "cors_origins: ['*']\n",
+			err:     "not found in type",
+		},
+		{
+			name:    "unsupported extension",
+			file:    "spaserver.toml",
+			content: `csp = "default-src 'self'"`,
+			err:     "unsupported extension",
+		},
+		{
+			name:    "invalid config",
+			file:    "spaserver.json",
+			content: `{"default_locale": "en"}`,
+			err:     "default_locale: requires locales",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ServeFromConfig(writeConfig(t, tc.file, tc.content), os.DirFS("testdata"))
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("error expected to contain %q, got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfigDurations(t *testing.T) {
+	tt := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{name: "json strings", file: "spaserver.json", content: `{"static_cache_max_age": "1h", "stale_while_revalidate": "1m", "file_cache_ttl": "30s", "shutdown_timeout": "10s"}`},
+		{name: "json seconds", file: "spaserver.json", content: `{"static_cache_max_age": 3600, "stale_while_revalidate": 60, "file_cache_ttl": 30, "shutdown_timeout": 10}`},
+		{name: "yaml strings", file: "spaserver.yaml", content: "static_cache_max_age: 1h\nstale_while_revalidate: 1m\nfile_cache_ttl: 30s\nshutdown_timeout: 10s\n"},
+		{name: "yaml seconds", file: "spaserver.yaml", content: "static_cache_max_age: 3600\nstale_while_revalidate: 60\nfile_cache_ttl: 30\nshutdown_timeout: 10\n"},
+	}
+
+	want := Config{
+		StaticCacheMaxAge:    Duration(time.Hour),
+		StaleWhileRevalidate: Duration(time.Minute),
+		FileCacheTTL:         Duration(30 * time.Second),
+		ShutdownTimeout:      Duration(10 * time.Second),
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := LoadConfig(writeConfig(t, tc.file, tc.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.StaticCacheMaxAge != want.StaticCacheMaxAge || cfg.StaleWhileRevalidate != want.StaleWhileRevalidate ||
+				cfg.FileCacheTTL != want.FileCacheTTL || cfg.ShutdownTimeout != want.ShutdownTimeout {
+				t.Errorf("durations expected: %v %v %v %v, got: %v %v %v %v",
+					want.StaticCacheMaxAge, want.StaleWhileRevalidate, want.FileCacheTTL, want.ShutdownTimeout,
+					cfg.StaticCacheMaxAge, cfg.StaleWhileRevalidate, cfg.FileCacheTTL, cfg.ShutdownTimeout)
+			}
+		})
+	}
+
+	// Round trip
+	b, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"static_cache_max_age":"1h0m0s"`) {
+		t.Errorf("JSON expected to contain the duration as a string, got: %s", b)
+	}
+	var got Config
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.StaticCacheMaxAge != want.StaticCacheMaxAge || got.ShutdownTimeout != want.ShutdownTimeout {
+		t.Errorf("round trip expected: %v %v, got: %v %v", want.StaticCacheMaxAge, want.ShutdownTimeout, got.StaticCacheMaxAge, got.ShutdownTimeout)
+	}
+
+	if _, err := LoadConfig(writeConfig(t, "spaserver.json", `{"file_cache_ttl": "soon"}`)); err == nil {
+		t.Error("expected error for an invalid duration")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	badCSP := "default-src 'self'\r\nX-Injected: 1"
+
+	tt := []struct {
+		name string
+		cfg  Config
+		err  string
+	}{
+		{name: "zero config is valid", cfg: Config{}},
+		{name: "header injection in csp", cfg: Config{CSP: &badCSP}, err: "csp"},
+		{name: "invalid mime type", cfg: Config{MIMETypes: map[string]string{".x": "not a type"}}, err: "mime_types"},
+		{name: "invalid content type pattern", cfg: Config{ContentTypes: map[string]string{"[": "text/plain"}}, err: "content_types"},
+		{name: "invalid content type", cfg: Config{ContentTypes: map[string]string{"*.ts": "not a type"}}, err: "content_types"},
+		{name: "unsupported digest algorithm", cfg: Config{ContentDigest: "md5"}, err: "content_digest"},
+		{name: "negative file cache ttl", cfg: Config{FileCacheTTL: Duration(-time.Second)}, err: "file_cache_ttl"},
+		{name: "default locale without locales", cfg: Config{DefaultLocale: "en"}, err: "default_locale"},
+		{name: "locale with path separator", cfg: Config{Locales: []string{"../fr"}}, err: "locales"},
+		{name: "relative probe path", cfg: Config{LivenessPath: "livez"}, err: "liveness_path"},
+		{name: "shared probe path", cfg: Config{LivenessPath: "/healthz", ReadinessPath: "/healthz"}, err: "readiness_path"},
+		{name: "invalid manifest path", cfg: Config{WebpackManifest: "../manifest.json"}, err: "webpack_manifest"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.Validate()
+			if tc.err == "" {
+				if err != nil {
+					t.Errorf("expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("error expected to contain %q, got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestServeWithConfig(t *testing.T) {
+	csp := "default-src 'none'"
+	cfg := Config{
+		CSP:       &csp,
+		MIMETypes: map[string]string{"css": "text/css"},
+	}
+	h := ServeWithConfig(os.DirFS("testdata"), cfg)
+
+	tt := []struct {
+		name   string
+		url    string
+		header string
+		want   string
+	}{
+		{name: "csp applied to index", url: "http://www.example.com/", header: "Content-Security-Policy", want: csp},
+		{name: "mime extension normalised", url: "http://www.example.com/css/main.css", header: "Content-Type", want: "text/css"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Header().Get(tc.header); got != tc.want {
+				t.Errorf("%s expected: %q, got: %q", tc.header, tc.want, got)
+			}
+		})
+	}
+
+	if cfg.MIMETypes["css"] != "text/css" {
+		t.Errorf("ServeWithConfig modified the caller's config: %v", cfg.MIMETypes)
+	}
+}
+
+func TestServeWithConfigDefaults(t *testing.T) {
+	h := ServeWithConfig(os.DirFS("testdata"), Config{})
+
+	r, err := http.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if got := w.Header().Get("Content-Security-Policy"); got != defaultCSP {
+		t.Errorf("Content-Security-Policy expected: %q, got: %q", defaultCSP, got)
+	}
+}
@@ -0,0 +1,30 @@
+package spaserver
+
+import (
+	"context"
+	"net/http"
+)
+
+// WithContextEnricher calls fn at the start of each request, before any
+// filesystem access, and replaces the request context with the one it
+// returns for the rest of the request. Use it to resolve tenant IDs, JWT
+// claims or feature flags from the request once, for handlers and loggers
+// downstream. The client IP is already available to fn through
+// RealIPFromContext. A nil context returned by fn keeps the original.
+func WithContextEnricher(fn func(ctx context.Context, r *http.Request) context.Context) Option {
+	return func(c *Config) {
+		c.ContextEnricher = fn
+	}
+}
+
+// enrichContext returns r with the context returned by the configured
+// enricher, if any.
+func enrichContext(cfg Config, r *http.Request) *http.Request {
+	if cfg.ContextEnricher == nil {
+		return r
+	}
+	if ctx := cfg.ContextEnricher(r.Context(), r); ctx != nil {
+		return r.WithContext(ctx)
+	}
+	return r
+}
@@ -0,0 +1,59 @@
+package spaserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeWithContextEnricher(t *testing.T) {
+	fsys := &countFS{FS: fstest.MapFS{"index.html": {Data: []byte("index.html")}}}
+
+	var calls int
+	var opens int64
+	var realIP string
+	h := Serve(fsys, WithContextEnricher(func(ctx context.Context, r *http.Request) context.Context {
+		calls++
+		opens = fsys.opens.Load()
+		realIP = RealIPFromContext(ctx)
+		return ctx
+	}))
+	before := fsys.opens.Load()
+
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/app/route", nil)
+	r.RemoteAddr = "203.0.113.7:1234"
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if calls != 1 {
+		t.Errorf("enricher calls expected: 1, got: %d", calls)
+	}
+	if opens != before {
+		t.Errorf("enricher expected to run before filesystem access, got %d opens", opens-before)
+	}
+	if realIP != "203.0.113.7" {
+		t.Errorf("real IP expected: %q, got: %q", "203.0.113.7", realIP)
+	}
+	if w.Code != http.StatusOK || w.Body.String() != "index.html" {
+		t.Errorf("response expected: 200 %q, got: %d %q", "index.html", w.Code, w.Body.String())
+	}
+}
+
+func TestEnrichContext(t *testing.T) {
+	type key struct{}
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+
+	cfg := Config{ContextEnricher: func(ctx context.Context, r *http.Request) context.Context {
+		return context.WithValue(ctx, key{}, "tenant-a")
+	}}
+	if got := enrichContext(cfg, r).Context().Value(key{}); got != "tenant-a" {
+		t.Errorf("context value expected: %q, got: %v", "tenant-a", got)
+	}
+
+	cfg.ContextEnricher = func(context.Context, *http.Request) context.Context { return nil }
+	if got := enrichContext(cfg, r); got != r {
+		t.Error("request expected unchanged when the enricher returns nil")
+	}
+}
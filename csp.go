@@ -0,0 +1,183 @@
+package spaserver
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// WithAutoCSPHashes allows the inline <script> and <style> blocks of
+// index.html under the Content-Security-Policy without 'unsafe-inline'. When
+// the handler is created (and on each Reload) the index is scanned, each
+// inline block is hashed with algos ("sha256", "sha384" or "sha512";
+// defaults to sha256), and the resulting 'sha256-…' sources are appended to
+// the script-src and style-src directives. When the policy has no such
+// directive, one is added from the default-src sources.
+func WithAutoCSPHashes(algos ...string) Option {
+	return func(c *Config) {
+		if len(algos) == 0 {
+			algos = []string{"sha256"}
+		}
+		c.AutoCSPHashes = algos
+	}
+}
+
+// WithTrustedTypes enables the Trusted Types API by appending
+// require-trusted-types-for 'script' and trusted-types <policyNames> to the
+// Content-Security-Policy, including a policy set with WithCSP. With no
+// names, no Trusted Types policies may be created. The SPA must create its
+// policies with trustedTypes.createPolicy before assigning to DOM sinks such
+// as innerHTML, so enabling this may require application changes.
+func WithTrustedTypes(policyNames ...string) Option {
+	return func(c *Config) {
+		c.TrustedTypes = append([]string{}, policyNames...)
+	}
+}
+
+// appendTrustedTypes appends the Trusted Types directives to policy.
+func appendTrustedTypes(policy string, names []string) string {
+	value := "'none'"
+	if len(names) > 0 {
+		value = strings.Join(names, " ")
+	}
+
+	directives := "require-trusted-types-for 'script'; trusted-types " + value
+	policy = strings.TrimRight(strings.TrimSpace(policy), ";")
+	if policy == "" {
+		return directives
+	}
+	return policy + "; " + directives
+}
+
+// trustedTypesName matches a valid Trusted Types policy name.
+var trustedTypesName = regexp.MustCompile(`^[A-Za-z0-9\-#=_/@.%]+$`)
+
+// inlineBlocks returns the content of every inline element of the given tag
+// ("script" or "style") in html. Script elements with a src attribute are
+// skipped. Tag names are matched case-insensitively.
+func inlineBlocks(html []byte, tag string) [][]byte {
+	var blocks [][]byte
+	lower := bytes.ToLower(html)
+	open := []byte("<" + tag)
+	closing := []byte("</" + tag)
+
+	for i := 0; ; {
+		start := bytes.Index(lower[i:], open)
+		if start < 0 {
+			return blocks
+		}
+		start += i + len(open)
+
+		// Require a tag name boundary, so <scripts> is not <script>
+		if start >= len(lower) || !strings.ContainsRune(" \t\r\n/>", rune(lower[start])) {
+			i = start
+			continue
+		}
+
+		end := bytes.IndexByte(lower[start:], '>')
+		if end < 0 {
+			return blocks
+		}
+		attrs := lower[start : start+end]
+		contentStart := start + end + 1
+
+		contentEnd := bytes.Index(lower[contentStart:], closing)
+		if contentEnd < 0 {
+			return blocks
+		}
+		contentEnd += contentStart
+
+		if tag != "script" || !srcAttr.Match(attrs) {
+			blocks = append(blocks, html[contentStart:contentEnd])
+		}
+		i = contentEnd + len(closing)
+	}
+}
+
+// srcAttr matches a src attribute in a lowercased tag.
+var srcAttr = regexp.MustCompile(`(^|\s)src\s*=`)
+
+// cspHashSources returns the CSP hash source ('<algo>-<base64>') of each
+// block for each algorithm.
+func cspHashSources(blocks [][]byte, algos []string) ([]string, error) {
+	var sources []string
+	for _, algo := range algos {
+		newHash, ok := sriHashes[strings.ToLower(algo)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported CSP hash algorithm %q", algo)
+		}
+		for _, b := range blocks {
+			h := newHash()
+			h.Write(b)
+			sources = append(sources, "'"+strings.ToLower(algo)+"-"+base64.StdEncoding.EncodeToString(h.Sum(nil))+"'")
+		}
+	}
+	return sources, nil
+}
+
+// appendCSPSources appends sources to directive in policy. If the directive
+// is absent but default-src is present, the directive is added with the
+// default-src sources so existing restrictions are kept. If neither is
+// present the directive is unrestricted and policy is returned unchanged.
+func appendCSPSources(policy, directive string, sources []string) string {
+	if len(sources) == 0 {
+		return policy
+	}
+
+	var defaultSrc string
+	var directives []string
+	found := false
+	for _, d := range strings.Split(policy, ";") {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(d, " ")
+		switch strings.ToLower(name) {
+		case directive:
+			d = name + " " + withSources(value, sources)
+			found = true
+		case "default-src":
+			defaultSrc = strings.TrimSpace(value)
+		}
+		directives = append(directives, d)
+	}
+
+	if !found {
+		if defaultSrc == "" {
+			return policy
+		}
+		directives = append(directives, directive+" "+withSources(defaultSrc, sources))
+	}
+
+	return strings.Join(directives, "; ")
+}
+
+// withSources appends sources to a directive value. 'none' cannot be
+// combined with other sources, so it is replaced.
+func withSources(value string, sources []string) string {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.EqualFold(value, "'none'") {
+		return strings.Join(sources, " ")
+	}
+	return value + " " + strings.Join(sources, " ")
+}
+
+// autoHashCSP returns policy extended with hash sources for the inline
+// scripts and styles of index.
+func autoHashCSP(policy string, index []byte, algos []string) (string, error) {
+	scripts, err := cspHashSources(inlineBlocks(index, "script"), algos)
+	if err != nil {
+		return "", err
+	}
+	styles, err := cspHashSources(inlineBlocks(index, "style"), algos)
+	if err != nil {
+		return "", err
+	}
+
+	policy = appendCSPSources(policy, "script-src", scripts)
+	policy = appendCSPSources(policy, "style-src", styles)
+	return policy, nil
+}
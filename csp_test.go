@@ -0,0 +1,154 @@
+package spaserver
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func sha256Source(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return "'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+}
+
+func TestInlineBlocks(t *testing.T) {
+	html := []byte(`<html><head>
+<script src="/app.js"></script>
+<SCRIPT type="module">window.config = {};</SCRIPT>
+<scripts>not a script</scripts>
+<style>body { color: red; }</style>
+</head><body><script>console.log(1)</script></body></html>`)
+
+	scripts := inlineBlocks(html, "script")
+	wantScripts := [][]byte{[]byte("window.config = {};"), []byte("console.log(1)")}
+	if !reflect.DeepEqual(scripts, wantScripts) {
+		t.Errorf("scripts expected: %q, got: %q", wantScripts, scripts)
+	}
+
+	styles := inlineBlocks(html, "style")
+	wantStyles := [][]byte{[]byte("body { color: red; }")}
+	if !reflect.DeepEqual(styles, wantStyles) {
+		t.Errorf("styles expected: %q, got: %q", wantStyles, styles)
+	}
+}
+
+func TestAppendCSPSources(t *testing.T) {
+	tt := []struct {
+		name   string
+		policy string
+		want   string
+	}{
+		{name: "existing directive", policy: "default-src 'self'; script-src 'self'", want: "default-src 'self'; script-src 'self' 'sha256-x'"},
+		{name: "derived from default-src", policy: "default-src 'self' https://cdn.example.com", want: "default-src 'self' https://cdn.example.com; script-src 'self' https://cdn.example.com 'sha256-x'"},
+		{name: "none replaced", policy: "default-src 'none'", want: "default-src 'none'; script-src 'sha256-x'"},
+		{name: "unrestricted left alone", policy: "img-src *", want: "img-src *"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := appendCSPSources(tc.policy, "script-src", []string{"'sha256-x'"}); got != tc.want {
+				t.Errorf("expected: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestServeWithAutoCSPHashes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte(`<html><head><script>window.env="prod"</script><style>h1{}</style></head></html>`)},
+	}
+
+	tt := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{
+			name: "default policy",
+			opts: []Option{WithAutoCSPHashes()},
+			want: "default-src 'self'; script-src 'self' " + sha256Source(`window.env="prod"`) + "; style-src 'self' " + sha256Source("h1{}"),
+		},
+		{
+			name: "custom policy",
+			opts: []Option{WithCSP("default-src 'self'; script-src 'self' https://cdn.example.com"), WithAutoCSPHashes("sha256")},
+			want: "default-src 'self'; script-src 'self' https://cdn.example.com " + sha256Source(`window.env="prod"`) + "; style-src 'self' " + sha256Source("h1{}"),
+		},
+		{
+			name: "omitted policy stays omitted",
+			opts: []Option{WithCSP(""), WithAutoCSPHashes()},
+			want: "",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := New(fsys, tc.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Content-Security-Policy"); got != tc.want {
+				t.Errorf("Content-Security-Policy expected: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+
+	if _, err := New(fsys, WithAutoCSPHashes("md5")); err == nil {
+		t.Error("expected error for unsupported algorithm")
+	}
+}
+
+func TestServeWithTrustedTypes(t *testing.T) {
+	tt := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{
+			name: "appended to default policy",
+			opts: []Option{WithTrustedTypes("app", "dompurify")},
+			want: "default-src 'self'; require-trusted-types-for 'script'; trusted-types app dompurify",
+		},
+		{
+			name: "appended to custom policy",
+			opts: []Option{WithCSP("default-src 'self'; img-src *;"), WithTrustedTypes("app")},
+			want: "default-src 'self'; img-src *; require-trusted-types-for 'script'; trusted-types app",
+		},
+		{
+			name: "option order does not matter",
+			opts: []Option{WithTrustedTypes("app"), WithCSP("default-src 'none'")},
+			want: "default-src 'none'; require-trusted-types-for 'script'; trusted-types app",
+		},
+		{
+			name: "no policy names",
+			opts: []Option{WithTrustedTypes()},
+			want: "default-src 'self'; require-trusted-types-for 'script'; trusted-types 'none'",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Serve(fstest.MapFS{"index.html": {Data: []byte("index.html")}}, tc.opts...)
+
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Content-Security-Policy"); got != tc.want {
+				t.Errorf("Content-Security-Policy expected: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+
+	if err := (Config{TrustedTypes: []string{"bad name"}}).Validate(); err == nil {
+		t.Error("expected error for invalid policy name")
+	}
+}
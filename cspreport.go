@@ -0,0 +1,170 @@
+package spaserver
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// CSPReport is a Content-Security-Policy violation report. Its fields and
+// JSON names are those of the application/csp-report format; reports in
+// the Reporting API's application/reports+json format are converted to it.
+type CSPReport struct {
+	DocumentURI        string `json:"document-uri"`
+	Referrer           string `json:"referrer"`
+	BlockedURI         string `json:"blocked-uri"`
+	ViolatedDirective  string `json:"violated-directive"`
+	EffectiveDirective string `json:"effective-directive"`
+	OriginalPolicy     string `json:"original-policy"`
+	Disposition        string `json:"disposition"`
+	SourceFile         string `json:"source-file"`
+	LineNumber         int    `json:"line-number"`
+	ColumnNumber       int    `json:"column-number"`
+	StatusCode         int    `json:"status-code"`
+	ScriptSample       string `json:"script-sample"`
+}
+
+// WithCSPReportEndpoint receives Content-Security-Policy violation reports
+// POSTed to path and passes each one to handler. Both the report-uri
+// (application/csp-report) and the Reporting API (application/reports+json)
+// formats are accepted. Point the policy at the endpoint, e.g. with
+// "report-uri /csp-report" in WithCSP. Reports caused by browser
+// extensions are discarded unless WithCSPReportFilter is used.
+func WithCSPReportEndpoint(path string, handler func(*http.Request, CSPReport)) Option {
+	return func(c *Config) {
+		c.CSPReportPath = path
+		c.CSPReportHandler = handler
+	}
+}
+
+// WithCSPReportFilter passes only the reports for which fn returns true to
+// the WithCSPReportEndpoint handler. It replaces the default filter, which
+// discards reports about chrome-extension://, moz-extension:// and
+// safari-web-extension:// sources.
+func WithCSPReportFilter(fn func(CSPReport) bool) Option {
+	return func(c *Config) {
+		c.CSPReportFilter = fn
+	}
+}
+
+// maxCSPReportSize limits the body of a report request.
+const maxCSPReportSize = 64 << 10
+
+// extensionSchemes are the URL schemes of browser extension resources,
+// which trigger violations the site cannot fix.
+var extensionSchemes = []string{"chrome-extension:", "moz-extension:", "safari-web-extension:", "safari-extension:"}
+
+// notFromExtension is the default report filter. It discards reports
+// about browser extension sources.
+func notFromExtension(report CSPReport) bool {
+	for _, uri := range []string{report.BlockedURI, report.SourceFile} {
+		for _, scheme := range extensionSchemes {
+			if strings.HasPrefix(strings.ToLower(uri), scheme) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// serveCSPReport answers a POST of violation reports to the report endpoint.
+func serveCSPReport(cfg Config, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		serveError(w, "405 Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCSPReportSize))
+	if err != nil {
+		serveError(w, "413 Request Entity Too Large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var reports []CSPReport
+	switch mediaType {
+	case "application/csp-report", "application/json":
+		reports, err = parseCSPReport(body)
+	case "application/reports+json":
+		reports, err = parseReportsJSON(body)
+	default:
+		serveError(w, "415 Unsupported Media Type", http.StatusUnsupportedMediaType)
+		return
+	}
+	if err != nil {
+		serveError(w, "400 Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	filter := cfg.CSPReportFilter
+	if filter == nil {
+		filter = notFromExtension
+	}
+	for _, report := range reports {
+		if filter(report) {
+			cfg.CSPReportHandler(r, report)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseCSPReport parses an application/csp-report body.
+func parseCSPReport(body []byte) ([]CSPReport, error) {
+	var v struct {
+		Report CSPReport `json:"csp-report"`
+	}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return nil, err
+	}
+	return []CSPReport{v.Report}, nil
+}
+
+// parseReportsJSON parses an application/reports+json body, returning its
+// csp-violation reports.
+func parseReportsJSON(body []byte) ([]CSPReport, error) {
+	var v []struct {
+		Type string `json:"type"`
+		Body struct {
+			DocumentURL        string `json:"documentURL"`
+			Referrer           string `json:"referrer"`
+			BlockedURL         string `json:"blockedURL"`
+			EffectiveDirective string `json:"effectiveDirective"`
+			OriginalPolicy     string `json:"originalPolicy"`
+			Disposition        string `json:"disposition"`
+			SourceFile         string `json:"sourceFile"`
+			LineNumber         int    `json:"lineNumber"`
+			ColumnNumber       int    `json:"columnNumber"`
+			StatusCode         int    `json:"statusCode"`
+			Sample             string `json:"sample"`
+		} `json:"body"`
+	}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return nil, err
+	}
+
+	var reports []CSPReport
+	for _, report := range v {
+		if report.Type != "csp-violation" {
+			continue
+		}
+		b := report.Body
+		reports = append(reports, CSPReport{
+			DocumentURI:        b.DocumentURL,
+			Referrer:           b.Referrer,
+			BlockedURI:         b.BlockedURL,
+			ViolatedDirective:  b.EffectiveDirective,
+			EffectiveDirective: b.EffectiveDirective,
+			OriginalPolicy:     b.OriginalPolicy,
+			Disposition:        b.Disposition,
+			SourceFile:         b.SourceFile,
+			LineNumber:         b.LineNumber,
+			ColumnNumber:       b.ColumnNumber,
+			StatusCode:         b.StatusCode,
+			ScriptSample:       b.Sample,
+		})
+	}
+	return reports, nil
+}
@@ -0,0 +1,100 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeWithCSPReportEndpoint(t *testing.T) {
+	fsys := fstest.MapFS{"index.html": {Data: []byte("<html>index</html>")}}
+
+	legacy := `{"csp-report":{"document-uri":"https://example.com/app","blocked-uri":"https://evil.example/x.js","violated-directive":"script-src-elem","effective-directive":"script-src-elem","original-policy":"default-src 'self'","disposition":"enforce","line-number":12,"status-code":200}}`
+	reporting := `[{"type":"csp-violation","age":10,"url":"https://example.com/app","body":{"documentURL":"https://example.com/app","blockedURL":"inline","effectiveDirective":"style-src-attr","originalPolicy":"default-src 'self'","disposition":"report","sample":"color: red","lineNumber":3,"columnNumber":7,"statusCode":200}},{"type":"deprecation","body":{}}]`
+	extension := `{"csp-report":{"document-uri":"https://example.com/app","blocked-uri":"chrome-extension://abc/inject.js","violated-directive":"script-src-elem"}}`
+
+	tt := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		opts        []Option
+		status      int
+		reports     []CSPReport
+	}{
+		{
+			name: "csp-report", method: http.MethodPost, contentType: "application/csp-report", body: legacy,
+			status: http.StatusNoContent,
+			reports: []CSPReport{{
+				DocumentURI: "https://example.com/app", BlockedURI: "https://evil.example/x.js",
+				ViolatedDirective: "script-src-elem", EffectiveDirective: "script-src-elem",
+				OriginalPolicy: "default-src 'self'", Disposition: "enforce", LineNumber: 12, StatusCode: 200,
+			}},
+		},
+		{
+			name: "reports+json", method: http.MethodPost, contentType: "application/reports+json", body: reporting,
+			status: http.StatusNoContent,
+			reports: []CSPReport{{
+				DocumentURI: "https://example.com/app", BlockedURI: "inline",
+				ViolatedDirective: "style-src-attr", EffectiveDirective: "style-src-attr",
+				OriginalPolicy: "default-src 'self'", Disposition: "report", ScriptSample: "color: red",
+				LineNumber: 3, ColumnNumber: 7, StatusCode: 200,
+			}},
+		},
+		{
+			name: "extension discarded", method: http.MethodPost, contentType: "application/csp-report", body: extension,
+			status: http.StatusNoContent,
+		},
+		{
+			name: "custom filter", method: http.MethodPost, contentType: "application/csp-report", body: extension,
+			opts:   []Option{WithCSPReportFilter(func(CSPReport) bool { return true })},
+			status: http.StatusNoContent,
+			reports: []CSPReport{{
+				DocumentURI: "https://example.com/app", BlockedURI: "chrome-extension://abc/inject.js", ViolatedDirective: "script-src-elem",
+			}},
+		},
+		{name: "invalid json", method: http.MethodPost, contentType: "application/csp-report", body: "{", status: http.StatusBadRequest},
+		{name: "unsupported type", method: http.MethodPost, contentType: "text/plain", body: legacy, status: http.StatusUnsupportedMediaType},
+		{name: "get", method: http.MethodGet, status: http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var reports []CSPReport
+			opts := append([]Option{WithCSPReportEndpoint("/csp-report", func(r *http.Request, report CSPReport) {
+				reports = append(reports, report)
+			})}, tc.opts...)
+			h := Serve(fsys, opts...)
+
+			r := httptest.NewRequest(tc.method, "http://www.example.com/csp-report", strings.NewReader(tc.body))
+			r.Header.Set("Content-Type", tc.contentType)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("status expected: %d, got: %d", tc.status, w.Code)
+			}
+			if strings.Contains(w.Body.String(), "index") {
+				t.Errorf("body expected without index.html, got: %q", w.Body.String())
+			}
+			if len(reports) != len(tc.reports) {
+				t.Fatalf("reports expected: %+v, got: %+v", tc.reports, reports)
+			}
+			for i := range reports {
+				if reports[i] != tc.reports[i] {
+					t.Errorf("report expected: %+v, got: %+v", tc.reports[i], reports[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCSPReportValidation(t *testing.T) {
+	fsys := fstest.MapFS{"index.html": {Data: []byte("index.html")}}
+	_, err := New(fsys, WithCSPReportEndpoint("csp-report", func(*http.Request, CSPReport) {}))
+	if want := `csp_report_path: "csp-report" must begin with /`; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("error expected to contain: %q, got: %v", want, err)
+	}
+}
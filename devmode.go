@@ -0,0 +1,244 @@
+package spaserver
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// devReloadPath is the WebSocket endpoint of the live-reload script.
+const devReloadPath = "/_dev/reload"
+
+// devReloadScript reloads the page when the server sends a message on the
+// live-reload WebSocket.
+const devReloadScript = `new WebSocket((location.protocol=="https:"?"wss://":"ws://")+location.host+"` + devReloadPath + `").onmessage=()=>location.reload()`
+
+// websocketGUID is the RFC 6455 handshake key suffix.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// watchDebounce coalesces the burst of file events of a single rebuild.
+const watchDebounce = 100 * time.Millisecond
+
+// DevMode returns a development handler for the SPA in fsys. It serves
+// like Serve, but injects a small live-reload script into index.html that
+// connects to a WebSocket at /_dev/reload, and adds the script's hash to
+// the Content-Security-Policy. Use Watch to reload connected browsers when
+// the build output changes.
+//
+// DevMode is for local development only and must not be used in
+// production: the reload endpoint is unauthenticated and the injected
+// script differs from the build output.
+func DevMode(fsys fs.FS, opts ...Option) *Handler {
+	opts = append(opts, func(c *Config) {
+		c.devReload = true
+	})
+	return Serve(fsys, opts...)
+}
+
+// Watch watches dir and its subdirectories for changes. On a change the
+// handler is reloaded from its current filesystem and, if it was created
+// by DevMode, every connected browser is sent a "reload" message. Closing
+// the returned io.Closer stops watching.
+func Watch(dir string, handler *Handler) (io.Closer, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		return watcher.Add(p)
+	})
+	if err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	w := &devWatcher{watcher: watcher, handler: handler, done: make(chan struct{})}
+	go w.run()
+	return w, nil
+}
+
+// devWatcher reloads a handler on file system events.
+type devWatcher struct {
+	watcher *fsnotify.Watcher
+	handler *Handler
+	done    chan struct{}
+}
+
+func (w *devWatcher) run() {
+	defer close(w.done)
+
+	var timer *time.Timer
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			}
+			// Watch directories created after Watch was called
+			if event.Has(fsnotify.Create) {
+				if fi, err := os.Stat(event.Name); err == nil && fi.IsDir() {
+					w.watcher.Add(event.Name)
+				}
+			}
+			if timer == nil {
+				timer = time.AfterFunc(watchDebounce, w.reload)
+			} else {
+				timer.Reset(watchDebounce)
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			w.handler.cfg.Logger.Warn("spaserver: watch error", "error", err)
+		}
+	}
+}
+
+// reload reloads the handler and notifies connected browsers.
+func (w *devWatcher) reload() {
+	if err := w.handler.Reload(w.handler.current.Load().fsys); err != nil {
+		w.handler.cfg.Logger.Warn("spaserver: reload failed", "error", err)
+	}
+	if w.handler.dev != nil {
+		w.handler.dev.broadcast("reload")
+	}
+}
+
+// Close stops watching.
+func (w *devWatcher) Close() error {
+	err := w.watcher.Close()
+	<-w.done
+	return err
+}
+
+// devReloadCSPSource returns the CSP hash source of the live-reload script.
+func devReloadCSPSource() string {
+	sum := sha256.Sum256([]byte(devReloadScript))
+	return "'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+}
+
+// injectDevReload adds the live-reload script to page.
+func injectDevReload(page []byte) []byte {
+	return injectHead(page, "<script>"+devReloadScript+"</script>")
+}
+
+// devReloader is the live-reload WebSocket endpoint. It only sends
+// messages; anything the browser sends is discarded.
+type devReloader struct {
+	mu      sync.Mutex
+	clients map[net.Conn]struct{}
+}
+
+// newDevReloader returns a devReloader with no clients.
+func newDevReloader() *devReloader {
+	return &devReloader{clients: make(map[net.Conn]struct{})}
+}
+
+// ServeHTTP upgrades the request to a WebSocket connection.
+func (d *devReloader) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !headerContainsToken(r.Header, "Connection", "upgrade") ||
+		!headerContainsToken(r.Header, "Upgrade", "websocket") ||
+		r.Header.Get("Sec-WebSocket-Version") != "13" || key == "" {
+		serveError(w, "400 Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		serveError(w, "500 Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return
+	}
+
+	d.mu.Lock()
+	d.clients[conn] = struct{}{}
+	d.mu.Unlock()
+
+	go d.discard(conn, rw.Reader)
+}
+
+// discard reads from conn until the browser disconnects, then removes it.
+func (d *devReloader) discard(conn net.Conn, r *bufio.Reader) {
+	io.Copy(io.Discard, r)
+	d.remove(conn)
+}
+
+// remove closes conn and forgets it.
+func (d *devReloader) remove(conn net.Conn) {
+	d.mu.Lock()
+	delete(d.clients, conn)
+	d.mu.Unlock()
+	conn.Close()
+}
+
+// broadcast sends msg as a WebSocket text frame to every client.
+func (d *devReloader) broadcast(msg string) {
+	frame, err := websocketTextFrame(msg)
+	if err != nil {
+		return
+	}
+
+	d.mu.Lock()
+	conns := make([]net.Conn, 0, len(d.clients))
+	for conn := range d.clients {
+		conns = append(conns, conn)
+	}
+	d.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if _, err := conn.Write(frame); err != nil {
+			d.remove(conn)
+		}
+	}
+}
+
+// websocketTextFrame returns an unmasked, unfragmented text frame.
+func websocketTextFrame(msg string) ([]byte, error) {
+	if len(msg) > 125 {
+		return nil, errors.New("websocket message too long")
+	}
+	return append([]byte{0x81, byte(len(msg))}, msg...), nil
+}
+
+// headerContainsToken reports whether the comma-separated header name
+// contains token, case-insensitively.
+func headerContainsToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
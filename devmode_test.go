@@ -0,0 +1,122 @@
+package spaserver
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestDevModeInjectsReloadScript(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("<html><head></head><body></body></html>")},
+	}
+	h := DevMode(fsys)
+
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	script := "<script>" + devReloadScript + "</script>"
+	if !strings.Contains(w.Body.String(), script+"</head>") {
+		t.Errorf("body expected to contain the reload script, got: %q", w.Body.String())
+	}
+	if len(devReloadScript) >= 200 {
+		t.Errorf("reload script expected to be under 200 bytes, got: %d", len(devReloadScript))
+	}
+
+	want := "default-src 'self'; script-src 'self' " + devReloadCSPSource()
+	if got := w.Header().Get("Content-Security-Policy"); got != want {
+		t.Errorf("Content-Security-Policy expected: %q, got: %q", want, got)
+	}
+}
+
+func TestServeDoesNotInjectReloadScript(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("<html><head></head></html>")},
+	}
+	h := Serve(fsys)
+
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if strings.Contains(w.Body.String(), "<script>") {
+		t.Errorf("body expected without reload script, got: %q", w.Body.String())
+	}
+}
+
+func TestDevModeReloadRequiresWebSocket(t *testing.T) {
+	h := DevMode(fstest.MapFS{"index.html": {Data: []byte("index.html")}})
+
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+devReloadPath, nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status expected: %d, got: %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestWatchSendsReload(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("<head></head>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	h := DevMode(os.DirFS(dir), WithGzip())
+	watcher, err := Watch(dir, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	io.WriteString(conn, "GET "+devReloadPath+" HTTP/1.1\r\n"+
+		"Host: "+srv.Listener.Addr().String()+"\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Accept-Encoding: gzip\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"+
+		"Sec-WebSocket-Version: 13\r\n\r\n")
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status expected: %d, got: %d", http.StatusSwitchingProtocols, resp.StatusCode)
+	}
+	if got, want := resp.Header.Get("Sec-WebSocket-Accept"), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="; got != want {
+		t.Errorf("Sec-WebSocket-Accept expected: %q, got: %q", want, got)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "main.js"), []byte("console.log(1)"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	frame := make([]byte, 2+len("reload"))
+	if _, err := io.ReadFull(br, frame); err != nil {
+		t.Fatal(err)
+	}
+	if want := append([]byte{0x81, 6}, "reload"...); string(frame) != string(want) {
+		t.Errorf("frame expected: %q, got: %q", want, frame)
+	}
+}
@@ -0,0 +1,77 @@
+package spaserver
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"net/http"
+)
+
+// digestAlgorithms are the hash algorithms of WithContentDigest, by their
+// RFC 9530 names.
+var digestAlgorithms = map[string]func() hash.Hash{
+	"sha-256": sha256.New,
+	"sha-512": sha512.New,
+}
+
+// WithContentDigest sends a Content-Digest header (RFC 9530) with every
+// file and HTML page, e.g. Content-Digest: sha-256=:X48E9q…=:, so that
+// clients and intermediaries can verify the response body end to end.
+// algo is "sha-256" or "sha-512". Digests of static files are cached per
+// file like content hash ETags; index pages, which may be modified by
+// injections, are digested for each response. The digest covers the bytes
+// sent: responses compressed on the fly and partial responses to Range
+// requests are sent without one, and pre-encoded gzip responses carry the
+// digest of the encoded file.
+func WithContentDigest(algo string) Option {
+	return func(c *Config) {
+		c.ContentDigest = algo
+	}
+}
+
+// formatDigest returns the Content-Digest value of the algo hash sum.
+func formatDigest(algo string, sum []byte) string {
+	return algo + "=:" + base64.StdEncoding.EncodeToString(sum) + ":"
+}
+
+// digestBytes returns the Content-Digest value of b.
+func digestBytes(algo string, b []byte) string {
+	h := digestAlgorithms[algo]()
+	h.Write(b)
+	return formatDigest(algo, h.Sum(nil))
+}
+
+// setBodyDigest sets the Content-Digest header of a response to r with
+// the body b.
+func setBodyDigest(cfg Config, w http.ResponseWriter, r *http.Request, b []byte) {
+	if cfg.ContentDigest == "" || r.Header.Get("Range") != "" {
+		return
+	}
+	w.Header().Set("Content-Digest", digestBytes(cfg.ContentDigest, b))
+}
+
+// setFileDigest sets the Content-Digest header of a response to r with the
+// static file name, using and filling the site's digest cache.
+func setFileDigest(s *site, cfg Config, w http.ResponseWriter, r *http.Request, name string, info fs.FileInfo, content io.ReadSeeker) error {
+	if cfg.ContentDigest == "" || r.Header.Get("Range") != "" {
+		return nil
+	}
+	sum, err := s.digests.sum(name, info.ModTime(), info.Size(), content, digestAlgorithms[cfg.ContentDigest])
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Digest", formatDigest(cfg.ContentDigest, sum))
+	return nil
+}
+
+// validateContentDigest reports an unsupported digest algorithm.
+func validateContentDigest(algo string) []error {
+	if _, ok := digestAlgorithms[algo]; algo != "" && !ok {
+		return []error{fmt.Errorf("content_digest: unsupported algorithm %q, must be sha-256 or sha-512", algo)}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package spaserver
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeWithContentDigest(t *testing.T) {
+	script := strings.Repeat("console.log('hello');\n", 100)
+	fsys := fstest.MapFS{
+		"index.html":       {Data: []byte("<html><head></head><body></body></html>")},
+		"js/app.js":        {Data: []byte(script)},
+		"docs/index.html":  {Data: []byte("<html>docs</html>")},
+		"css/main.css":     {Data: []byte("body {}")},
+		"img/favicon.webp": {Data: []byte("webp")},
+	}
+	sha256Digest := func(b string) string {
+		sum := sha256.Sum256([]byte(b))
+		return "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"
+	}
+	sha512Digest := func(b string) string {
+		sum := sha512.Sum512([]byte(b))
+		return "sha-512=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"
+	}
+
+	tt := []struct {
+		name    string
+		opts    []Option
+		url     string
+		headers map[string]string
+		want    func(body string) string
+	}{
+		{name: "static file", opts: []Option{WithContentDigest("sha-256")}, url: "/css/main.css", want: sha256Digest},
+		{name: "sha-512", opts: []Option{WithContentDigest("sha-512")}, url: "/css/main.css", want: sha512Digest},
+		{name: "index", opts: []Option{WithContentDigest("sha-256")}, url: "/", want: sha256Digest},
+		{name: "modified index", opts: []Option{WithContentDigest("sha-256"), WithEnvVars("APP_")}, url: "/app/route", want: sha256Digest},
+		{name: "mpa directory index", opts: []Option{WithContentDigest("sha-256"), WithMPAMode()}, url: "/docs/", want: sha256Digest},
+		{name: "range request", opts: []Option{WithContentDigest("sha-256")}, url: "/css/main.css", headers: map[string]string{"Range": "bytes=0-1"}},
+		{name: "compressed", opts: []Option{WithContentDigest("sha-256"), WithGzip()}, url: "/js/app.js", headers: map[string]string{"Accept-Encoding": "gzip"}},
+		{name: "not compressed", opts: []Option{WithContentDigest("sha-256"), WithGzip()}, url: "/js/app.js", want: sha256Digest},
+		{name: "not found", opts: []Option{WithContentDigest("sha-256"), WithBlockHiddenFiles()}, url: "/.env"},
+		{name: "no option", url: "/css/main.css"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := New(fsys, tc.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+tc.url, nil)
+			for k, v := range tc.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			var want string
+			if tc.want != nil {
+				want = tc.want(w.Body.String())
+			}
+			if got := w.Header().Get("Content-Digest"); got != want {
+				t.Errorf("Content-Digest expected: %q, got: %q", want, got)
+			}
+		})
+	}
+}
+
+func TestServeWithContentDigestPreencoded(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("index.html")},
+		"js/app.js":  {Data: []byte(strings.Repeat("console.log('hello');\n", 100))},
+	}
+	h, err := New(fsys, WithContentDigest("sha-256"), WithPreencodeGzip())
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitPreencoded(t, h)
+
+	for range 2 {
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com/js/app.js", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+			t.Fatalf("Content-Encoding expected: %q, got: %q", "gzip", got)
+		}
+		body, err := io.ReadAll(w.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256(body)
+		want := "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"
+		if got := w.Header().Get("Content-Digest"); got != want {
+			t.Errorf("Content-Digest expected: %q, got: %q", want, got)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package spaserver
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// WithDownloadPattern serves static files matching the path.Match glob
+// with Content-Disposition: attachment, so browsers download them instead
+// of rendering them, e.g. CSV exports or generated PDFs. A glob starting
+// with "/" matches the URL path ("/exports/*"); any other glob matches the
+// base name ("*.csv"). The download's file name is the last segment of the
+// URL path. Multiple calls accumulate.
+func WithDownloadPattern(glob string) Option {
+	return func(c *Config) {
+		c.DownloadPatterns = append(c.DownloadPatterns, glob)
+	}
+}
+
+// setDownloadHeaders sets Content-Disposition for a static file requested
+// at the clean URL path upath, if it matches a download pattern.
+func setDownloadHeaders(cfg Config, w http.ResponseWriter, upath string) {
+	base := path.Base(upath)
+	for _, p := range cfg.DownloadPatterns {
+		subject := base
+		if strings.HasPrefix(p, "/") {
+			subject = upath
+		}
+		if ok, _ := path.Match(p, subject); ok {
+			w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": base}))
+			return
+		}
+	}
+}
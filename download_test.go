@@ -0,0 +1,49 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeWithDownloadPattern(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":              {Data: []byte("index.html")},
+		"exports/report.csv":      {Data: []byte("a,b\n1,2\n")},
+		"exports/summary.pdf":     {Data: []byte("%PDF-1.7")},
+		"docs/guide.pdf":          {Data: []byte("%PDF-1.7")},
+		"exports/résumé 2024.csv": {Data: []byte("a,b\n")},
+	}
+	h := Serve(fsys, WithDownloadPattern("*.csv"), WithDownloadPattern("/exports/*"))
+
+	tt := []struct {
+		url         string
+		disposition string
+		contentType string
+	}{
+		{url: "/exports/report.csv", disposition: `attachment; filename=report.csv`, contentType: "text/csv; charset=utf-8"},
+		{url: "/exports/summary.pdf", disposition: `attachment; filename=summary.pdf`, contentType: "application/pdf"},
+		{url: "/exports/r%C3%A9sum%C3%A9%202024.csv", disposition: `attachment; filename*=utf-8''r%C3%A9sum%C3%A9%202024.csv`, contentType: "text/csv; charset=utf-8"},
+		{url: "/docs/guide.pdf", contentType: "application/pdf"},
+		{url: "/", contentType: "text/html; charset=utf-8"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.url, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+tc.url, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status expected: %d, got: %d", http.StatusOK, w.Code)
+			}
+			if got := w.Header().Get("Content-Disposition"); got != tc.disposition {
+				t.Errorf("Content-Disposition expected: %q, got: %q", tc.disposition, got)
+			}
+			if got := w.Header().Get("Content-Type"); got != tc.contentType {
+				t.Errorf("Content-Type expected: %q, got: %q", tc.contentType, got)
+			}
+		})
+	}
+}
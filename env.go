@@ -0,0 +1,65 @@
+package spaserver
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+)
+
+// WithEnvVars exposes the environment variables whose names start with
+// prefix to the SPA as window.__ENV__, injected before </head> in index
+// responses. The prefix is stripped, so with prefix "APP_" the variable
+// APP_API_URL becomes window.__ENV__.API_URL. Variables are read when the
+// handler is created and on each Reload. An empty prefix disables the
+// option rather than exposing the whole environment.
+//
+// The script is inline, so the Content-Security-Policy must allow it, for
+// example with WithAutoCSPHashes.
+func WithEnvVars(prefix string) Option {
+	return func(c *Config) {
+		c.EnvVarPrefix = prefix
+	}
+}
+
+// WithEnvVarMap exposes vars to the SPA as window.__ENV__ like WithEnvVars,
+// allowing values other than strings, such as booleans and numbers. Entries
+// take precedence over environment variables of the same name. Multiple
+// calls accumulate.
+func WithEnvVarMap(vars map[string]any) Option {
+	return func(c *Config) {
+		if c.EnvVars == nil {
+			c.EnvVars = make(map[string]any)
+		}
+		for k, v := range vars {
+			c.EnvVars[k] = v
+		}
+	}
+}
+
+// envScript returns the <script> tag defining window.__ENV__, or "" when
+// no variables are configured. Values are JSON encoded, which escapes <, >
+// and & so that a value cannot close the script element.
+func envScript(cfg Config) (string, error) {
+	if cfg.EnvVarPrefix == "" && cfg.EnvVars == nil {
+		return "", nil
+	}
+
+	vars := make(map[string]any)
+	if cfg.EnvVarPrefix != "" {
+		for _, kv := range os.Environ() {
+			k, v, _ := strings.Cut(kv, "=")
+			if name, ok := strings.CutPrefix(k, cfg.EnvVarPrefix); ok && name != "" {
+				vars[name] = v
+			}
+		}
+	}
+	for k, v := range cfg.EnvVars {
+		vars[k] = v
+	}
+
+	b, err := json.Marshal(vars)
+	if err != nil {
+		return "", err
+	}
+	return "<script>window.__ENV__=" + string(b) + "</script>", nil
+}
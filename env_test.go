@@ -0,0 +1,73 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeWithEnvVars(t *testing.T) {
+	t.Setenv("SPATEST_API_URL", "https://api.example.com")
+	t.Setenv("SPATEST_XSS", "</script><script>alert(1)</script>")
+	t.Setenv("OTHER_SECRET", "hunter2")
+
+	fsys := fstest.MapFS{"index.html": {Data: []byte("<html><head></head><body></body></html>")}}
+
+	tt := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{
+			name: "environment",
+			opts: []Option{WithEnvVars("SPATEST_")},
+			want: `<script>window.__ENV__={"API_URL":"https://api.example.com","XSS":"\u003c/script\u003e\u003cscript\u003ealert(1)\u003c/script\u003e"}</script>`,
+		},
+		{
+			name: "map",
+			opts: []Option{WithEnvVarMap(map[string]any{"DEBUG": true, "RETRIES": 3, "NAME": "app"})},
+			want: `<script>window.__ENV__={"DEBUG":true,"NAME":"app","RETRIES":3}</script>`,
+		},
+		{
+			name: "map overrides environment",
+			opts: []Option{WithEnvVars("SPATEST_"), WithEnvVarMap(map[string]any{"API_URL": "http://localhost", "XSS": nil})},
+			want: `<script>window.__ENV__={"API_URL":"http://localhost","XSS":null}</script>`,
+		},
+		{
+			name: "empty prefix disabled",
+			opts: []Option{WithEnvVars("")},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Serve(fsys, tc.opts...)
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			want := "<html><head>" + tc.want + "</head><body></body></html>"
+			if got := w.Body.String(); got != want {
+				t.Errorf("body expected: %q, got: %q", want, got)
+			}
+			if strings.Contains(w.Body.String(), "hunter2") {
+				t.Error("body exposes an environment variable without the prefix")
+			}
+		})
+	}
+}
+
+func TestServeWithEnvVarsAutoCSPHashes(t *testing.T) {
+	fsys := fstest.MapFS{"index.html": {Data: []byte("<head></head>")}}
+	h := Serve(fsys, WithEnvVarMap(map[string]any{"A": 1}), WithAutoCSPHashes())
+
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if got := w.Header().Get("Content-Security-Policy"); !strings.Contains(got, "script-src 'self' 'sha256-") {
+		t.Errorf("Content-Security-Policy expected to allow the env script, got: %q", got)
+	}
+}
@@ -0,0 +1,100 @@
+package spaserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"sync"
+	"time"
+)
+
+// WithContentHashETags sends a strong ETag with every static file: the
+// hex SHA-256 of its content, e.g. "9f86d0…". Unlike modification times,
+// content hashes are identical across deployments of unchanged files, so
+// CDNs and browsers keep their cached copies. Hashes are cached per file
+// and recomputed when its modification time or size changes.
+func WithContentHashETags() Option {
+	return func(c *Config) {
+		c.ContentHashETags = true
+	}
+}
+
+// WithFileCacheTTL expires entries of the per-file caches, the content
+// hash ETags, Content-Digest sums and pre-encoded gzip files, ttl after
+// they were stored, so that files changed without a change to their
+// modification time or size, such as by deploys within the same second or
+// on NFS mounts with coarse timestamps, are read again. An expired entry is
+// treated as a miss. Within the TTL, such a file is still served with its
+// old bytes under the cached strong ETag, so clients and caches may keep
+// the old content until the entry expires. By default entries do not
+// expire and are only invalidated by a change of modification time or
+// size.
+func WithFileCacheTTL(ttl time.Duration) Option {
+	return func(c *Config) {
+		c.FileCacheTTL = Duration(ttl)
+	}
+}
+
+// expired reports whether a cache entry stored at stored has outlived ttl
+// at now. A zero ttl never expires.
+func expired(stored, now time.Time, ttl time.Duration) bool {
+	return ttl > 0 && now.Sub(stored) > ttl
+}
+
+// hashEntry is a cached content hash.
+type hashEntry struct {
+	modTime time.Time
+	size    int64
+	sum     []byte
+	stored  time.Time
+}
+
+// hashCache caches content hashes by file name. All hashes of a cache must
+// be computed by the same hash function.
+type hashCache struct {
+	mu      sync.Mutex
+	entries map[string]hashEntry
+	ttl     time.Duration // zero keeps entries until the file changes
+	now     func() time.Time
+}
+
+// sum returns the newHash hash of the file name with the given
+// modification time and size, hashing content on a cache miss. content is
+// left positioned at its start.
+func (c *hashCache) sum(name string, modTime time.Time, size int64, content io.ReadSeeker, newHash func() hash.Hash) ([]byte, error) {
+	c.mu.Lock()
+	e, ok := c.entries[name]
+	c.mu.Unlock()
+	if ok && e.modTime.Equal(modTime) && e.size == size && !expired(e.stored, c.now(), c.ttl) {
+		return e.sum, nil
+	}
+
+	h := newHash()
+	if _, err := io.Copy(h, content); err != nil {
+		return nil, err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	sum := h.Sum(nil)
+
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = make(map[string]hashEntry)
+	}
+	c.entries[name] = hashEntry{modTime: modTime, size: size, sum: sum, stored: c.now()}
+	c.mu.Unlock()
+
+	return sum, nil
+}
+
+// contentETag returns the content hash ETag of the file name, using and
+// filling the site's ETag cache.
+func (s *site) contentETag(name string, modTime time.Time, size int64, content io.ReadSeeker) (string, error) {
+	sum, err := s.etags.sum(name, modTime, size, content, sha256.New)
+	if err != nil {
+		return "", err
+	}
+	return `"` + hex.EncodeToString(sum) + `"`, nil
+}
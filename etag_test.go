@@ -0,0 +1,96 @@
+package spaserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestServeWithContentHashETags(t *testing.T) {
+	content := []byte("console.log('app')")
+	sum := sha256.Sum256(content)
+	want := `"` + hex.EncodeToString(sum[:]) + `"`
+
+	fsys := fstest.MapFS{
+		"index.html":  {Data: []byte("index.html")},
+		"js/main.js":  {Data: content, ModTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		"js/other.js": {Data: []byte("console.log('other')")},
+	}
+	h := Serve(fsys, WithContentHashETags())
+
+	get := func(url string, header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+url, nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	if got := get("/js/main.js", nil).Header().Get("ETag"); got != want {
+		t.Errorf("ETag expected: %q, got: %q", want, got)
+	}
+	if got := get("/js/other.js", nil).Header().Get("ETag"); got == want || got == "" {
+		t.Errorf("ETag of other file expected to differ from %q, got: %q", want, got)
+	}
+
+	// A redeploy touches the file without changing its content
+	fsys["js/main.js"].ModTime = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	if got := get("/js/main.js", nil).Header().Get("ETag"); got != want {
+		t.Errorf("ETag after mod time change expected: %q, got: %q", want, got)
+	}
+
+	w := get("/js/main.js", http.Header{"If-None-Match": {want}})
+	if w.Code != http.StatusNotModified {
+		t.Errorf("status expected: %d, got: %d", http.StatusNotModified, w.Code)
+	}
+
+	if got := get("/", nil).Header().Get("ETag"); got != "" {
+		t.Errorf("index ETag expected to be absent, got: %q", got)
+	}
+}
+
+func TestServeWithContentHashETagsChangedContent(t *testing.T) {
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("index.html")},
+		"js/main.js": {Data: []byte("v1"), ModTime: modTime},
+	}
+	h := Serve(fsys, WithContentHashETags())
+
+	etag := func() string {
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com/js/main.js", nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Header().Get("ETag")
+	}
+
+	before := etag()
+	fsys["js/main.js"] = &fstest.MapFile{Data: []byte("v2 longer"), ModTime: modTime.Add(time.Second)}
+	if after := etag(); after == before {
+		t.Errorf("ETag expected to change with content, got: %q", after)
+	}
+}
+
+func TestServeWithContentHashETagsCompressed(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":   {Data: []byte("index.html")},
+		"css/main.css": {Data: []byte(strings.Repeat("body {}\n", 256))},
+	}
+	h := Serve(fsys, WithContentHashETags(), WithGzip())
+
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/css/main.css", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if got := w.Header().Get("ETag"); !strings.HasPrefix(got, `W/"`) {
+		t.Errorf("ETag of compressed response expected to be weak, got: %q", got)
+	}
+}
@@ -0,0 +1,65 @@
+package spaserver
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+func FuzzPath(f *testing.F) {
+	for _, seed := range []string{
+		"css/main.css",
+		"../go.mod",
+		"%2e%2e/go.mod",
+		"%2e%2e%2fgo.mod",
+		"%252e%252e/go.mod",
+		"..%2f..%2fgo.mod",
+		"css/..%2f..%2fgo.mod",
+		"..\\go.mod",
+		"%2e%2e%5cgo.mod",
+		"css/main.css%00.html",
+		"%00",
+		"..%c0%afgo.mod",
+		"..%e2%88%95go.mod",
+		"..%ef%bc%8fgo.mod",
+		"//go.mod",
+		"/../../go.mod",
+		"index.html/..",
+		strings.Repeat("a/", 4096),
+		strings.Repeat("../", 1024) + "go.mod",
+	} {
+		f.Add(seed)
+	}
+
+	// go.mod lives outside testdata, so serving it would be an escape
+	outside, err := os.ReadFile("go.mod")
+	if err != nil {
+		f.Fatal(err)
+	}
+	h := Serve(os.DirFS("testdata"))
+
+	f.Fuzz(func(t *testing.T, p string) {
+		u, err := url.Parse("http://www.example.com/" + p)
+		if err != nil {
+			t.Skip()
+		}
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+		r.URL = u
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		switch w.Code {
+		case http.StatusOK, http.StatusMovedPermanently, http.StatusBadRequest,
+			http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError:
+		default:
+			t.Errorf("path %q: unexpected status %d", u.Path, w.Code)
+		}
+		if bytes.Contains(w.Body.Bytes(), outside) {
+			t.Errorf("path %q: served a file outside testdata", u.Path)
+		}
+	})
+}
@@ -0,0 +1,30 @@
+package spaserver
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// WithGRPCWebPassthrough forwards gRPC-Web requests, those with a
+// Content-Type of application/grpc-web, application/grpc-web-text or one
+// of their +proto and +json variants, to handler. They are subject to the
+// IP filter and basic auth but otherwise bypass all SPA handling, so
+// responses carry no SPA headers such as Content-Security-Policy or
+// Cache-Control. This lets a
+// single binary serve the SPA and its gRPC-Web API without Envoy.
+func WithGRPCWebPassthrough(handler http.Handler) Option {
+	return func(c *Config) {
+		c.GRPCWebHandler = handler
+	}
+}
+
+// isGRPCWeb reports whether r is a gRPC-Web request.
+func isGRPCWeb(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	base, _, _ := strings.Cut(mediaType, "+")
+	return base == "application/grpc-web" || base == "application/grpc-web-text"
+}
@@ -0,0 +1,93 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeWithGRPCWebPassthrough(t *testing.T) {
+	fsys := fstest.MapFS{"index.html": {Data: []byte("<html>index</html>")}}
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/grpc-web+proto")
+		w.Header().Set("Grpc-Status", "0")
+		w.Write([]byte("grpc"))
+	})
+	h := Serve(fsys, WithGRPCWebPassthrough(api))
+
+	tt := []struct {
+		contentType string
+		body        string
+	}{
+		{contentType: "application/grpc-web", body: "grpc"},
+		{contentType: "application/grpc-web+proto", body: "grpc"},
+		{contentType: "application/grpc-web-text+proto", body: "grpc"},
+		{contentType: "application/grpc", body: "<html>index</html>"},
+		{contentType: "application/json", body: "<html>index</html>"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.contentType, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "http://www.example.com/api.v1.Greeter/SayHello", strings.NewReader("\x00\x00\x00\x00\x00"))
+			r.Header.Set("Content-Type", tc.contentType)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Body.String(); got != tc.body {
+				t.Errorf("body expected: %q, got: %q", tc.body, got)
+			}
+			if tc.body != "grpc" {
+				return
+			}
+			for _, header := range []string{"Content-Security-Policy", "Cache-Control", "X-Frame-Options"} {
+				if got := w.Header().Get(header); got != "" {
+					t.Errorf("%s expected to be absent, got: %q", header, got)
+				}
+			}
+		})
+	}
+}
+
+func TestGRPCWebPassthroughAccessControl(t *testing.T) {
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("grpc"))
+	})
+	h := Serve(fstest.MapFS{"index.html": {Data: []byte("index.html")}},
+		WithGRPCWebPassthrough(api),
+		WithIPBlocklist("192.0.2.0/24"),
+		WithBasicAuthFunc("api", func(user, pass string) (bool, error) { return user == "admin" && pass == "s3cret", nil }),
+	)
+
+	tt := []struct {
+		name       string
+		remoteAddr string
+		auth       bool
+		status     int
+	}{
+		{name: "authorized", remoteAddr: "203.0.113.7:1234", auth: true, status: http.StatusOK},
+		{name: "blocked", remoteAddr: "192.0.2.1:1234", auth: true, status: http.StatusForbidden},
+		{name: "unauthenticated", remoteAddr: "203.0.113.7:1234", status: http.StatusUnauthorized},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "http://www.example.com/api.v1.Greeter/SayHello", strings.NewReader("\x00\x00\x00\x00\x00"))
+			r.RemoteAddr = tc.remoteAddr
+			r.Header.Set("Content-Type", "application/grpc-web+proto")
+			if tc.auth {
+				r.SetBasicAuth("admin", "s3cret")
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("status expected: %d, got: %d", tc.status, w.Code)
+			}
+			if tc.status != http.StatusOK && w.Body.String() == "grpc" {
+				t.Error("refused request expected not to reach the handler")
+			}
+		})
+	}
+}
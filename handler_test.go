@@ -0,0 +1,232 @@
+package spaserver
+
+import (
+	"io/fs"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+// gateFS blocks Open of the named file until release is closed.
+type gateFS struct {
+	fs.FS
+	name    string
+	opened  chan struct{}
+	release chan struct{}
+}
+
+func (g *gateFS) Open(name string) (fs.File, error) {
+	if name == g.name {
+		close(g.opened)
+		<-g.release
+	}
+	return g.FS.Open(name)
+}
+
+func TestHandlerReload(t *testing.T) {
+	v1 := fstest.MapFS{
+		"index.html": {Data: []byte("v1")},
+		"app.v1.js":  {Data: []byte("app v1")},
+	}
+	v2 := fstest.MapFS{
+		"index.html": {Data: []byte("v2")},
+		"app.v2.js":  {Data: []byte("app v2")},
+	}
+
+	h := Serve(v1)
+
+	get := func(url string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	if body := get("http://www.example.com/").Body.String(); body != "v1" {
+		t.Fatalf("body before reload expected: v1, got: %s", body)
+	}
+
+	if err := h.Reload(v2); err != nil {
+		t.Fatal(err)
+	}
+
+	if body := get("http://www.example.com/").Body.String(); body != "v2" {
+		t.Errorf("body after reload expected: v2, got: %s", body)
+	}
+	if body := get("http://www.example.com/app.v2.js").Body.String(); body != "app v2" {
+		t.Errorf("new asset body expected: app v2, got: %s", body)
+	}
+	if body := get("http://www.example.com/app.v1.js").Body.String(); body != "v2" {
+		t.Errorf("removed asset expected to fall back to new index, got: %s", body)
+	}
+}
+
+func TestHandlerReloadInFlight(t *testing.T) {
+	old := &gateFS{
+		FS:      fstest.MapFS{"index.html": {Data: []byte("old")}},
+		name:    "route",
+		opened:  make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	h := Serve(old)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com/route", nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		done <- w
+	}()
+
+	<-old.opened
+	if err := h.Reload(fstest.MapFS{"index.html": {Data: []byte("new")}}); err != nil {
+		t.Fatal(err)
+	}
+	close(old.release)
+
+	if body := (<-done).Body.String(); body != "old" {
+		t.Errorf("in-flight request expected to complete against old filesystem, got: %s", body)
+	}
+}
+
+func TestHandlerReloadErrors(t *testing.T) {
+	v1 := fstest.MapFS{"index.html": {Data: []byte("v1")}}
+	h := Serve(v1, WithWebpackManifest("manifest.json"))
+
+	if err := h.Reload(nil); err == nil {
+		t.Error("expected error reloading nil filesystem")
+	}
+	if err := h.Reload(fstest.MapFS{"index.html": {Data: []byte("v2")}}); err == nil {
+		t.Error("expected error reloading filesystem without manifest")
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if body := w.Body.String(); body != "v1" {
+		t.Errorf("failed reload expected to keep serving v1, got: %s", body)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tt := []struct {
+		name string
+		fsys fs.FS
+		opts []Option
+		err  string
+	}{
+		{
+			name: "valid filesystem",
+			fsys: fstest.MapFS{"index.html": {Data: []byte("index.html")}},
+		},
+		{
+			name: "missing index",
+			fsys: fstest.MapFS{"app.js": {Data: []byte("app")}},
+			err:  "index.html not found at the filesystem root",
+		},
+		{
+			name: "index is a directory",
+			fsys: fstest.MapFS{"index.html/app.js": {Data: []byte("app")}},
+			err:  "index.html at the filesystem root is a directory",
+		},
+		{
+			name: "missing webpack manifest",
+			fsys: fstest.MapFS{"index.html": {Data: []byte("index.html")}},
+			opts: []Option{WithWebpackManifest("manifest.json")},
+			err:  "webpack manifest manifest.json not loaded",
+		},
+		{
+			name: "invalid options",
+			fsys: fstest.MapFS{"index.html": {Data: []byte("index.html")}},
+			opts: []Option{WithCSP("default-src 'self'\r\nX-Injected: 1")},
+			err:  "invalid configuration",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := New(tc.fsys, tc.opts...)
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got: %v", err)
+				}
+				if h == nil {
+					t.Fatal("expected handler")
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("error expected to contain %q, got: %v", tc.err, err)
+			}
+			if verr := Validate(tc.fsys, tc.opts...); verr == nil || verr.Error() != err.Error() {
+				t.Errorf("Validate expected: %v, got: %v", err, verr)
+			}
+		})
+	}
+}
+
+func TestServeParallel(t *testing.T) {
+	script := strings.Repeat("console.log('app');\n", 100)
+	fsys := fstest.MapFS{
+		"index.html":   {Data: []byte("index.html")},
+		"js/app.js":    {Data: []byte(script)},
+		"css/main.css": {Data: []byte("body {}")},
+	}
+	h, err := New(fsys,
+		WithIndexPreload(),
+		WithSingleFlight(),
+		WithContentHashETags(),
+		WithPreencodeGzip(),
+		WithBlockHiddenFiles(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := []struct {
+		url            string
+		acceptEncoding string
+		status         int
+		body           string
+	}{
+		{url: "/", status: http.StatusOK, body: "index.html"},
+		{url: "/some/route", status: http.StatusOK, body: "index.html"},
+		{url: "/js/app.js", status: http.StatusOK, body: script},
+		{url: "/js/app.js", acceptEncoding: "gzip", status: http.StatusOK},
+		{url: "/css/main.css", status: http.StatusOK, body: "body {}"},
+		{url: "/.env", status: http.StatusNotFound, body: "404 Page Not Found\n"},
+	}
+
+	var wg sync.WaitGroup
+	for range 1000 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := requests[rand.IntN(len(requests))]
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+req.url, nil)
+			if req.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", req.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != req.status {
+				t.Errorf("%s: status expected: %d, got: %d", req.url, req.status, w.Code)
+			}
+			if w.Body.Len() == 0 {
+				t.Errorf("%s: expected a non-empty body", req.url)
+			}
+			if req.body != "" && w.Body.String() != req.body {
+				t.Errorf("%s: body expected: %q, got: %q", req.url, req.body, w.Body.String())
+			}
+		}()
+	}
+	wg.Wait()
+}
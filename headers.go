@@ -0,0 +1,272 @@
+package spaserver
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+)
+
+// WithDocumentPolicy sends a Document-Policy header with HTML page
+// responses, e.g. "document-write=?0".
+//
+// Document-Policy configures the behavior of the document itself (for
+// example disallowing document.write, unoptimized images or oversized
+// images), and applies only to the document that receives it.
+// Permissions-Policy instead gates access to powerful features and device
+// APIs (camera, geolocation, payment) and is inherited by embedded frames.
+// A feature belongs to exactly one of the two headers.
+func WithDocumentPolicy(policy string) Option {
+	return func(c *Config) {
+		c.DocumentPolicy = policy
+	}
+}
+
+// WithOriginAgentCluster sends Origin-Agent-Cluster: ?1 with HTML page
+// responses, asking the browser to place the document in an origin-keyed
+// agent cluster isolated from other same-site origins. It only affects SPAs
+// that relax document.domain to script across subdomains. Off by default
+// because browser support is still incomplete.
+func WithOriginAgentCluster() Option {
+	return func(c *Config) {
+		c.OriginAgentCluster = true
+	}
+}
+
+// WithXRobotsTag sends an X-Robots-Tag header with HTML page responses,
+// e.g. "noindex, nofollow" on staging and preview deployments to keep them
+// out of search results without changing the HTML.
+func WithXRobotsTag(directives string) Option {
+	return func(c *Config) {
+		c.XRobotsTag = directives
+	}
+}
+
+// WithCanonicalResponseHeader sends Link: <baseURL + path>; rel="canonical"
+// with index responses, where path is the request's URL path, e.g.
+// WithCanonicalResponseHeader("https://www.example.com"). Crawlers and
+// non-HTML clients can read the canonical URL without parsing the page.
+func WithCanonicalResponseHeader(baseURL string) Option {
+	return func(c *Config) {
+		c.CanonicalBaseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// setCanonicalLink adds the canonical Link header for the index response
+// to r.
+func setCanonicalLink(cfg Config, w http.ResponseWriter, r *http.Request) {
+	if cfg.CanonicalBaseURL != "" {
+		w.Header().Add("Link", "<"+cfg.CanonicalBaseURL+r.URL.EscapedPath()+`>; rel="canonical"`)
+	}
+}
+
+// WithDeploymentVersion sends X-Deployment-Version: version, such as a Git
+// SHA or release tag, with every response the handler writes, including
+// redirects and errors, so the active deployment can be checked from the
+// browser's developer tools or curl.
+func WithDeploymentVersion(version string) Option {
+	return func(c *Config) {
+		c.DeploymentVersion = version
+	}
+}
+
+// WithDNSPrefetchControl sends X-DNS-Prefetch-Control: on with HTML page
+// responses when allow is true, letting the browser resolve the domains of
+// links in the page ahead of time. By default pages are sent with
+// X-DNS-Prefetch-Control: off, which browsers already assume for HTTPS
+// pages but not for HTTP ones.
+func WithDNSPrefetchControl(allow bool) Option {
+	return func(c *Config) {
+		c.DNSPrefetchControl = &allow
+	}
+}
+
+// clearSiteDataTypes are the data types of the Clear-Site-Data header, and
+// the default types cleared by WithClearSiteData.
+var (
+	clearSiteDataTypes   = []string{"cache", "cookies", "storage", "executionContexts"}
+	defaultClearSiteData = []string{"cache", "cookies", "storage"}
+)
+
+// WithClearSiteData sends a Clear-Site-Data header with every response to
+// path, e.g. "/logout", asking the browser to wipe the given types of data
+// stored for the origin: "cache", "cookies", "storage" or
+// "executionContexts". Without types, cache, cookies and storage are
+// cleared. The response is otherwise served as usual.
+func WithClearSiteData(path string, types ...string) Option {
+	return func(c *Config) {
+		if c.ClearSiteData == nil {
+			c.ClearSiteData = make(map[string][]string)
+		}
+		if len(types) == 0 {
+			types = defaultClearSiteData
+		}
+		c.ClearSiteData[path] = types
+	}
+}
+
+// setClearSiteData sets the Clear-Site-Data header configured for upath.
+func setClearSiteData(cfg Config, w http.ResponseWriter, upath string) {
+	types, ok := cfg.ClearSiteData[upath]
+	if !ok {
+		return
+	}
+	quoted := make([]string, len(types))
+	for i, t := range types {
+		quoted[i] = `"` + t + `"`
+	}
+	w.Header().Set("Clear-Site-Data", strings.Join(quoted, ","))
+}
+
+// validateClearSiteData reports invalid paths and data types.
+func validateClearSiteData(paths map[string][]string) []error {
+	var errs []error
+	for p, types := range paths {
+		if !strings.HasPrefix(p, "/") {
+			errs = append(errs, fmt.Errorf("clear_site_data: %q must begin with /", p))
+		}
+		for _, t := range types {
+			if !slices.Contains(clearSiteDataTypes, t) {
+				errs = append(errs, fmt.Errorf("clear_site_data: %q: invalid type %q", p, t))
+			}
+		}
+	}
+	return errs
+}
+
+// WithHideServerHeaders removes the Server, X-Powered-By and
+// X-AspNet-Version headers from every response, including those set by
+// middleware wrapping the handler, since security scanners flag them as
+// information disclosure.
+func WithHideServerHeaders() Option {
+	return func(c *Config) {
+		c.HideServerHeaders = true
+	}
+}
+
+// serverHeaders are removed by WithHideServerHeaders.
+var serverHeaders = []string{"Server", "X-Powered-By", "X-AspNet-Version"}
+
+// hideHeadersWriter strips serverHeaders just before the header is
+// written, after all handlers have had a chance to set them.
+type hideHeadersWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (hw *hideHeadersWriter) WriteHeader(code int) {
+	if !hw.wroteHeader {
+		hw.wroteHeader = true
+		for _, k := range serverHeaders {
+			hw.Header().Del(k)
+		}
+	}
+	hw.ResponseWriter.WriteHeader(code)
+}
+
+func (hw *hideHeadersWriter) Write(b []byte) (int, error) {
+	if !hw.wroteHeader {
+		hw.WriteHeader(http.StatusOK)
+	}
+	return hw.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the wrapped ResponseWriter for http.ResponseController.
+func (hw *hideHeadersWriter) Unwrap() http.ResponseWriter {
+	return hw.ResponseWriter
+}
+
+func (hw *hideHeadersWriter) Flush() {
+	if !hw.wroteHeader {
+		hw.WriteHeader(http.StatusOK)
+	}
+	if f, ok := hw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// HeaderRule adds Headers to the responses for URL paths matching Pattern,
+// a path.Match glob such as "/api/*".
+type HeaderRule struct {
+	Pattern string      `json:"pattern" yaml:"pattern"`
+	Headers http.Header `json:"headers" yaml:"headers"`
+}
+
+// WithHeaders adds headers to responses whose URL path matches the
+// path.Match glob pattern, e.g.
+//
+//	WithHeaders("/api/*", http.Header{"X-API": {"v2"}})
+//
+// Values are appended to any the header already has. Headers the server
+// sets itself, such as Content-Security-Policy, X-Frame-Options and
+// X-Content-Type-Options, take precedence. Multiple calls accumulate.
+func WithHeaders(pattern string, headers http.Header) Option {
+	return func(c *Config) {
+		c.Headers = append(c.Headers, HeaderRule{Pattern: pattern, Headers: headers})
+	}
+}
+
+// setRuleHeaders adds the headers of the rules matching upath. Headers
+// already set are left untouched so that rules cannot override the
+// server's security headers.
+func setRuleHeaders(rules []HeaderRule, w http.ResponseWriter, upath string) {
+	var preset map[string]bool
+	for _, rule := range rules {
+		if ok, _ := path.Match(rule.Pattern, upath); !ok {
+			continue
+		}
+		if preset == nil {
+			preset = make(map[string]bool, len(w.Header()))
+			for k := range w.Header() {
+				preset[k] = true
+			}
+		}
+		for k, vs := range rule.Headers {
+			k = http.CanonicalHeaderKey(k)
+			if preset[k] {
+				continue
+			}
+			for _, v := range vs {
+				w.Header().Add(k, v)
+			}
+		}
+	}
+}
+
+// validateHeaderRules reports invalid patterns, header names and values.
+func validateHeaderRules(rules []HeaderRule) []error {
+	var errs []error
+	for _, rule := range rules {
+		if _, err := path.Match(rule.Pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("headers: invalid pattern %q: %w", rule.Pattern, err))
+		}
+		for k, vs := range rule.Headers {
+			if !validHeaderName(k) {
+				errs = append(errs, fmt.Errorf("headers: %q: invalid header name", k))
+			}
+			for _, v := range vs {
+				if strings.ContainsAny(v, "\r\n\x00") {
+					errs = append(errs, fmt.Errorf("headers: %q: value must not contain CR, LF or NUL characters", k))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// validHeaderName reports whether name is an RFC 7230 token.
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range []byte(name) {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,325 @@
+package spaserver
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// headerTestFS is a minimal SPA used by the header option tests.
+var headerTestFS = fstest.MapFS{
+	"index.html":   {Data: []byte("index.html")},
+	"css/main.css": {Data: []byte("body {}")},
+}
+
+// assertPageHeader checks that header is set to want on the index response
+// and absent from static file responses.
+func assertPageHeader(t *testing.T, h http.Handler, header, want string) {
+	t.Helper()
+
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if got := w.Header().Get(header); got != want {
+		t.Errorf("index %s expected: %q, got: %q", header, want, got)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "http://www.example.com/css/main.css", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if got := w.Header().Get(header); got != "" {
+		t.Errorf("static file %s expected to be absent, got: %q", header, got)
+	}
+}
+
+func TestServeWithDocumentPolicy(t *testing.T) {
+	assertPageHeader(t, Serve(headerTestFS, WithDocumentPolicy("document-write=?0")), "Document-Policy", "document-write=?0")
+	assertPageHeader(t, Serve(headerTestFS), "Document-Policy", "")
+
+	if _, err := New(headerTestFS, WithDocumentPolicy("document-write=?0\r\nSet-Cookie: x=1")); err == nil {
+		t.Error("expected error for policy containing CRLF")
+	}
+}
+
+func TestServeWithOriginAgentCluster(t *testing.T) {
+	assertPageHeader(t, Serve(headerTestFS, WithOriginAgentCluster()), "Origin-Agent-Cluster", "?1")
+	assertPageHeader(t, Serve(headerTestFS), "Origin-Agent-Cluster", "")
+}
+
+func TestServeWithXRobotsTag(t *testing.T) {
+	assertPageHeader(t, Serve(headerTestFS, WithXRobotsTag("noindex, nofollow")), "X-Robots-Tag", "noindex, nofollow")
+	assertPageHeader(t, Serve(headerTestFS), "X-Robots-Tag", "")
+
+	if _, err := New(headerTestFS, WithXRobotsTag("noindex\r\nSet-Cookie: x=1")); err == nil {
+		t.Error("expected error for directives containing CRLF")
+	}
+}
+
+func TestServeWithCanonicalResponseHeader(t *testing.T) {
+	tt := []struct {
+		name string
+		opts []Option
+		url  string
+		want string
+	}{
+		{name: "root", opts: []Option{WithCanonicalResponseHeader("https://www.example.com")}, url: "http://example.com/", want: `<https://www.example.com/>; rel="canonical"`},
+		{name: "route", opts: []Option{WithCanonicalResponseHeader("https://www.example.com/")}, url: "http://example.com/some/route?q=1", want: `<https://www.example.com/some/route>; rel="canonical"`},
+		{name: "escaped path", opts: []Option{WithCanonicalResponseHeader("https://www.example.com")}, url: "http://example.com/caf%C3%A9", want: `<https://www.example.com/caf%C3%A9>; rel="canonical"`},
+		{name: "static file", opts: []Option{WithCanonicalResponseHeader("https://www.example.com")}, url: "http://example.com/css/main.css"},
+		{name: "no option", url: "http://example.com/"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Serve(headerTestFS, tc.opts...)
+			r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Link"); got != tc.want {
+				t.Errorf("Link expected: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+
+	if _, err := New(headerTestFS, WithCanonicalResponseHeader("www.example.com")); err == nil {
+		t.Error("expected error for a base URL without scheme")
+	}
+}
+
+// brokenFS fails to open broken.css with an error that is neither
+// fs.ErrNotExist nor fs.ErrPermission.
+type brokenFS struct {
+	fs.FS
+}
+
+func (f brokenFS) Open(name string) (fs.File, error) {
+	if name == "broken.css" {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("i/o error")}
+	}
+	return f.FS.Open(name)
+}
+
+func TestServeWithDeploymentVersion(t *testing.T) {
+	h := Serve(brokenFS{headerTestFS}, WithDeploymentVersion("abc123"), WithBlockHiddenFiles())
+
+	tt := []struct {
+		name   string
+		url    string
+		status int
+	}{
+		{name: "index", url: "/", status: http.StatusOK},
+		{name: "static file", url: "/css/main.css", status: http.StatusOK},
+		{name: "redirect", url: "/index.html", status: http.StatusMovedPermanently},
+		{name: "not found", url: "/.env", status: http.StatusNotFound},
+		{name: "internal error", url: "/broken.css", status: http.StatusInternalServerError},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+tc.url, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("status expected: %d, got: %d", tc.status, w.Code)
+			}
+			if got := w.Header().Get("X-Deployment-Version"); got != "abc123" {
+				t.Errorf("X-Deployment-Version expected: %q, got: %q", "abc123", got)
+			}
+		})
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+	w := httptest.NewRecorder()
+	Serve(headerTestFS).ServeHTTP(w, r)
+	if got := w.Header().Get("X-Deployment-Version"); got != "" {
+		t.Errorf("X-Deployment-Version expected to be absent, got: %q", got)
+	}
+}
+
+func TestServeWithDNSPrefetchControl(t *testing.T) {
+	assertPageHeader(t, Serve(headerTestFS), "X-DNS-Prefetch-Control", "off")
+	assertPageHeader(t, Serve(headerTestFS, WithDNSPrefetchControl(false)), "X-DNS-Prefetch-Control", "off")
+	assertPageHeader(t, Serve(headerTestFS, WithDNSPrefetchControl(true)), "X-DNS-Prefetch-Control", "on")
+}
+
+func TestServeWithClearSiteData(t *testing.T) {
+	h := Serve(headerTestFS,
+		WithClearSiteData("/logout"),
+		WithClearSiteData("/reset", "storage", "executionContexts"),
+	)
+
+	tt := []struct {
+		url  string
+		code int
+		want string
+	}{
+		{url: "http://www.example.com/logout", code: http.StatusOK, want: `"cache","cookies","storage"`},
+		{url: "http://www.example.com/reset/", code: http.StatusOK, want: `"storage","executionContexts"`},
+		{url: "http://www.example.com/", code: http.StatusOK},
+		{url: "http://www.example.com/css/main.css", code: http.StatusOK},
+	}
+
+	for _, tc := range tt {
+		r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != tc.code {
+			t.Errorf("%s status expected: %d, got: %d", tc.url, tc.code, w.Code)
+		}
+		if got := w.Header().Get("Clear-Site-Data"); got != tc.want {
+			t.Errorf("%s Clear-Site-Data expected: %q, got: %q", tc.url, tc.want, got)
+		}
+	}
+
+	_, err := New(headerTestFS, WithClearSiteData("/logout", "cookies", "*"))
+	if want := `clear_site_data: "/logout": invalid type "*"`; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("error expected to contain: %q, got: %v", want, err)
+	}
+}
+
+func TestServeWithHideServerHeaders(t *testing.T) {
+	tt := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{name: "hidden", opts: []Option{WithHideServerHeaders()}},
+		{name: "hidden with gzip", opts: []Option{WithHideServerHeaders(), WithGzip()}},
+		{name: "not hidden", want: "nginx"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			spa := Serve(headerTestFS, tc.opts...)
+			// Simulate middleware that advertises the server stack
+			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Server", "nginx")
+				w.Header().Set("X-Powered-By", "nginx")
+				w.Header().Set("X-AspNet-Version", "nginx")
+				spa.ServeHTTP(w, r)
+			})
+
+			for _, url := range []string{"http://www.example.com/", "http://www.example.com/css/main.css"} {
+				r := httptest.NewRequest(http.MethodGet, url, nil)
+				r.Header.Set("Accept-Encoding", "gzip")
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+
+				for _, header := range []string{"Server", "X-Powered-By", "X-AspNet-Version"} {
+					if got := w.Header().Get(header); got != tc.want {
+						t.Errorf("%s %s expected: %q, got: %q", url, header, tc.want, got)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestServeWithHeaders(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":      {Data: []byte("index.html")},
+		"api/status.json": {Data: []byte("{}")},
+		"css/main.css":    {Data: []byte("body {}")},
+	}
+	h := Serve(fsys,
+		WithHeaders("/api/*", http.Header{"X-API": {"v2"}, "x-frame-options": {"SAMEORIGIN"}}),
+		WithHeaders("/api/*", http.Header{"X-API": {"v3"}}),
+		WithHeaders("/*", http.Header{"X-Content-Type-Options": {"sniff"}}),
+		WithHeaders("/", http.Header{"X-Frame-Options": {"SAMEORIGIN"}}),
+	)
+
+	tt := []struct {
+		name         string
+		url          string
+		api          []string
+		frameOptions string
+	}{
+		{name: "matching path", url: "http://www.example.com/api/status.json", api: []string{"v2", "v3"}, frameOptions: "SAMEORIGIN"},
+		{name: "spa fallback", url: "http://www.example.com/api/users", api: []string{"v2", "v3"}, frameOptions: "DENY"},
+		{name: "security header kept", url: "http://www.example.com/", frameOptions: "DENY"},
+		{name: "non-matching path", url: "http://www.example.com/css/main.css"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Header().Values("X-API"); strings.Join(got, ",") != strings.Join(tc.api, ",") {
+				t.Errorf("X-API expected: %q, got: %q", tc.api, got)
+			}
+			if got := w.Header().Get("X-Frame-Options"); got != tc.frameOptions {
+				t.Errorf("X-Frame-Options expected: %q, got: %q", tc.frameOptions, got)
+			}
+			if got := w.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+				t.Errorf("X-Content-Type-Options expected: %q, got: %q", "nosniff", got)
+			}
+		})
+	}
+}
+
+func TestServeNosniffBeforeSPAHandling(t *testing.T) {
+	passthrough := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("events"))
+	})
+	h := Serve(headerTestFS,
+		WithMetricsEndpoint("/metrics"),
+		WithMaxPathLength(16),
+		WithSSEPassthrough("/events", passthrough),
+	)
+
+	tt := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{name: "metrics", path: "/metrics", status: http.StatusOK},
+		{name: "oversized path", path: "/" + strings.Repeat("a", 32), status: http.StatusBadRequest},
+		{name: "passthrough", path: "/events", status: http.StatusOK},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+tc.path, nil)
+			r.Header.Set("Accept", "text/event-stream")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("status expected: %d, got: %d", tc.status, w.Code)
+			}
+			if got := w.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+				t.Errorf("X-Content-Type-Options expected: %q, got: %q", "nosniff", got)
+			}
+		})
+	}
+}
+
+func TestNewWithHeadersInvalid(t *testing.T) {
+	tt := []struct {
+		name    string
+		pattern string
+		headers http.Header
+	}{
+		{name: "invalid name", pattern: "/api/*", headers: http.Header{"X API": {"v2"}}},
+		{name: "empty name", pattern: "/api/*", headers: http.Header{"": {"v2"}}},
+		{name: "invalid value", pattern: "/api/*", headers: http.Header{"X-API": {"v2\r\nSet-Cookie: x=1"}}},
+		{name: "invalid pattern", pattern: "/api/[", headers: http.Header{"X-API": {"v2"}}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := New(headerTestFS, WithHeaders(tc.pattern, tc.headers)); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package spaserver
+
+import (
+	"encoding/json"
+	"regexp"
+)
+
+// WithImportMap injects an import map mapping bare ES module specifiers to
+// URLs into index responses, e.g.
+//
+//	WithImportMap(map[string]string{"react": "https://esm.sh/react@18"})
+//
+// The <script type="importmap"> element is inserted before the first
+// <script type="module"> tag, which it must precede, or before </head>
+// when the page has none. Multiple calls accumulate.
+func WithImportMap(imports map[string]string) Option {
+	return func(c *Config) {
+		if c.ImportMap == nil {
+			c.ImportMap = make(map[string]string)
+		}
+		for specifier, url := range imports {
+			c.ImportMap[specifier] = url
+		}
+	}
+}
+
+// moduleScriptTag matches the opening tag of a module script.
+var moduleScriptTag = regexp.MustCompile(`(?i)<script\b[^>]*\btype\s*=\s*["']?module\b`)
+
+// importMapScript returns the import map <script> element for imports.
+// The JSON escapes <, > and & so that no specifier or URL can close the
+// element.
+func importMapScript(imports map[string]string) string {
+	// Marshaling a map of strings cannot fail
+	b, _ := json.Marshal(struct {
+		Imports map[string]string `json:"imports"`
+	}{imports})
+	return `<script type="importmap">` + string(b) + `</script>`
+}
+
+// injectImportMap inserts script before the first module script of page.
+// It reports false, leaving page unchanged, when there is none.
+func injectImportMap(page []byte, script string) ([]byte, bool) {
+	loc := moduleScriptTag.FindIndex(page)
+	if loc == nil {
+		return page, false
+	}
+	return insertAt(page, loc[0], script), true
+}
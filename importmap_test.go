@@ -0,0 +1,74 @@
+package spaserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeWithImportMap(t *testing.T) {
+	imports := map[string]string{
+		"react":     "https://esm.sh/react@18",
+		"lib/":      "/vendor/lib/",
+		"</script>": "/x?a=1&b=<2>",
+	}
+	importMap := `<script type="importmap">{"imports":{"\u003c/script\u003e":"/x?a=1\u0026b=\u003c2\u003e","lib/":"/vendor/lib/","react":"https://esm.sh/react@18"}}</script>`
+
+	tt := []struct {
+		name  string
+		index string
+		want  string
+	}{
+		{
+			name:  "before module script",
+			index: `<head><script src="/legacy.js"></script><SCRIPT type='module' src="/main.js"></SCRIPT><script type="module">import "react"</script></head>`,
+			want:  `<head><script src="/legacy.js"></script>` + importMap + `<SCRIPT type='module' src="/main.js"></SCRIPT><script type="module">import "react"</script></head>`,
+		},
+		{
+			name:  "module script in body",
+			index: `<head><title>App</title></head><body><script type=module src="/main.js"></script></body>`,
+			want:  `<head><title>App</title></head><body>` + importMap + `<script type=module src="/main.js"></script></body>`,
+		},
+		{
+			name:  "no module script",
+			index: `<head><title>App</title></head>`,
+			want:  `<head><title>App</title>` + importMap + `</head>`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			fsys := fstest.MapFS{"index.html": {Data: []byte(tc.index)}}
+			h := Serve(fsys, WithImportMap(imports))
+
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Body.String(); got != tc.want {
+				t.Errorf("body expected: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestImportMapScriptJSON(t *testing.T) {
+	script := importMapScript(map[string]string{"react": "https://esm.sh/react@18", "</script>": "&"})
+
+	m := regexp.MustCompile(`^<script type="importmap">(.*)</script>$`).FindStringSubmatch(script)
+	if m == nil {
+		t.Fatalf("import map script malformed: %q", script)
+	}
+	var got struct {
+		Imports map[string]string `json:"imports"`
+	}
+	if err := json.Unmarshal([]byte(m[1]), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Imports["react"] != "https://esm.sh/react@18" || got.Imports["</script>"] != "&" || len(got.Imports) != 2 {
+		t.Errorf("imports expected to round-trip, got: %q", got.Imports)
+	}
+}
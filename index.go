@@ -0,0 +1,37 @@
+package spaserver
+
+import (
+	"io/fs"
+	"time"
+)
+
+// WithIndexPreload reads index.html once when the handler is created, and
+// again on each Reload, and serves it from memory instead of reading the
+// filesystem on every SPA fallback. Changes to index.html on disk are not
+// picked up until Reload is called.
+func WithIndexPreload() Option {
+	return func(c *Config) {
+		c.IndexPreload = true
+	}
+}
+
+// WithIndexLastModified sends the modification time of index.html as the
+// Last-Modified header of index responses, so CDNs can revalidate the page
+// by date. The no-cache headers are sent as before.
+func WithIndexLastModified() Option {
+	return func(c *Config) {
+		c.IndexLastModified = true
+	}
+}
+
+// indexModTime returns the modification time passed to http.ServeContent
+// for the index file name: the file's own when cfg.IndexLastModified is
+// set, otherwise the Unix epoch, which ServeContent omits.
+func (s *site) indexModTime(cfg Config, name string) time.Time {
+	if cfg.IndexLastModified {
+		if fi, err := fs.Stat(s.fsys, name); err == nil {
+			return fi.ModTime()
+		}
+	}
+	return time.Unix(0, 0)
+}
@@ -0,0 +1,80 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestServeWithIndexPreload(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("v1")},
+	}
+	h := Serve(fsys, WithIndexPreload())
+
+	get := func() string {
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com/some/route", nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Body.String()
+	}
+
+	// Changes on disk are not visible until Reload.
+	fsys["index.html"] = &fstest.MapFile{Data: []byte("v2")}
+	if body := get(); body != "v1" {
+		t.Errorf("body expected preloaded: v1, got: %s", body)
+	}
+
+	if err := h.Reload(fsys); err != nil {
+		t.Fatal(err)
+	}
+	if body := get(); body != "v2" {
+		t.Errorf("body after reload expected: v2, got: %s", body)
+	}
+
+	if err := h.Reload(fstest.MapFS{}); err == nil {
+		t.Error("expected reload error when index.html is missing")
+	}
+	if body := get(); body != "v2" {
+		t.Errorf("body after failed reload expected: v2, got: %s", body)
+	}
+}
+
+func TestServeWithIndexLastModified(t *testing.T) {
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("index.html"), ModTime: modTime},
+	}
+
+	tt := []struct {
+		name   string
+		opts   []Option
+		method string
+		want   string
+	}{
+		{name: "get", opts: []Option{WithIndexLastModified()}, method: http.MethodGet, want: modTime.Format(http.TimeFormat)},
+		{name: "head", opts: []Option{WithIndexLastModified()}, method: http.MethodHead, want: modTime.Format(http.TimeFormat)},
+		{name: "no option", method: http.MethodGet, want: ""},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Serve(fsys, tc.opts...)
+			r := httptest.NewRequest(tc.method, "http://www.example.com/some/route", nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Last-Modified"); got != tc.want {
+				t.Errorf("Last-Modified expected: %q, got: %q", tc.want, got)
+			}
+			if got := w.Header().Get("Cache-Control"); got != noCacheHeaders["Cache-Control"] {
+				t.Errorf("Cache-Control expected: %q, got: %q", noCacheHeaders["Cache-Control"], got)
+			}
+			if got := w.Header().Get("Expires"); got != epoch {
+				t.Errorf("Expires expected: %q, got: %q", epoch, got)
+			}
+		})
+	}
+}
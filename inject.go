@@ -0,0 +1,113 @@
+package spaserver
+
+import (
+	"regexp"
+	"strings"
+)
+
+// WithHeadInjection inserts html immediately before the closing </head>
+// tag of every index response, e.g. critical CSS, a polyfill loader or
+// dns-prefetch hints. Pages without </head> are served unchanged. With
+// WithAutoCSPHashes, inline scripts and styles in html are hashed like
+// those of index.html. Multiple calls accumulate in order.
+func WithHeadInjection(html string) Option {
+	return func(c *Config) {
+		c.HeadInjections = append(c.HeadInjections, html)
+	}
+}
+
+// WithBodyInjection inserts html immediately before the closing </body>
+// tag of every index response, or at the end when there is none. Use it
+// for analytics snippets or chat widgets that must load after the SPA.
+// With WithAutoCSPHashes, inline scripts and styles in html are hashed
+// like those of index.html. Multiple calls accumulate in order.
+func WithBodyInjection(html string) Option {
+	return func(c *Config) {
+		c.BodyInjections = append(c.BodyInjections, html)
+	}
+}
+
+// closeTag matches the closing </head> and </body> tags.
+var closeTag = regexp.MustCompile(`(?i)</(?:head|body)\s*>`)
+
+// injectHTML returns a copy of page with head inserted before the first
+// </head> tag and body inserted before the last </body> tag, or appended
+// when there is none. Both markers are found in a single scan of page.
+// page is never modified, as it may be the preloaded index.
+func injectHTML(page []byte, head, body string) []byte {
+	if head == "" && body == "" {
+		return page
+	}
+
+	headAt, bodyAt := -1, len(page)
+	for _, loc := range closeTag.FindAllIndex(page, -1) {
+		if page[loc[0]+2]|0x20 == 'h' {
+			if headAt < 0 {
+				headAt = loc[0]
+			}
+		} else {
+			bodyAt = loc[0]
+		}
+	}
+	if headAt < 0 {
+		head, headAt = "", 0
+	}
+
+	// Insert in document order; a </body> before </head> is malformed but
+	// must not corrupt the page
+	first, second := head, body
+	firstAt, secondAt := headAt, bodyAt
+	if bodyAt < headAt {
+		first, second = body, head
+		firstAt, secondAt = bodyAt, headAt
+	}
+
+	out := make([]byte, 0, len(page)+len(head)+len(body))
+	out = append(out, page[:firstAt]...)
+	out = append(out, first...)
+	out = append(out, page[firstAt:secondAt]...)
+	out = append(out, second...)
+	return append(out, page[secondAt:]...)
+}
+
+// insertAt returns a copy of page with s inserted at offset i.
+func insertAt(page []byte, i int, s string) []byte {
+	out := make([]byte, 0, len(page)+len(s))
+	out = append(out, page[:i]...)
+	out = append(out, s...)
+	return append(out, page[i:]...)
+}
+
+// injectHead inserts tags before the closing </head> tag of page.
+func injectHead(page []byte, tags string) []byte {
+	return injectHTML(page, tags, "")
+}
+
+// modifiesIndex reports whether index responses differ from the index
+// file, so that their length is only known after injection.
+func (s *site) modifiesIndex(cfg Config) bool {
+	return s.importMap != "" || s.envScript != "" || s.speculationRules != "" ||
+		cfg.PWA != nil || len(cfg.HeadInjections) > 0 || len(cfg.BodyInjections) > 0 ||
+		cfg.devReload
+}
+
+// injectIndex applies the configured HTML injections to an index page of
+// s.
+func (s *site) injectIndex(cfg Config, page []byte) []byte {
+	var head strings.Builder
+	if s.importMap != "" {
+		var ok bool
+		if page, ok = injectImportMap(page, s.importMap); !ok {
+			head.WriteString(s.importMap)
+		}
+	}
+	head.WriteString(s.envScript)
+	head.WriteString(s.speculationRules)
+	if cfg.PWA != nil {
+		head.WriteString(pwaTags(page, cfg.PWA))
+	}
+	for _, html := range cfg.HeadInjections {
+		head.WriteString(html)
+	}
+	return injectHTML(page, head.String(), strings.Join(cfg.BodyInjections, ""))
+}
@@ -0,0 +1,138 @@
+package spaserver
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeWithBodyInjection(t *testing.T) {
+	snippet := `<script src="/chat.js"></script>`
+
+	tt := []struct {
+		name  string
+		index string
+		want  string
+	}{
+		{name: "before body", index: "<html><body><div id=app></div></body></html>", want: `<html><body><div id=app></div><script src="/chat.js"></script></body></html>`},
+		{name: "case insensitive", index: "<HTML><BODY></BODY></HTML>", want: `<HTML><BODY><script src="/chat.js"></script></BODY></HTML>`},
+		{name: "last body tag", index: "<body><script>x='</body>'</script></body>", want: `<body><script>x='</body>'</script><script src="/chat.js"></script></body>`},
+		{name: "no body", index: "<div id=app></div>", want: `<div id=app></div><script src="/chat.js"></script>`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			fsys := fstest.MapFS{"index.html": {Data: []byte(tc.index)}}
+			h := Serve(fsys, WithIndexPreload(), WithBodyInjection(snippet))
+
+			for i := 0; i < 2; i++ {
+				r := httptest.NewRequest(http.MethodGet, "http://www.example.com/dashboard", nil)
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+
+				if got := w.Body.String(); got != tc.want {
+					t.Errorf("body expected: %q, got: %q", tc.want, got)
+				}
+				if got, want := w.Header().Get("Content-Length"), strconv.Itoa(len(tc.want)); got != want {
+					t.Errorf("Content-Length expected: %q, got: %q", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestServeWithBodyInjectionAutoCSPHashes(t *testing.T) {
+	fsys := fstest.MapFS{"index.html": {Data: []byte("<html><body></body></html>")}}
+	h := Serve(fsys, WithAutoCSPHashes(), WithBodyInjection("<script>track()</script>"))
+
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	sum := sha256.Sum256([]byte("track()"))
+	want := "default-src 'self'; script-src 'self' 'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+	if got := w.Header().Get("Content-Security-Policy"); got != want {
+		t.Errorf("Content-Security-Policy expected: %q, got: %q", want, got)
+	}
+}
+
+func TestServeWithHeadInjection(t *testing.T) {
+	css := "<style>body{margin:0}</style>"
+	snippet := `<script src="/chat.js"></script>`
+
+	tt := []struct {
+		name  string
+		opts  []Option
+		index string
+		want  string
+	}{
+		{
+			name:  "head",
+			opts:  []Option{WithHeadInjection(css)},
+			index: "<html><head><title>App</title></Head><body></body></html>",
+			want:  "<html><head><title>App</title><style>body{margin:0}</style></Head><body></body></html>",
+		},
+		{
+			name:  "head and body",
+			opts:  []Option{WithHeadInjection(css), WithBodyInjection(snippet)},
+			index: "<html><head></head><body></body></html>",
+			want:  `<html><head><style>body{margin:0}</style></head><body><script src="/chat.js"></script></body></html>`,
+		},
+		{
+			name:  "accumulate in order",
+			opts:  []Option{WithHeadInjection("<a>"), WithHeadInjection("<b>")},
+			index: "<head></head>",
+			want:  "<head><a><b></head>",
+		},
+		{
+			name:  "with pwa",
+			opts:  []Option{WithPWA("/manifest.json", ""), WithHeadInjection(css)},
+			index: "<head></head>",
+			want:  `<head><link rel="manifest" href="/manifest.json"><style>body{margin:0}</style></head>`,
+		},
+		{
+			name:  "no head",
+			opts:  []Option{WithHeadInjection(css), WithBodyInjection(snippet)},
+			index: "<body></body>",
+			want:  `<body><script src="/chat.js"></script></body>`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			fsys := fstest.MapFS{
+				"index.html":    {Data: []byte(tc.index)},
+				"manifest.json": {Data: []byte("{}")},
+			}
+			h := Serve(fsys, tc.opts...)
+
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Body.String(); got != tc.want {
+				t.Errorf("body expected: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestInjectHTMLMalformed(t *testing.T) {
+	got := string(injectHTML([]byte("</body><p></head>"), "H", "B"))
+	if want := "B</body><p>H</head>"; got != want {
+		t.Errorf("injectHTML expected: %q, got: %q", want, got)
+	}
+}
+
+func BenchmarkInjectHTML(b *testing.B) {
+	page := []byte("<html><head><title>App</title></head><body>" + strings.Repeat("<div></div>", 1000) + "</body></html>")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		injectHTML(page, "<style>body{margin:0}</style>", `<script src="/chat.js"></script>`)
+	}
+}
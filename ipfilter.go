@@ -0,0 +1,105 @@
+package spaserver
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+)
+
+// WithIPAllowlist restricts access to clients whose IP address is within
+// one of cidrs. Other clients receive 403 Forbidden. Both IPv4 and IPv6
+// CIDRs are supported, and a bare address matches only itself. Multiple
+// calls accumulate.
+func WithIPAllowlist(cidrs ...string) Option {
+	return func(c *Config) {
+		c.IPAllowlist = append(c.IPAllowlist, cidrs...)
+	}
+}
+
+// WithIPBlocklist denies access to clients whose IP address is within one
+// of cidrs with 403 Forbidden. The blocklist takes precedence over the
+// allowlist. Multiple calls accumulate.
+func WithIPBlocklist(cidrs ...string) Option {
+	return func(c *Config) {
+		c.IPBlocklist = append(c.IPBlocklist, cidrs...)
+	}
+}
+
+// parsePrefixes parses CIDRs or bare IP addresses.
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		p, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			addr, aerr := netip.ParseAddr(cidr)
+			if aerr != nil {
+				return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+			}
+			p = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	return prefixes, nil
+}
+
+// containsAddr reports whether addr is within any of prefixes.
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteAddr returns the IP address of r.RemoteAddr.
+func remoteAddr(r *http.Request) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// allowIP reports whether the client address addr passes the IP blocklist
+// and allowlist. Clients with an unparseable address (ok is false) are
+// denied when either list is configured.
+func (h *Handler) allowIP(addr netip.Addr, ok bool) bool {
+	if h.ipDenyAll {
+		return false
+	}
+	if len(h.ipAllow) == 0 && len(h.ipBlock) == 0 {
+		return true
+	}
+
+	if !ok {
+		return false
+	}
+	if containsAddr(h.ipBlock, addr) {
+		return false
+	}
+	if len(h.ipAllow) > 0 {
+		return containsAddr(h.ipAllow, addr)
+	}
+	return true
+}
+
+// checkAccess applies the IP filter and Basic authentication to requests
+// handed to a passthrough handler, which skip the rest of the SPA
+// handling. It responds 403 or 401 and reports false if r is refused.
+func (h *Handler) checkAccess(w http.ResponseWriter, r *http.Request, addr netip.Addr, ok bool) bool {
+	if !h.allowIP(addr, ok) {
+		serveError(w, "403 Forbidden", http.StatusForbidden)
+		return false
+	}
+	if h.cfg.basicAuthEnabled() && !h.authorize(r) {
+		serveUnauthorized(h.cfg, w)
+		return false
+	}
+	return true
+}
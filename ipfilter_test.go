@@ -0,0 +1,69 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeWithIPFilter(t *testing.T) {
+	fsys := fstest.MapFS{"index.html": {Data: []byte("index.html")}}
+
+	tt := []struct {
+		name       string
+		opts       []Option
+		remoteAddr string
+		statusCode int
+	}{
+		{name: "no lists", remoteAddr: "203.0.113.7:1234", statusCode: 200},
+		{name: "allowlisted IPv4", opts: []Option{WithIPAllowlist("10.0.0.0/8")}, remoteAddr: "10.1.2.3:1234", statusCode: 200},
+		{name: "not allowlisted IPv4", opts: []Option{WithIPAllowlist("10.0.0.0/8")}, remoteAddr: "203.0.113.7:1234", statusCode: 403},
+		{name: "allowlisted IPv6", opts: []Option{WithIPAllowlist("2001:db8::/32")}, remoteAddr: "[2001:db8::1]:1234", statusCode: 200},
+		{name: "not allowlisted IPv6", opts: []Option{WithIPAllowlist("2001:db8::/32")}, remoteAddr: "[2001:db9::1]:1234", statusCode: 403},
+		{name: "IPv4-mapped IPv6 address", opts: []Option{WithIPAllowlist("10.0.0.0/8")}, remoteAddr: "[::ffff:10.0.0.1]:1234", statusCode: 200},
+		{name: "bare address", opts: []Option{WithIPAllowlist("192.0.2.1")}, remoteAddr: "192.0.2.1:1234", statusCode: 200},
+		{name: "blocklisted", opts: []Option{WithIPBlocklist("192.0.2.0/24")}, remoteAddr: "192.0.2.55:1234", statusCode: 403},
+		{name: "not blocklisted", opts: []Option{WithIPBlocklist("192.0.2.0/24")}, remoteAddr: "198.51.100.1:1234", statusCode: 200},
+		{name: "blocklist takes precedence", opts: []Option{WithIPAllowlist("10.0.0.0/8"), WithIPBlocklist("10.0.0.1")}, remoteAddr: "10.0.0.1:1234", statusCode: 403},
+		{name: "calls accumulate", opts: []Option{WithIPAllowlist("10.0.0.0/8"), WithIPAllowlist("192.0.2.0/24")}, remoteAddr: "192.0.2.1:1234", statusCode: 200},
+		{name: "unparseable remote address", opts: []Option{WithIPBlocklist("192.0.2.0/24")}, remoteAddr: "garbage", statusCode: 403},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := New(fsys, tc.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+			r.RemoteAddr = tc.remoteAddr
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.statusCode {
+				t.Errorf("statusCode expected: %d, got: %d", tc.statusCode, w.Code)
+			}
+		})
+	}
+}
+
+func TestIPFilterInvalidCIDR(t *testing.T) {
+	fsys := fstest.MapFS{"index.html": {Data: []byte("index.html")}}
+
+	_, err := New(fsys, WithIPAllowlist("10.0.0.0/33"))
+	if err == nil || !strings.Contains(err.Error(), "ip_allowlist") {
+		t.Errorf("expected ip_allowlist error, got: %v", err)
+	}
+
+	// Serve cannot report the error, so it must fail closed.
+	h := Serve(fsys, WithIPBlocklist("not-a-cidr"))
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("statusCode expected: 403, got: %d", w.Code)
+	}
+}
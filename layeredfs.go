@@ -0,0 +1,129 @@
+package spaserver
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+)
+
+// layeredFS looks files up in primary first and falls back to fallback.
+type layeredFS struct {
+	primary  fs.FS
+	fallback fs.FS
+}
+
+// NewLayeredFS returns a filesystem that serves files from primary and falls
+// back to fallback for files primary does not have. Directory listings are
+// the union of both, with primary entries taking precedence over fallback
+// entries of the same name.
+//
+// Typical uses are serving a freshly built os.DirFS("dist") over an embedded
+// copy during development, or a small on-disk patch over a full embed.FS.
+func NewLayeredFS(primary, fallback fs.FS) fs.FS {
+	return &layeredFS{primary: primary, fallback: fallback}
+}
+
+// Open implements fs.FS.
+func (l *layeredFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	f, err := l.primary.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return l.fallback.Open(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return f, nil
+	}
+
+	return &layeredDir{File: f, fs: l, name: name}, nil
+}
+
+// Stat implements fs.StatFS.
+func (l *layeredFS) Stat(name string) (fs.FileInfo, error) {
+	fi, err := fs.Stat(l.primary, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fs.Stat(l.fallback, name)
+	}
+	return fi, err
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (l *layeredFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	primary, perr := fs.ReadDir(l.primary, name)
+	if perr != nil && !errors.Is(perr, fs.ErrNotExist) {
+		return nil, perr
+	}
+	fallback, ferr := fs.ReadDir(l.fallback, name)
+	if ferr != nil && !errors.Is(ferr, fs.ErrNotExist) {
+		return nil, ferr
+	}
+	if perr != nil && ferr != nil {
+		return nil, perr
+	}
+
+	seen := make(map[string]bool, len(primary))
+	entries := make([]fs.DirEntry, 0, len(primary)+len(fallback))
+	for _, e := range primary {
+		seen[e.Name()] = true
+		entries = append(entries, e)
+	}
+	for _, e := range fallback {
+		if !seen[e.Name()] {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	return entries, nil
+}
+
+// layeredDir is a directory opened from the primary layer whose listing is
+// merged with the fallback layer.
+type layeredDir struct {
+	fs.File
+	fs      *layeredFS
+	name    string
+	entries []fs.DirEntry
+	read    bool
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *layeredDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		entries, err := d.fs.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries = entries
+		d.read = true
+	}
+
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
@@ -0,0 +1,88 @@
+package spaserver
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLayeredFS(t *testing.T) {
+	primary := fstest.MapFS{
+		"index.html":      {Data: []byte("primary index")},
+		"css/patched.css": {Data: []byte("patched")},
+	}
+	fallback := fstest.MapFS{
+		"index.html":   {Data: []byte("fallback index")},
+		"css/main.css": {Data: []byte("main")},
+		"js/app.js":    {Data: []byte("app")},
+	}
+	fsys := NewLayeredFS(primary, fallback)
+
+	if err := fstest.TestFS(fsys, "index.html", "css/patched.css", "css/main.css", "js/app.js"); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"index.html":      "primary index",
+		"css/patched.css": "patched",
+		"css/main.css":    "main",
+		"js/app.js":       "app",
+	}
+	for name, want := range files {
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want {
+			t.Errorf("%s expected: %q, got: %q", name, want, b)
+		}
+	}
+
+	entries, err := fs.ReadDir(fsys, "css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if len(names) != 2 || names[0] != "main.css" || names[1] != "patched.css" {
+		t.Errorf("ReadDir(css) expected: [main.css patched.css], got: %v", names)
+	}
+
+	if _, err := fs.Stat(fsys, "missing.txt"); !os.IsNotExist(err) {
+		t.Errorf("Stat(missing.txt) expected ErrNotExist, got: %v", err)
+	}
+}
+
+func TestServeLayeredFS(t *testing.T) {
+	primary := fstest.MapFS{
+		"css/main.css": {Data: []byte("patched")},
+	}
+	h := Serve(NewLayeredFS(primary, os.DirFS("testdata")))
+
+	tt := []struct {
+		url  string
+		body string
+	}{
+		{url: "http://www.example.com/", body: "index.html\n"},
+		{url: "http://www.example.com/css/main.css", body: "patched"},
+		{url: "http://www.example.com/root-main.css", body: "body {\n\tdisplay: none;\n}\n"},
+	}
+
+	for _, tc := range tt {
+		r, err := http.NewRequest(http.MethodGet, tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Body.String() != tc.body {
+			t.Errorf("%s body expected: %q, got: %q", tc.url, tc.body, w.Body.String())
+		}
+	}
+}
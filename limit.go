@@ -0,0 +1,75 @@
+package spaserver
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// Gauge records a current value. prometheus.Gauge satisfies it.
+type Gauge interface {
+	Set(float64)
+}
+
+// WithMaxConnections limits the handler to n requests in flight at once.
+// Requests beyond the limit are answered immediately with 503 Service
+// Unavailable and Retry-After: 1 instead of queueing, so a traffic spike
+// cannot exhaust memory or file descriptors.
+func WithMaxConnections(n int) Option {
+	return func(c *Config) {
+		c.MaxConnections = n
+	}
+}
+
+// WithMaxConnectionsGauge reports the number of requests in flight to
+// gauge as requests enter and leave the handler, for alerting before the
+// WithMaxConnections limit is reached.
+func WithMaxConnectionsGauge(gauge Gauge) Option {
+	return func(c *Config) {
+		c.MaxConnectionsGauge = gauge
+	}
+}
+
+// connLimiter is a semaphore bounding the requests in flight.
+type connLimiter struct {
+	sem    chan struct{}
+	active atomic.Int64
+	gauge  Gauge
+}
+
+// newConnLimiter returns the limiter configured by cfg, or nil if requests
+// are unlimited.
+func newConnLimiter(cfg Config) *connLimiter {
+	if cfg.MaxConnections <= 0 {
+		return nil
+	}
+	return &connLimiter{sem: make(chan struct{}, cfg.MaxConnections), gauge: cfg.MaxConnectionsGauge}
+}
+
+// acquire takes a slot without blocking and reports whether one was free.
+func (l *connLimiter) acquire() bool {
+	select {
+	case l.sem <- struct{}{}:
+		l.report(l.active.Add(1))
+		return true
+	default:
+		return false
+	}
+}
+
+// release frees a slot taken by acquire.
+func (l *connLimiter) release() {
+	l.report(l.active.Add(-1))
+	<-l.sem
+}
+
+func (l *connLimiter) report(active int64) {
+	if l.gauge != nil {
+		l.gauge.Set(float64(active))
+	}
+}
+
+// serveOverloaded answers a request rejected by the connection limit.
+func serveOverloaded(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "1")
+	serveError(w, "503 Service Unavailable", http.StatusServiceUnavailable)
+}
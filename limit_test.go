@@ -0,0 +1,81 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+// gaugeFunc adapts a function to Gauge.
+type gaugeFunc func(float64)
+
+func (f gaugeFunc) Set(v float64) { f(v) }
+
+func TestServeWithMaxConnections(t *testing.T) {
+	gate := &gateFS{
+		FS: fstest.MapFS{
+			"index.html": {Data: []byte("index.html")},
+			"slow.js":    {Data: []byte("slow")},
+		},
+		name:    "slow.js",
+		opened:  make(chan struct{}),
+		release: make(chan struct{}),
+	}
+
+	var mu sync.Mutex
+	var levels []float64
+	h := Serve(gate,
+		WithMaxConnections(1),
+		WithMaxConnectionsGauge(gaugeFunc(func(v float64) {
+			mu.Lock()
+			levels = append(levels, v)
+			mu.Unlock()
+		})),
+	)
+
+	get := func(url string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+url, nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- get("/slow.js") }()
+	<-gate.opened
+
+	w := get("/")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status over the limit expected: %d, got: %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After expected: %q, got: %q", "1", got)
+	}
+
+	close(gate.release)
+	if w := <-done; w.Code != http.StatusOK {
+		t.Errorf("status of the admitted request expected: %d, got: %d", http.StatusOK, w.Code)
+	}
+
+	if w := get("/"); w.Code != http.StatusOK {
+		t.Errorf("status after release expected: %d, got: %d", http.StatusOK, w.Code)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []float64{1, 0, 1, 0}; !slices.Equal(levels, want) {
+		t.Errorf("gauge levels expected: %v, got: %v", want, levels)
+	}
+}
+
+func TestConfigValidateMaxConnections(t *testing.T) {
+	if err := (Config{MaxConnections: -1}).Validate(); err == nil {
+		t.Error("expected error for a negative limit")
+	}
+	if err := (Config{MaxConnectionsGauge: gaugeFunc(func(float64) {})}).Validate(); err == nil {
+		t.Error("expected error for a gauge without a limit")
+	}
+}
@@ -0,0 +1,156 @@
+package spaserver
+
+import (
+	"io/fs"
+	"maps"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WithLocales serves a localized index file for SPA fallback requests. The
+// Accept-Language header is negotiated against supported, and
+// index.<locale>.html is served when it exists in the filesystem;
+// otherwise index.html is served. defaultLocale is used when no supported
+// locale matches. Responses carry Vary: Accept-Language.
+func WithLocales(supported []string, defaultLocale string) Option {
+	return func(c *Config) {
+		c.Locales = supported
+		c.DefaultLocale = defaultLocale
+	}
+}
+
+// languageRange is a single entry of an Accept-Language header.
+type languageRange struct {
+	tag string
+	q   float64
+}
+
+// parseAcceptLanguage parses an Accept-Language header into language ranges
+// ordered by descending quality. Ranges with q=0 are dropped.
+func parseAcceptLanguage(header string) []languageRange {
+	var ranges []languageRange
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+
+		q := parseQuality(params)
+		if q <= 0 {
+			continue
+		}
+
+		ranges = append(ranges, languageRange{tag: tag, q: q})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+
+	return ranges
+}
+
+// parseQuality returns the q parameter of the ";"-separated params of an
+// Accept-* header entry, defaulting to 1. An invalid q value is treated as 0.
+func parseQuality(params string) float64 {
+	q := 1.0
+	for _, param := range strings.Split(params, ";") {
+		k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || strings.TrimSpace(k) != "q" {
+			continue
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			f = 0
+		}
+		q = f
+	}
+	return q
+}
+
+// negotiateLocale returns the entry of supported that best matches the
+// Accept-Language header, or defaultLocale when nothing matches. A range
+// matches a supported locale exactly or by primary subtag, so "fr-CA"
+// selects "fr" and "en" selects "en-US".
+func negotiateLocale(header string, supported []string, defaultLocale string) string {
+	for _, lr := range parseAcceptLanguage(header) {
+		if lr.tag == "*" {
+			return defaultLocale
+		}
+		for _, locale := range supported {
+			if strings.EqualFold(lr.tag, locale) {
+				return locale
+			}
+		}
+		primary, _, _ := strings.Cut(lr.tag, "-")
+		for _, locale := range supported {
+			p, _, _ := strings.Cut(strings.ToLower(locale), "-")
+			if primary == p {
+				return locale
+			}
+		}
+	}
+	return defaultLocale
+}
+
+// localeIndexPage returns the index file to serve for the request's
+// negotiated locale, falling back to indexPage when the locale has no
+// index.<locale>.html file in fsys.
+func localeIndexPage(fsys fs.FS, cfg Config, r *http.Request) string {
+	locale := negotiateLocale(r.Header.Get("Accept-Language"), cfg.Locales, cfg.DefaultLocale)
+	if locale == "" {
+		return indexPage
+	}
+
+	name := "index." + locale + ".html"
+	if _, err := fs.Stat(fsys, name); err != nil {
+		if locale != cfg.DefaultLocale {
+			cfg.Logger.Warn("spaserver: locale index not found", "locale", locale, "path", name)
+		}
+		return indexPage
+	}
+
+	return name
+}
+
+// WithLanguageRedirect redirects requests for the root path / to the path
+// mapped to the request's negotiated language, e.g. {"fr": "/fr/"}, with
+// 302 Found and Vary: Accept-Language. Languages are matched as by
+// WithLocales. Requests with no matching language, or whose path already
+// begins with the mapped path, are served as usual. Multiple calls
+// accumulate.
+func WithLanguageRedirect(localeMap map[string]string) Option {
+	return func(c *Config) {
+		if c.LanguageRedirects == nil {
+			c.LanguageRedirects = make(map[string]string, len(localeMap))
+		}
+		maps.Copy(c.LanguageRedirects, localeMap)
+	}
+}
+
+// languageRedirect redirects a request for the clean URL path upath to its
+// language's path and reports whether it did.
+func languageRedirect(cfg Config, w http.ResponseWriter, r *http.Request, upath string) bool {
+	if upath != "/" || len(cfg.LanguageRedirects) == 0 {
+		return false
+	}
+	w.Header().Add("Vary", "Accept-Language")
+
+	locales := slices.Sorted(maps.Keys(cfg.LanguageRedirects))
+	locale := negotiateLocale(r.Header.Get("Accept-Language"), locales, "")
+	target, ok := cfg.LanguageRedirects[locale]
+	if !ok || strings.HasPrefix(upath, target) {
+		return false
+	}
+
+	if q := r.URL.RawQuery; q != "" {
+		target += "?" + q
+	}
+	w.Header().Set("Location", target)
+	w.WriteHeader(http.StatusFound)
+	return true
+}
@@ -0,0 +1,166 @@
+package spaserver
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestNegotiateLocale(t *testing.T) {
+	supported := []string{"en", "fr", "pt-BR"}
+
+	tt := []struct {
+		header string
+		want   string
+	}{
+		{header: "", want: "en"},
+		{header: "fr", want: "fr"},
+		{header: "fr-CA,fr;q=0.9", want: "fr"},
+		{header: "de, fr;q=0.5, en;q=0.8", want: "en"},
+		{header: "pt-br", want: "pt-BR"},
+		{header: "pt", want: "pt-BR"},
+		{header: "de, *;q=0.1", want: "en"},
+		{header: "fr;q=0, de", want: "en"},
+	}
+
+	for _, tc := range tt {
+		if got := negotiateLocale(tc.header, supported, "en"); got != tc.want {
+			t.Errorf("negotiateLocale(%q) expected: %q, got: %q", tc.header, tc.want, got)
+		}
+	}
+}
+
+func TestServeWithLocales(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":    {Data: []byte("index.html")},
+		"index.fr.html": {Data: []byte("index.fr.html")},
+		"css/main.css":  {Data: []byte("body {}")},
+	}
+
+	tt := []struct {
+		name           string
+		url            string
+		acceptLanguage string
+		body           string
+		vary           string
+		warn           bool
+	}{
+		{
+			name:           "matching locale serves localized index",
+			url:            "http://www.example.com/",
+			acceptLanguage: "fr-FR,fr;q=0.9,en;q=0.8",
+			body:           "index.fr.html",
+			vary:           "Accept-Language",
+		},
+		{
+			name:           "fallback route serves localized index",
+			url:            "http://www.example.com/some/route",
+			acceptLanguage: "fr",
+			body:           "index.fr.html",
+			vary:           "Accept-Language",
+		},
+		{
+			name:           "missing locale file falls back to index.html",
+			url:            "http://www.example.com/",
+			acceptLanguage: "de",
+			body:           "index.html",
+			vary:           "Accept-Language",
+			warn:           true,
+		},
+		{
+			name:           "unsupported locale serves default",
+			url:            "http://www.example.com/",
+			acceptLanguage: "ja",
+			body:           "index.html",
+			vary:           "Accept-Language",
+		},
+		{
+			name:           "static files are not localized",
+			url:            "http://www.example.com/css/main.css",
+			acceptLanguage: "fr",
+			body:           "body {}",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&logs, nil))
+			h := Serve(fsys, WithLocales([]string{"en", "fr", "de"}, "en"), WithLogger(logger))
+
+			r, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("Accept-Language", tc.acceptLanguage)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != 200 {
+				t.Fatalf("statusCode expected: 200, got: %d", w.Code)
+			}
+			if body := w.Body.String(); body != tc.body {
+				t.Errorf("body expected: %s, got: %s", tc.body, body)
+			}
+			if got := w.Header().Get("Vary"); got != tc.vary {
+				t.Errorf("Vary expected: %q, got: %q", tc.vary, got)
+			}
+			if warned := strings.Contains(logs.String(), "level=WARN"); warned != tc.warn {
+				t.Errorf("warning logged expected: %v, got: %v (%q)", tc.warn, warned, logs.String())
+			}
+		})
+	}
+}
+
+func TestServeWithLanguageRedirect(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("index.html")},
+	}
+	h := Serve(fsys,
+		WithLanguageRedirect(map[string]string{"fr": "/fr/", "de": "/de/"}),
+		WithLanguageRedirect(map[string]string{"en": "/"}),
+	)
+
+	tt := []struct {
+		name           string
+		url            string
+		acceptLanguage string
+		status         int
+		location       string
+		vary           bool
+	}{
+		{name: "redirect", url: "/", acceptLanguage: "fr-CA,fr;q=0.9", status: http.StatusFound, location: "/fr/", vary: true},
+		{name: "preferred language", url: "/", acceptLanguage: "es, de;q=0.8, fr;q=0.5", status: http.StatusFound, location: "/de/", vary: true},
+		{name: "query preserved", url: "/?utm=1", acceptLanguage: "de", status: http.StatusFound, location: "/de/?utm=1", vary: true},
+		{name: "unrecognized language", url: "/", acceptLanguage: "es", status: http.StatusOK, vary: true},
+		{name: "no accept-language", url: "/", status: http.StatusOK, vary: true},
+		{name: "already at mapped path", url: "/", acceptLanguage: "en", status: http.StatusOK, vary: true},
+		{name: "not root", url: "/fr/", acceptLanguage: "de", status: http.StatusOK},
+		{name: "route", url: "/some/route", acceptLanguage: "fr", status: http.StatusOK},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+tc.url, nil)
+			if tc.acceptLanguage != "" {
+				r.Header.Set("Accept-Language", tc.acceptLanguage)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("status expected: %d, got: %d", tc.status, w.Code)
+			}
+			if got := w.Header().Get("Location"); got != tc.location {
+				t.Errorf("Location expected: %q, got: %q", tc.location, got)
+			}
+			if got := headerContainsToken(w.Header(), "Vary", "Accept-Language"); got != tc.vary {
+				t.Errorf("Vary: Accept-Language expected: %t, got: %t", tc.vary, got)
+			}
+		})
+	}
+}
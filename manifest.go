@@ -0,0 +1,69 @@
+package spaserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Cache-Control sent with content-hashed assets that never change.
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+// WithWebpackManifest rewrites requests for logical asset names to their
+// content-hashed file names using the JSON manifest emitted by
+// webpack-manifest-plugin. The manifest path is relative to the served
+// filesystem. Rewritten responses are sent with an immutable Cache-Control
+// header.
+func WithWebpackManifest(manifestPath string) Option {
+	return func(c *Config) {
+		c.WebpackManifest = manifestPath
+	}
+}
+
+// webpackManifest maps logical asset names to their content-hashed
+// counterparts, as emitted by webpack-manifest-plugin.
+type webpackManifest map[string]string
+
+// loadWebpackManifest reads and parses the manifest file at name.
+func loadWebpackManifest(fsys fs.FS, name string) (webpackManifest, error) {
+	b, err := fs.ReadFile(fsys, strings.TrimPrefix(name, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("read webpack manifest: %w", err)
+	}
+
+	var m webpackManifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("parse webpack manifest: %w", err)
+	}
+
+	return m, nil
+}
+
+// lookup returns the hashed file name for the request name, if any.
+// Keys are matched against the full relative path first, then against
+// the base name. Values containing a slash are treated as paths relative
+// to the FS root; bare file names are resolved in the directory of the
+// request.
+func (m webpackManifest) lookup(name string) (string, bool) {
+	target, ok := m[name]
+	if !ok {
+		target, ok = m[path.Base(name)]
+	}
+	if !ok || target == "" {
+		return "", false
+	}
+
+	if strings.Contains(target, "/") {
+		target = strings.TrimPrefix(path.Clean("/"+target), "/")
+	} else {
+		target = path.Join(path.Dir(name), target)
+	}
+
+	if target == name {
+		return "", false
+	}
+
+	return target, true
+}
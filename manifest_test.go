@@ -0,0 +1,82 @@
+package spaserver
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeWithWebpackManifest(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":                 {Data: []byte("index.html")},
+		"manifest.json":              {Data: []byte(`{"main.js": "main.abc123.js", "static/js/vendor.js": "/static/js/vendor.def456.js", "missing.js": "missing.000000.js"}`)},
+		"static/js/main.abc123.js":   {Data: []byte("main")},
+		"static/js/vendor.def456.js": {Data: []byte("vendor")},
+		"static/js/unversioned.js":   {Data: []byte("unversioned")},
+		"static/js/missing.js":       {Data: []byte("missing")},
+	}
+
+	tt := []struct {
+		name         string
+		url          string
+		body         string
+		cacheControl string
+		warn         bool
+	}{
+		{
+			name:         "base name key rewritten in request directory",
+			url:          "http://www.example.com/static/js/main.js",
+			body:         "main",
+			cacheControl: immutableCacheControl,
+		},
+		{
+			name:         "full path key rewritten to absolute target",
+			url:          "http://www.example.com/static/js/vendor.js",
+			body:         "vendor",
+			cacheControl: immutableCacheControl,
+		},
+		{
+			name: "file without manifest entry served normally",
+			url:  "http://www.example.com/static/js/unversioned.js",
+			body: "unversioned",
+		},
+		{
+			name: "missing target falls back to original file",
+			url:  "http://www.example.com/static/js/missing.js",
+			body: "missing",
+			warn: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&logs, nil))
+			h := Serve(fsys, WithWebpackManifest("manifest.json"), WithLogger(logger))
+
+			r, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != 200 {
+				t.Fatalf("statusCode expected: 200, got: %d", w.Code)
+			}
+			if body := w.Body.String(); body != tc.body {
+				t.Errorf("body expected: %s, got: %s", tc.body, body)
+			}
+			if got := w.Header().Get("Cache-Control"); got != tc.cacheControl {
+				t.Errorf("Cache-Control expected: %q, got: %q", tc.cacheControl, got)
+			}
+			if warned := strings.Contains(logs.String(), "level=WARN"); warned != tc.warn {
+				t.Errorf("warning logged expected: %v, got: %v (%q)", tc.warn, warned, logs.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package spaserver
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// defaultAllowedMethods are the methods listed in the Allow header by
+// default: the ones the handler serves content for.
+var defaultAllowedMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+
+// WithAllowedMethods sets the methods listed in the Allow header of OPTIONS
+// responses. Defaults to GET, HEAD and OPTIONS. Multiple calls accumulate.
+func WithAllowedMethods(methods ...string) Option {
+	return func(c *Config) {
+		c.AllowedMethods = append(c.AllowedMethods, methods...)
+	}
+}
+
+// serveOptions answers an OPTIONS request with the allowed methods and no
+// body, for any path, without touching the filesystem.
+func serveOptions(cfg Config, w http.ResponseWriter) {
+	w.Header().Set("Allow", strings.Join(cfg.AllowedMethods, ", "))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// WithMethodHandler delegates requests with the given method, e.g. POST
+// form submissions or webhooks, to handler instead of serving the SPA. It
+// applies to every path and runs after IP filtering and basic auth, before
+// the filesystem is accessed. Unless WithAllowedMethods is used, the method
+// is added to the Allow header of OPTIONS responses. Multiple calls
+// accumulate, one per method.
+func WithMethodHandler(method string, handler http.Handler) Option {
+	return func(c *Config) {
+		if c.MethodHandlers == nil {
+			c.MethodHandlers = make(map[string]http.Handler)
+		}
+		c.MethodHandlers[method] = handler
+	}
+}
+
+// allowedMethods returns the default methods of the Allow header: the
+// ones the handler serves content for, then those with a method handler.
+func allowedMethods(handlers map[string]http.Handler) []string {
+	methods := slices.Clone(defaultAllowedMethods)
+	for _, m := range slices.Sorted(maps.Keys(handlers)) {
+		if !slices.Contains(methods, m) {
+			methods = append(methods, m)
+		}
+	}
+	return methods
+}
@@ -0,0 +1,127 @@
+package spaserver
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+)
+
+// countFS counts calls to Open.
+type countFS struct {
+	fs.FS
+	opens atomic.Int64
+}
+
+func (c *countFS) Open(name string) (fs.File, error) {
+	c.opens.Add(1)
+	return c.FS.Open(name)
+}
+
+func TestServeOptions(t *testing.T) {
+	tt := []struct {
+		name  string
+		opts  []Option
+		allow string
+	}{
+		{name: "default", allow: "GET, HEAD, OPTIONS"},
+		{name: "method handlers", opts: []Option{WithMethodHandler("PUT", http.NotFoundHandler()), WithMethodHandler("POST", http.NotFoundHandler())}, allow: "GET, HEAD, OPTIONS, POST, PUT"},
+		{name: "configured", opts: []Option{WithAllowedMethods("GET", "HEAD"), WithAllowedMethods("OPTIONS", "POST")}, allow: "GET, HEAD, OPTIONS, POST"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			fsys := &countFS{FS: fstest.MapFS{"index.html": {Data: []byte("<html>index</html>")}}}
+			h := Serve(fsys, tc.opts...)
+			opens := fsys.opens.Load()
+
+			for _, url := range []string{"http://www.example.com/", "http://www.example.com/app/route"} {
+				r := httptest.NewRequest(http.MethodOptions, url, nil)
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+
+				if w.Code != http.StatusNoContent {
+					t.Errorf("%s status expected: %d, got: %d", url, http.StatusNoContent, w.Code)
+				}
+				if got := w.Header().Get("Allow"); got != tc.allow {
+					t.Errorf("%s Allow expected: %q, got: %q", url, tc.allow, got)
+				}
+				if strings.Contains(w.Body.String(), "index") {
+					t.Errorf("%s body expected without index.html, got: %q", url, w.Body.String())
+				}
+			}
+			if got := fsys.opens.Load(); got != opens {
+				t.Errorf("filesystem opens expected: %d, got: %d", opens, got)
+			}
+		})
+	}
+}
+
+func TestAllowedMethodsValidation(t *testing.T) {
+	_, err := New(fstest.MapFS{"index.html": {Data: []byte("index.html")}}, WithAllowedMethods("GET", "BAD METHOD"))
+	if want := `allowed_methods: invalid method "BAD METHOD"`; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("error expected to contain: %q, got: %v", want, err)
+	}
+}
+
+func TestServeWithMethodHandler(t *testing.T) {
+	fsys := &countFS{FS: fstest.MapFS{"index.html": {Data: []byte("index.html")}}}
+	webhook := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(r.Method + " " + r.URL.Path))
+	})
+	h := Serve(fsys,
+		WithMethodHandler(http.MethodPost, webhook),
+		WithMethodHandler(http.MethodPut, webhook),
+		WithBasicAuthFunc("hooks", func(user, pass string) (bool, error) {
+			return user == "admin" && pass == "secret", nil
+		}),
+	)
+	opens := fsys.opens.Load()
+
+	tt := []struct {
+		name   string
+		method string
+		auth   bool
+		status int
+		body   string
+	}{
+		{name: "post", method: http.MethodPost, auth: true, status: http.StatusAccepted, body: "POST /hooks/deploy"},
+		{name: "put", method: http.MethodPut, auth: true, status: http.StatusAccepted, body: "PUT /hooks/deploy"},
+		{name: "post without auth", method: http.MethodPost, status: http.StatusUnauthorized},
+		{name: "get", method: http.MethodGet, auth: true, status: http.StatusOK, body: "index.html"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, "http://www.example.com/hooks/deploy", nil)
+			if tc.auth {
+				r.SetBasicAuth("admin", "secret")
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("status expected: %d, got: %d", tc.status, w.Code)
+			}
+			if tc.body != "" && w.Body.String() != tc.body {
+				t.Errorf("body expected: %q, got: %q", tc.body, w.Body.String())
+			}
+			if tc.method != http.MethodGet {
+				if got := fsys.opens.Load(); got != opens {
+					t.Errorf("filesystem opens expected: %d, got: %d", opens, got)
+				}
+			}
+		})
+	}
+
+	_, err := New(fstest.MapFS{"index.html": {Data: []byte("index.html")}}, WithMethodHandler("BAD METHOD", webhook), WithMethodHandler("PATCH", nil))
+	for _, want := range []string{`method_handlers: invalid method "BAD METHOD"`, "method_handlers: PATCH: nil handler"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error expected to contain: %q, got: %v", want, err)
+		}
+	}
+}
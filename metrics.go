@@ -0,0 +1,169 @@
+package spaserver
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// WithMetricsEndpoint serves request metrics in the Prometheus text
+// exposition format at path, e.g. "/metrics", so a single binary needs no
+// second HTTP server for scraping. Requests to path are subject to the IP
+// allowlist and blocklist but otherwise bypass all SPA handling, including
+// WithBasicAuth and security headers other than X-Content-Type-Options,
+// and are not counted themselves.
+func WithMetricsEndpoint(path string) Option {
+	return func(c *Config) {
+		c.MetricsPath = path
+	}
+}
+
+// WithMetricsAuth requires HTTP Basic authentication for the metrics
+// endpoint, with user and a bcrypt hash of the password. It is independent
+// of WithBasicAuth, which does not apply to the endpoint.
+func WithMetricsAuth(user, hashedPassword string) Option {
+	return func(c *Config) {
+		c.MetricsUser = user
+		c.MetricsPasswordHash = hashedPassword
+	}
+}
+
+// metricsContentType is the Content-Type of the Prometheus text format.
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// durationBuckets are the upper bounds, in seconds, of the request
+// duration histogram.
+var durationBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// metrics counts the requests served by a handler.
+type metrics struct {
+	mu       sync.Mutex
+	inFlight int64
+	requests map[int]uint64 // by status code
+	bytes    uint64
+	buckets  []uint64 // request counts per duration bucket, not cumulative
+	sum      float64  // total request duration in seconds
+	count    uint64
+}
+
+// newMetrics returns metrics with no requests counted.
+func newMetrics() *metrics {
+	return &metrics{
+		requests: make(map[int]uint64),
+		buckets:  make([]uint64, len(durationBuckets)+1),
+	}
+}
+
+// start counts a request in flight and returns the writer recording its
+// response, to pass to done when the request is complete.
+func (m *metrics) start(w http.ResponseWriter) *responseRecorder {
+	m.mu.Lock()
+	m.inFlight++
+	m.mu.Unlock()
+	return newResponseRecorder(w)
+}
+
+// done counts the request recorded by rr, which took d.
+func (m *metrics) done(rr *responseRecorder, d time.Duration) {
+	code := rr.Status()
+	if code == 0 {
+		// net/http sends 200 for a handler that writes nothing
+		code = http.StatusOK
+	}
+	seconds := d.Seconds()
+	bucket, _ := slices.BinarySearch(durationBuckets, seconds)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inFlight--
+	m.requests[code]++
+	m.bytes += uint64(rr.Written())
+	m.buckets[bucket]++
+	m.sum += seconds
+	m.count++
+}
+
+// writeTo writes the metrics in the Prometheus text exposition format.
+func (m *metrics) writeTo(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString("# HELP spaserver_requests_total Requests served, by status code.\n")
+	b.WriteString("# TYPE spaserver_requests_total counter\n")
+	codes := make([]int, 0, len(m.requests))
+	for code := range m.requests {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	for _, code := range codes {
+		fmt.Fprintf(&b, "spaserver_requests_total{code=\"%d\"} %d\n", code, m.requests[code])
+	}
+
+	b.WriteString("# HELP spaserver_requests_in_flight Requests being served.\n")
+	b.WriteString("# TYPE spaserver_requests_in_flight gauge\n")
+	fmt.Fprintf(&b, "spaserver_requests_in_flight %d\n", m.inFlight)
+
+	b.WriteString("# HELP spaserver_response_bytes_total Response body bytes written.\n")
+	b.WriteString("# TYPE spaserver_response_bytes_total counter\n")
+	fmt.Fprintf(&b, "spaserver_response_bytes_total %d\n", m.bytes)
+
+	b.WriteString("# HELP spaserver_request_duration_seconds Time taken to serve requests.\n")
+	b.WriteString("# TYPE spaserver_request_duration_seconds histogram\n")
+	var cumulative uint64
+	for i, le := range durationBuckets {
+		cumulative += m.buckets[i]
+		fmt.Fprintf(&b, "spaserver_request_duration_seconds_bucket{le=\"%s\"} %d\n", strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+	}
+	fmt.Fprintf(&b, "spaserver_request_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.count)
+	fmt.Fprintf(&b, "spaserver_request_duration_seconds_sum %s\n", strconv.FormatFloat(m.sum, 'g', -1, 64))
+	fmt.Fprintf(&b, "spaserver_request_duration_seconds_count %d\n", m.count)
+
+	io.WriteString(w, b.String())
+}
+
+// serveMetrics answers a request to the metrics endpoint.
+func (h *Handler) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	if !h.allowIP(h.clientIP(r)) {
+		serveError(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	if (h.cfg.MetricsUser != "" || h.cfg.MetricsPasswordHash != "") && !h.authorizeMetrics(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="metrics", charset="UTF-8"`)
+		http.Error(w, "401 Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "405 Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", metricsContentType)
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Method == http.MethodHead {
+		return
+	}
+	h.metrics.writeTo(w)
+}
+
+// authorizeMetrics reports whether r carries the metrics credentials.
+func (h *Handler) authorizeMetrics(r *http.Request) bool {
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	// The hash is always compared, so a wrong user takes as long as a
+	// wrong password
+	userOK := subtle.ConstantTimeCompare([]byte(user), []byte(h.cfg.MetricsUser)) == 1
+	passOK := bcrypt.CompareHashAndPassword([]byte(h.cfg.MetricsPasswordHash), []byte(pass)) == nil
+	return userOK && passOK
+}
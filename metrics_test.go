@@ -0,0 +1,163 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestServeWithMetricsEndpoint(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":   {Data: []byte("index.html")},
+		"css/main.css": {Data: []byte("body {}")},
+	}
+	h := Serve(fsys, WithMetricsEndpoint("/metrics"))
+
+	for _, url := range []string{"/", "/css/main.css", "/index.html"} {
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+url, nil)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	scrape := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com/metrics", nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+	scrape()
+	w := scrape()
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status expected: %d, got: %d", http.StatusOK, w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); got != metricsContentType {
+		t.Errorf("Content-Type expected: %q, got: %q", metricsContentType, got)
+	}
+	if got := w.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("X-Content-Type-Options expected: %q, got: %q", "nosniff", got)
+	}
+
+	body := w.Body.String()
+	for _, line := range []string{
+		"# TYPE spaserver_requests_total counter",
+		`spaserver_requests_total{code="200"} 2`,
+		`spaserver_requests_total{code="301"} 1`,
+		"spaserver_requests_in_flight 0",
+		"spaserver_response_bytes_total 17",
+		`spaserver_request_duration_seconds_bucket{le="+Inf"} 3`,
+		"spaserver_request_duration_seconds_count 3",
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("metrics expected to contain: %q, got:\n%s", line, body)
+		}
+	}
+}
+
+func TestMetricsEndpointAuth(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{"index.html": {Data: []byte("index.html")}}
+	h, err := New(fsys, WithMetricsEndpoint("/metrics"), WithMetricsAuth("prometheus", string(hash)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		name       string
+		user, pass string
+		status     int
+	}{
+		{name: "no credentials", status: http.StatusUnauthorized},
+		{name: "wrong password", user: "prometheus", pass: "wrong", status: http.StatusUnauthorized},
+		{name: "wrong user", user: "admin", pass: "s3cret", status: http.StatusUnauthorized},
+		{name: "valid", user: "prometheus", pass: "s3cret", status: http.StatusOK},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com/metrics", nil)
+			if tc.user != "" {
+				r.SetBasicAuth(tc.user, tc.pass)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("status expected: %d, got: %d", tc.status, w.Code)
+			}
+			if tc.status == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("WWW-Authenticate expected")
+			}
+		})
+	}
+}
+
+func TestMetricsEndpointIPFilter(t *testing.T) {
+	fsys := fstest.MapFS{"index.html": {Data: []byte("index.html")}}
+	h := Serve(fsys, WithMetricsEndpoint("/metrics"), WithIPAllowlist("10.0.0.0/8"), WithIPBlocklist("10.0.0.9"))
+
+	tt := []struct {
+		name       string
+		remoteAddr string
+		status     int
+	}{
+		{name: "allowed", remoteAddr: "10.0.0.1:1234", status: http.StatusOK},
+		{name: "not allowed", remoteAddr: "192.0.2.1:1234", status: http.StatusForbidden},
+		{name: "blocked", remoteAddr: "10.0.0.9:1234", status: http.StatusForbidden},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com/metrics", nil)
+			r.RemoteAddr = tc.remoteAddr
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("status expected: %d, got: %d", tc.status, w.Code)
+			}
+			if tc.status == http.StatusForbidden && strings.Contains(w.Body.String(), "spaserver_requests_total") {
+				t.Error("metrics served to a refused client")
+			}
+		})
+	}
+}
+
+func TestMetricsDurationBuckets(t *testing.T) {
+	m := newMetrics()
+	for _, d := range []time.Duration{time.Millisecond, 30 * time.Millisecond, time.Minute} {
+		m.done(m.start(httptest.NewRecorder()), d)
+	}
+
+	var b strings.Builder
+	m.writeTo(&b)
+	for _, line := range []string{
+		`spaserver_request_duration_seconds_bucket{le="0.001"} 1`,
+		`spaserver_request_duration_seconds_bucket{le="0.025"} 1`,
+		`spaserver_request_duration_seconds_bucket{le="0.05"} 2`,
+		`spaserver_request_duration_seconds_bucket{le="10"} 2`,
+		`spaserver_request_duration_seconds_bucket{le="+Inf"} 3`,
+		"spaserver_request_duration_seconds_sum 60.031",
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("metrics expected to contain: %q, got:\n%s", line, b.String())
+		}
+	}
+}
+
+func TestMetricsAuthValidation(t *testing.T) {
+	fsys := fstest.MapFS{"index.html": {Data: []byte("index.html")}}
+	_, err := New(fsys, WithMetricsAuth("prometheus", "not-a-hash"))
+	for _, want := range []string{"metrics_user: requires metrics_path", "metrics_password_hash:"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error expected to contain: %q, got: %v", want, err)
+		}
+	}
+}
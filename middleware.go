@@ -0,0 +1,29 @@
+package spaserver
+
+import "net/http"
+
+// Middleware wraps an http.Handler with behavior that runs before and
+// after it.
+type Middleware func(http.Handler) http.Handler
+
+// Chain composes middlewares into one, applied left to right: the first
+// middleware is the outermost and sees each request first and its response
+// last. For example
+//
+//	Chain(auth, logRequests)(spaserver.Serve(fsys))
+//
+// is auth(logRequests(spaserver.Serve(fsys))).
+//
+// Every middleware runs outside the handler, so it sees requests before
+// any behavior configured through options, such as IP filtering, basic
+// auth or compression, and sees responses after it. Headers a middleware
+// sets before calling the next handler may be overwritten by the handler.
+// Options such as WithLogger apply only to the handler itself.
+func Chain(middlewares ...Middleware) Middleware {
+	return func(h http.Handler) http.Handler {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			h = middlewares[i](h)
+		}
+		return h
+	}
+}
@@ -0,0 +1,41 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name+" before")
+				next.ServeHTTP(w, r)
+				calls = append(calls, name+" after")
+			})
+		}
+	}
+
+	h := Chain(trace("a"), trace("b"))(Serve(headerTestFS))
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Body.String() != "index.html" {
+		t.Errorf("body expected: index.html, got: %s", w.Body.String())
+	}
+	want := "a before, b before, b after, a after"
+	if got := strings.Join(calls, ", "); got != want {
+		t.Errorf("calls expected: %q, got: %q", want, got)
+	}
+}
+
+func TestChainEmpty(t *testing.T) {
+	h := Serve(headerTestFS)
+	if got := Chain()(h); got != http.Handler(h) {
+		t.Error("expected an empty chain to return the handler unchanged")
+	}
+}
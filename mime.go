@@ -0,0 +1,133 @@
+package spaserver
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// defaultMIMETypes are always applied, regardless of the host's MIME
+// database, for types browsers are strict about.
+var defaultMIMETypes = map[string]string{
+	// WebAssembly.instantiateStreaming rejects anything else
+	".wasm": "application/wasm",
+	// Web app manifests; strict PWA implementations reject application/json
+	".webmanifest": "application/manifest+json",
+}
+
+// defaultFileTypes are always applied to files with these base names,
+// taking precedence over any extension.
+var defaultFileTypes = map[string]string{
+	"manifest.json": "application/manifest+json",
+}
+
+// WithMIMEType registers mimeType as the Content-Type for files with the
+// extension ext, taking precedence over mime.TypeByExtension. Multiple calls
+// accumulate.
+func WithMIMEType(ext, mimeType string) Option {
+	return func(c *Config) {
+		if c.MIMETypes == nil {
+			c.MIMETypes = make(map[string]string)
+		}
+		c.MIMETypes[normalizeExt(ext)] = mimeType
+	}
+}
+
+// WithContentType serves static files matching the path.Match glob pattern
+// with Content-Type mimeType, taking precedence over every other source,
+// e.g. WithContentType("*.json", "application/json; charset=utf-8"). As
+// with WithDownloadPattern, a glob starting with "/" matches the URL path
+// and any other glob matches the base name. When several patterns match,
+// the one with the most literal characters wins. Multiple calls
+// accumulate.
+func WithContentType(pattern, mimeType string) Option {
+	return func(c *Config) {
+		if c.ContentTypes == nil {
+			c.ContentTypes = make(map[string]string)
+		}
+		c.ContentTypes[pattern] = mimeType
+	}
+}
+
+// contentTypeOverride returns the Content-Type of the most specific
+// pattern in cfg.ContentTypes matching name.
+func contentTypeOverride(cfg Config, name string) (string, bool) {
+	var best string
+	bestLen := -1
+	for p := range cfg.ContentTypes {
+		subject := path.Base(name)
+		if strings.HasPrefix(p, "/") {
+			subject = "/" + strings.TrimPrefix(name, "/")
+		}
+		if ok, _ := path.Match(p, subject); !ok {
+			continue
+		}
+		// Break ties on the pattern itself so the result does not depend
+		// on map order
+		if n := literalLen(p); n > bestLen || n == bestLen && p < best {
+			best, bestLen = p, n
+		}
+	}
+	if bestLen < 0 {
+		return "", false
+	}
+	return cfg.ContentTypes[best], true
+}
+
+// literalLen returns the number of characters of the path.Match pattern p
+// that match only themselves.
+func literalLen(p string) int {
+	n := 0
+	for i := 0; i < len(p); i++ {
+		switch p[i] {
+		case '*', '?':
+		case '[':
+			if j := strings.IndexByte(p[i:], ']'); j > 0 {
+				i += j
+			}
+		case '\\':
+			i++
+			n++
+		default:
+			n++
+		}
+	}
+	return n
+}
+
+// normalizeExt lowercases ext and ensures it has a leading dot.
+func normalizeExt(ext string) string {
+	ext = strings.ToLower(ext)
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+// setContentType sets the Content-Type header for name to
+// configuredContentType. When that is empty the header is left untouched
+// and http.ServeContent falls back to mime.TypeByExtension and content
+// sniffing.
+func setContentType(cfg Config, w http.ResponseWriter, name string) {
+	if ctype := configuredContentType(cfg, name); ctype != "" {
+		w.Header().Set("Content-Type", ctype)
+	}
+}
+
+// configuredContentType returns the Content-Type for name from the
+// configured patterns, then the built-in file name defaults, then the
+// configured MIME overrides, then the built-in extension defaults, or ""
+// when none matches.
+func configuredContentType(cfg Config, name string) string {
+	if ctype, ok := contentTypeOverride(cfg, name); ok {
+		return ctype
+	}
+	if ctype, ok := defaultFileTypes[path.Base(name)]; ok {
+		return ctype
+	}
+	ext := normalizeExt(path.Ext(name))
+	if ctype, ok := cfg.MIMETypes[ext]; ok {
+		return ctype
+	}
+	return defaultMIMETypes[ext]
+}
@@ -0,0 +1,274 @@
+package spaserver
+
+import (
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeWithMIMEType(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":       {Data: []byte("index.html")},
+		"app.wasm":         {Data: []byte("\x00asm\x01\x00\x00\x00")},
+		"photo.heic":       {Data: []byte("heic")},
+		"css/main.css":     {Data: []byte("body {}")},
+		"data/report.AVIF": {Data: []byte("avif")},
+	}
+
+	tt := []struct {
+		name string
+		opts []Option
+		url  string
+		want string
+	}{
+		{
+			name: "wasm override",
+			opts: []Option{WithMIMEType(".wasm", "application/wasm")},
+			url:  "http://www.example.com/app.wasm",
+			want: "application/wasm",
+		},
+		{
+			name: "extension without leading dot",
+			opts: []Option{WithMIMEType("heic", "image/heic")},
+			url:  "http://www.example.com/photo.heic",
+			want: "image/heic",
+		},
+		{
+			name: "extension matched case-insensitively",
+			opts: []Option{WithMIMEType(".avif", "image/avif")},
+			url:  "http://www.example.com/data/report.AVIF",
+			want: "image/avif",
+		},
+		{
+			name: "multiple calls accumulate",
+			opts: []Option{WithMIMEType(".heic", "image/heic"), WithMIMEType(".css", "text/css")},
+			url:  "http://www.example.com/css/main.css",
+			want: "text/css",
+		},
+		{
+			name: "no override uses default detection",
+			url:  "http://www.example.com/css/main.css",
+			want: "text/css; charset=utf-8",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Serve(fsys, tc.opts...)
+
+			r, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != 200 {
+				t.Fatalf("statusCode expected: 200, got: %d", w.Code)
+			}
+			if got := w.Header().Get("Content-Type"); got != tc.want {
+				t.Errorf("Content-Type expected: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestServeWithMIMETypeIgnoresOSDatabase(t *testing.T) {
+	// Simulate an OS MIME database with a wrong mapping for .wasm.
+	if err := mime.AddExtensionType(".wasm", "application/octet-stream"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { mime.AddExtensionType(".wasm", "application/wasm") })
+
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("index.html")},
+		"app.wasm":   {Data: []byte("\x00asm\x01\x00\x00\x00")},
+	}
+	h := Serve(fsys, WithMIMEType(".wasm", "application/wasm"))
+
+	r, err := http.NewRequest(http.MethodGet, "http://www.example.com/app.wasm", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if got := w.Header().Get("Content-Type"); got != "application/wasm" {
+		t.Errorf("Content-Type expected: application/wasm, got: %q", got)
+	}
+}
+
+func TestServeWASMContentType(t *testing.T) {
+	// Simulate an OS MIME database without the application/wasm mapping.
+	if err := mime.AddExtensionType(".wasm", "application/octet-stream"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { mime.AddExtensionType(".wasm", "application/wasm") })
+
+	fsys := fstest.MapFS{
+		"index.html":   {Data: []byte("index.html")},
+		"pkg/app.wasm": {Data: []byte("\x00asm\x01\x00\x00\x00")},
+	}
+	h := Serve(fsys)
+
+	r, err := http.NewRequest(http.MethodGet, "http://www.example.com/pkg/app.wasm", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != 200 {
+		t.Fatalf("statusCode expected: 200, got: %d", w.Code)
+	}
+	if got := w.Header().Values("Content-Type"); len(got) != 1 || got[0] != "application/wasm" {
+		t.Errorf("Content-Type expected: [application/wasm], got: %q", got)
+	}
+}
+
+func TestServeWebManifestContentType(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":          {Data: []byte("index.html")},
+		"manifest.json":       {Data: []byte(`{"name":"app"}`)},
+		"pwa/app.webmanifest": {Data: []byte(`{"name":"app"}`)},
+		"data/config.json":    {Data: []byte(`{}`)},
+	}
+	h := Serve(fsys, WithMIMEType(".json", "application/json; charset=utf-8"))
+
+	tt := []struct {
+		url  string
+		want string
+	}{
+		{url: "http://www.example.com/manifest.json", want: "application/manifest+json"},
+		{url: "http://www.example.com/pwa/app.webmanifest", want: "application/manifest+json"},
+		{url: "http://www.example.com/data/config.json", want: "application/json; charset=utf-8"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.url, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Content-Type"); got != tc.want {
+				t.Errorf("Content-Type expected: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestServeWithContentType(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":         {Data: []byte("index.html")},
+		"data/config.json":   {Data: []byte("{}")},
+		"src/app.ts":         {Data: []byte("export {}")},
+		"manifest.json":      {Data: []byte("{}")},
+		"app.wasm":           {Data: []byte("\x00asm\x01\x00\x00\x00")},
+		"api/v1/schema.json": {Data: []byte("{}")},
+	}
+
+	tt := []struct {
+		name string
+		opts []Option
+		url  string
+		want string
+	}{
+		{
+			name: "base name pattern",
+			opts: []Option{WithContentType("*.json", "application/json; charset=utf-8")},
+			url:  "http://www.example.com/data/config.json",
+			want: "application/json; charset=utf-8",
+		},
+		{
+			name: "unknown extension",
+			opts: []Option{WithContentType("*.ts", "application/typescript")},
+			url:  "http://www.example.com/src/app.ts",
+			want: "application/typescript",
+		},
+		{
+			name: "url path pattern",
+			opts: []Option{WithContentType("/api/*/*.json", "application/schema+json")},
+			url:  "http://www.example.com/api/v1/schema.json",
+			want: "application/schema+json",
+		},
+		{
+			name: "longer literal wins",
+			opts: []Option{
+				WithContentType("*.json", "application/json"),
+				WithContentType("/api/*/schema.json", "application/schema+json"),
+				WithContentType("*", "application/octet-stream"),
+			},
+			url:  "http://www.example.com/api/v1/schema.json",
+			want: "application/schema+json",
+		},
+		{
+			name: "wildcard falls back",
+			opts: []Option{
+				WithContentType("schema.json", "application/schema+json"),
+				WithContentType("*.json", "application/json"),
+			},
+			url:  "http://www.example.com/data/config.json",
+			want: "application/json",
+		},
+		{
+			name: "overrides file name default",
+			opts: []Option{WithContentType("manifest.json", "application/json")},
+			url:  "http://www.example.com/manifest.json",
+			want: "application/json",
+		},
+		{
+			name: "overrides mime type",
+			opts: []Option{WithMIMEType(".wasm", "application/octet-stream"), WithContentType("*.wasm", "application/wasm")},
+			url:  "http://www.example.com/app.wasm",
+			want: "application/wasm",
+		},
+		{
+			name: "no match",
+			opts: []Option{WithContentType("*.ts", "application/typescript")},
+			url:  "http://www.example.com/data/config.json",
+			want: "application/json",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Serve(fsys, tc.opts...)
+
+			r, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != 200 {
+				t.Fatalf("statusCode expected: 200, got: %d", w.Code)
+			}
+			if got := w.Header().Get("Content-Type"); got != tc.want {
+				t.Errorf("Content-Type expected: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLiteralLen(t *testing.T) {
+	tt := []struct {
+		pattern string
+		want    int
+	}{
+		{"*", 0},
+		{"*.json", 5},
+		{"/api/*/schema.json", 17},
+		{"app.[jt]s", 5},
+		{`a\*b`, 3},
+		{"?.js", 3},
+	}
+
+	for _, tc := range tt {
+		if got := literalLen(tc.pattern); got != tc.want {
+			t.Errorf("literalLen(%q) expected: %d, got: %d", tc.pattern, tc.want, got)
+		}
+	}
+}
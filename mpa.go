@@ -0,0 +1,51 @@
+package spaserver
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// WithMPAMode serves a multi-page site instead of a single-page app: paths
+// that match no file get 404 Not Found, or the WithNotFoundPage page,
+// rather than index.html, and a directory serves the index.html inside it
+// with the same headers as the root index page. Directory requests without
+// a trailing slash are redirected to add one, so relative links resolve.
+// The handler is then a static file server with spaserver's security
+// header defaults, for documentation or marketing sites.
+func WithMPAMode() Option {
+	return func(c *Config) {
+		c.MPAMode = true
+	}
+}
+
+// serveDirectoryIndex serves the index file of the directory dir in MPA
+// mode.
+func serveDirectoryIndex(s *site, cfg Config, w http.ResponseWriter, r *http.Request, dir string) {
+	if !strings.HasSuffix(r.URL.Path, "/") {
+		localRedirect(w, r, path.Base(r.URL.Path)+"/")
+		return
+	}
+
+	name := path.Join(dir, indexPage)
+	start := time.Now()
+	b, err := s.readFile(name)
+	requestTiming(r).add(timingFS, start)
+	if errors.Is(err, fs.ErrPermission) {
+		serveError(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		serveNotFound(s, cfg, w, r)
+		return
+	}
+
+	setPageHeaders(s, cfg, w)
+	setContentType(cfg, w, name)
+	setBodyDigest(cfg, w, r, b)
+	http.ServeContent(w, r, name, s.indexModTime(cfg, name), bytes.NewReader(b))
+}
@@ -0,0 +1,78 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeWithMPAMode(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":      {Data: []byte("home")},
+		"404.html":        {Data: []byte("not found")},
+		"about.html":      {Data: []byte("about")},
+		"docs/index.html": {Data: []byte("docs")},
+		"css/main.css":    {Data: []byte("body {}")},
+	}
+
+	tt := []struct {
+		name     string
+		opts     []Option
+		url      string
+		status   int
+		body     string
+		location string
+	}{
+		{name: "root", url: "/", status: http.StatusOK, body: "home"},
+		{name: "page", url: "/about.html", status: http.StatusOK, body: "about"},
+		{name: "static file", url: "/css/main.css", status: http.StatusOK, body: "body {}"},
+		{name: "directory index", url: "/docs/", status: http.StatusOK, body: "docs"},
+		{name: "directory without slash", url: "/docs", status: http.StatusMovedPermanently, location: "docs/"},
+		{name: "index redirect", url: "/docs/index.html", status: http.StatusMovedPermanently, location: "./"},
+		{name: "directory without index", url: "/css/", status: http.StatusNotFound, body: "404 Page Not Found\n"},
+		{name: "unknown path", url: "/some/route", status: http.StatusNotFound, body: "404 Page Not Found\n"},
+		{name: "not found page", opts: []Option{WithNotFoundPage("404.html")}, url: "/some/route", status: http.StatusNotFound, body: "not found"},
+		{name: "missing not found page", opts: []Option{WithNotFoundPage("missing.html")}, url: "/some/route", status: http.StatusNotFound, body: "404 Page Not Found\n"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Serve(fsys, append([]Option{WithMPAMode()}, tc.opts...)...)
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+tc.url, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("status expected: %d, got: %d", tc.status, w.Code)
+			}
+			if tc.body != "" && w.Body.String() != tc.body {
+				t.Errorf("body expected: %q, got: %q", tc.body, w.Body.String())
+			}
+			if got := w.Header().Get("Location"); got != tc.location {
+				t.Errorf("Location expected: %q, got: %q", tc.location, got)
+			}
+		})
+	}
+}
+
+func TestServeWithMPAModeDirectoryHeaders(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":      {Data: []byte("home")},
+		"docs/index.html": {Data: []byte("docs")},
+	}
+	h := Serve(fsys, WithMPAMode())
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/docs/", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	for k, want := range map[string]string{
+		"Cache-Control":           noCacheHeaders["Cache-Control"],
+		"Content-Security-Policy": defaultCSP,
+		"Content-Type":            "text/html; charset=utf-8",
+	} {
+		if got := w.Header().Get(k); got != want {
+			t.Errorf("%s expected: %q, got: %q", k, want, got)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package spaserver
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// WithNotFoundPage serves the named file with a 404 Not Found status for
+// requests whose path does not exist, instead of falling back to index.html
+// with a 200. This matches the 404.html convention of Netlify and GitHub
+// Pages. The page is sent with the same no-cache and security headers as
+// index.html. If the file does not exist, a warning is logged and the
+// index.html fallback is used.
+func WithNotFoundPage(name string) Option {
+	return func(c *Config) {
+		c.NotFoundPage = name
+	}
+}
+
+// serveNotFound serves the configured not-found page with a 404 status.
+// When no page is configured or it is missing, it falls back to the index
+// page, or to a plain 404 in MPA mode.
+func serveNotFound(s *site, cfg Config, w http.ResponseWriter, r *http.Request) {
+	if cfg.NotFoundPage == "" {
+		serveNotFoundFallback(s, cfg, w, r)
+		return
+	}
+
+	name := strings.TrimPrefix(cfg.NotFoundPage, "/")
+	b, err := s.readFile(name)
+	if err != nil {
+		cfg.Logger.Warn("spaserver: not found page not loaded", "path", name, "error", err)
+		serveNotFoundFallback(s, cfg, w, r)
+		return
+	}
+
+	setPageHeaders(s, cfg, w)
+	setContentType(cfg, w, name)
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+	w.WriteHeader(http.StatusNotFound)
+	if r.Method != http.MethodHead {
+		w.Write(b)
+	}
+}
+
+// serveNotFoundFallback answers a request for a missing path when there is
+// no not-found page.
+func serveNotFoundFallback(s *site, cfg Config, w http.ResponseWriter, r *http.Request) {
+	if cfg.MPAMode {
+		serveError(w, "404 Page Not Found", http.StatusNotFound)
+		return
+	}
+	serveIndex(s, cfg, w, r)
+}
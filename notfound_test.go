@@ -0,0 +1,76 @@
+package spaserver
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeWithNotFoundPage(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":   {Data: []byte("index.html")},
+		"404.html":     {Data: []byte("not found")},
+		"css/main.css": {Data: []byte("body {}")},
+	}
+
+	tt := []struct {
+		name       string
+		page       string
+		method     string
+		url        string
+		statusCode int
+		body       string
+		warn       bool
+	}{
+		{name: "missing path serves 404 page", page: "404.html", url: "http://www.example.com/missing", statusCode: 404, body: "not found"},
+		{name: "HEAD omits body", page: "404.html", method: http.MethodHead, url: "http://www.example.com/missing", statusCode: 404},
+		{name: "root still serves index", page: "404.html", url: "http://www.example.com/", statusCode: 200, body: "index.html"},
+		{name: "existing file served", page: "404.html", url: "http://www.example.com/css/main.css", statusCode: 200, body: "body {}"},
+		{name: "missing page falls back to index", page: "missing.html", url: "http://www.example.com/missing", statusCode: 200, body: "index.html", warn: true},
+		{name: "option unset serves index", url: "http://www.example.com/missing", statusCode: 200, body: "index.html"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			opts := []Option{WithLogger(slog.New(slog.NewTextHandler(&logs, nil)))}
+			if tc.page != "" {
+				opts = append(opts, WithNotFoundPage(tc.page))
+			}
+			h := Serve(fsys, opts...)
+
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			r := httptest.NewRequest(method, tc.url, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.statusCode {
+				t.Errorf("statusCode expected: %d, got: %d", tc.statusCode, w.Code)
+			}
+			if body := w.Body.String(); body != tc.body {
+				t.Errorf("body expected: %q, got: %q", tc.body, body)
+			}
+			if tc.statusCode == 404 {
+				if got := w.Header().Get("Cache-Control"); got != noCacheHeaders["Cache-Control"] {
+					t.Errorf("Cache-Control expected: %q, got: %q", noCacheHeaders["Cache-Control"], got)
+				}
+				if got := w.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+					t.Errorf("Content-Type expected: text/html; charset=utf-8, got: %q", got)
+				}
+				if got := w.Header().Get("Content-Length"); got != "9" {
+					t.Errorf("Content-Length expected: 9, got: %q", got)
+				}
+			}
+			if warned := strings.Contains(logs.String(), "level=WARN"); warned != tc.warn {
+				t.Errorf("warning logged expected: %v, got: %v (%q)", tc.warn, warned, logs.String())
+			}
+		})
+	}
+}
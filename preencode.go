@@ -0,0 +1,239 @@
+package spaserver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithPreencodeGzip enables gzip and compresses every compressible static
+// file once, in the background, when the handler is created and on each
+// Reload. Compressed requests for those files are then served from memory
+// instead of being compressed on every request, moving the CPU cost to
+// startup. Files are compressed at gzip.BestCompression. Until a file has
+// been encoded, or if it changes on disk afterwards, it is compressed per
+// request as usual. With WithFileCacheTTL, an expired file is encoded
+// again once, in the background, and compressed per request meanwhile.
+// Encoding stops when Reload replaces the filesystem.
+func WithPreencodeGzip() Option {
+	return func(c *Config) {
+		c.Gzip = true
+		c.PreencodeGzip = true
+	}
+}
+
+// WithPreencodeWorkers sets the number of goroutines compressing files for
+// WithPreencodeGzip. Defaults to half of GOMAXPROCS, and at least 1, so
+// startup does not saturate the CPU.
+func WithPreencodeWorkers(n int) Option {
+	return func(c *Config) {
+		c.PreencodeWorkers = n
+	}
+}
+
+// preencodeWorkers returns the number of goroutines compressing files.
+func (cfg Config) preencodeWorkers() int {
+	if cfg.PreencodeWorkers > 0 {
+		return cfg.PreencodeWorkers
+	}
+	return max(1, runtime.GOMAXPROCS(0)/2)
+}
+
+// gzipFile is the gzip encoding of a static file with the given
+// modification time and size.
+type gzipFile struct {
+	modTime time.Time
+	size    int64
+	ctype   string
+	data    []byte
+	digest  string // Content-Digest of data, empty unless ContentDigest is set
+	stored  time.Time
+}
+
+// gzipCache holds the pre-encoded files of a site, keyed by name.
+type gzipCache struct {
+	files sync.Map      // name to *gzipFile
+	done  chan struct{} // closed when every file has been encoded
+	ttl   time.Duration // zero keeps files until they change
+	now   func() time.Time
+
+	// ctx is cancelled when the site is replaced, stopping the encoding
+	ctx  context.Context
+	stop context.CancelFunc
+	// refreshing holds the names of expired files being encoded again
+	refreshing sync.Map
+	refreshes  sync.WaitGroup
+}
+
+// preencode starts encoding the compressible files of s in the background.
+// The encoding stops when the site is closed.
+func (s *site) preencode(cfg Config) {
+	ctx, stop := context.WithCancel(context.Background())
+	s.gzipped = &gzipCache{done: make(chan struct{}), ttl: time.Duration(cfg.FileCacheTTL), now: time.Now, ctx: ctx, stop: stop}
+
+	names := make(chan string)
+	var wg sync.WaitGroup
+	for range cfg.preencodeWorkers() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range names {
+				if err := s.encodeGzip(cfg, name); err != nil {
+					cfg.Logger.Warn("spaserver: file not pre-encoded", "path", name, "error", err)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		err := fs.WalkDir(s.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && !blocked(cfg, name) {
+				select {
+				case names <- name:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, context.Canceled) {
+			cfg.Logger.Warn("spaserver: pre-encoding stopped", "error", err)
+		}
+		close(names)
+		wg.Wait()
+		close(s.gzipped.done)
+	}()
+}
+
+// encodeGzip stores the gzip encoding of the file name if it is eligible
+// for compression.
+func (s *site) encodeGzip(cfg Config, name string) error {
+	if compressedExts[strings.ToLower(path.Ext(name))] {
+		return nil
+	}
+	// Without a known type the response would be sniffed, which needs the
+	// identity bytes
+	ctype := configuredContentType(cfg, name)
+	if ctype == "" {
+		ctype = mime.TypeByExtension(path.Ext(name))
+	}
+	if ctype == "" || !matchMediaType(cfg.CompressionTypes, ctype) || matchMediaType(cfg.CompressionExcludeTypes, ctype) {
+		return nil
+	}
+
+	info, seeker, closeFile, err := s.openStatic(name)
+	if err != nil {
+		return err
+	}
+	defer closeFile()
+	if info.IsDir() || info.Size() < *cfg.CompressionMinSize {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(zw, seeker); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	f := &gzipFile{modTime: info.ModTime(), size: info.Size(), ctype: ctype, data: buf.Bytes(), stored: s.gzipped.now()}
+	if cfg.ContentDigest != "" {
+		f.digest = digestBytes(cfg.ContentDigest, f.data)
+	}
+	s.gzipped.files.Store(name, f)
+	return nil
+}
+
+// refreshGzip encodes the expired file name again in the background,
+// unless it is already being encoded or the site has been closed.
+func (s *site) refreshGzip(cfg Config, name string) {
+	c := s.gzipped
+	if c.ctx.Err() != nil {
+		return
+	}
+	if _, busy := c.refreshing.LoadOrStore(name, struct{}{}); busy {
+		return
+	}
+	c.refreshes.Add(1)
+	go func() {
+		defer c.refreshes.Done()
+		defer c.refreshing.Delete(name)
+		if err := s.encodeGzip(cfg, name); err != nil {
+			cfg.Logger.Warn("spaserver: file not pre-encoded", "path", name, "error", err)
+		}
+	}()
+}
+
+// lookup returns the encoding of name if it matches the file described by
+// info.
+func (c *gzipCache) lookup(name string, info fs.FileInfo) (*gzipFile, bool) {
+	v, ok := c.files.Load(name)
+	if !ok {
+		return nil, false
+	}
+	f := v.(*gzipFile)
+	if !f.modTime.Equal(info.ModTime()) || f.size != info.Size() {
+		return nil, false
+	}
+	return f, true
+}
+
+// servePreencoded serves the pre-encoded gzip body of the static file name
+// and reports whether it did. Headers other than the encoding's must
+// already be set.
+func servePreencoded(s *site, cfg Config, w http.ResponseWriter, r *http.Request, name string, info fs.FileInfo) bool {
+	if s.gzipped == nil || negotiateEncoding(r.Header.Get("Accept-Encoding"), cfg.encodings()) != "gzip" {
+		return false
+	}
+	start := time.Now()
+	f, ok := s.gzipped.lookup(name, info)
+	if ok && expired(f.stored, s.gzipped.now(), s.gzipped.ttl) {
+		// The file may have changed in place: compress it per request
+		// until it has been encoded again
+		s.refreshGzip(cfg, name)
+		ok = false
+	}
+	requestTiming(r).add(timingCache, start)
+	if !ok {
+		return false
+	}
+
+	h := w.Header()
+	if h.Get("Content-Type") == "" {
+		h.Set("Content-Type", f.ctype)
+	}
+	addVary(h, "Accept-Encoding")
+	h.Set("Content-Encoding", "gzip")
+	// Replace the digest of the identity bytes, which is absent for Range
+	// requests
+	if f.digest != "" && h.Get("Content-Digest") != "" {
+		h.Set("Content-Digest", f.digest)
+	}
+	// A strong ETag identifies the exact bytes of the identity
+	// representation
+	if etag := h.Get("Etag"); strings.HasPrefix(etag, `"`) {
+		h.Set("Etag", "W/"+etag)
+	}
+
+	http.ServeContent(w, r, path.Base(name), info.ModTime(), bytes.NewReader(f.data))
+	return true
+}
@@ -0,0 +1,240 @@
+package spaserver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// waitPreencoded waits for the current site of h to finish pre-encoding.
+func waitPreencoded(t *testing.T, h *Handler) {
+	t.Helper()
+	select {
+	case <-h.current.Load().gzipped.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pre-encoding did not finish")
+	}
+}
+
+func TestServeWithPreencodeGzip(t *testing.T) {
+	script := strings.Repeat("console.log('hello');\n", 100)
+	fsys := fstest.MapFS{
+		"index.html":     {Data: []byte("index.html")},
+		"js/app.js":      {Data: []byte(script)},
+		"js/small.js":    {Data: []byte("1")},
+		"img/photo.png":  {Data: bytes.Repeat([]byte{0x89}, 2048)},
+		"data/export.gz": {Data: []byte("gz")},
+	}
+
+	h, err := New(fsys, WithPreencodeGzip(), WithPreencodeWorkers(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitPreencoded(t, h)
+
+	var names []string
+	h.current.Load().gzipped.files.Range(func(k, _ any) bool {
+		names = append(names, k.(string))
+		return true
+	})
+	if len(names) != 1 || names[0] != "js/app.js" {
+		t.Errorf("pre-encoded files expected: [js/app.js], got: %v", names)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/js/app.js", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status expected: 200, got: %d", w.Code)
+	}
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding expected: gzip, got: %q", got)
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/javascript; charset=utf-8" {
+		t.Errorf("Content-Type expected: %q, got: %q", "text/javascript; charset=utf-8", got)
+	}
+	if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+		t.Errorf("Vary expected: Accept-Encoding, got: %q", got)
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := io.ReadAll(zr); string(b) != script {
+		t.Error("decoded body does not match the file")
+	}
+
+	// Identity requests are unaffected
+	r = httptest.NewRequest(http.MethodGet, "http://www.example.com/js/app.js", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("identity Content-Encoding expected to be absent, got: %q", got)
+	}
+	if w.Body.String() != script {
+		t.Error("identity body does not match the file")
+	}
+}
+
+func TestServeWithPreencodeGzipChangedFile(t *testing.T) {
+	script := strings.Repeat("console.log('v1');\n", 100)
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("index.html")},
+		"js/app.js":  {Data: []byte(script)},
+	}
+
+	h, err := New(fsys, WithPreencodeGzip())
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitPreencoded(t, h)
+
+	updated := strings.Repeat("console.log('v2');\n", 200)
+	fsys["js/app.js"] = &fstest.MapFile{Data: []byte(updated), ModTime: time.Now()}
+
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/js/app.js", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := io.ReadAll(zr); string(b) != updated {
+		t.Error("expected the changed file to be compressed per request")
+	}
+}
+
+func TestServeWithFileCacheTTL(t *testing.T) {
+	dir := t.TempDir()
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	write := func(name, data string) {
+		t.Helper()
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		// Keep the modification time, as a same-second deploy would
+		if err := os.Chtimes(p, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before := strings.Repeat("console.log('before');\n", 100)
+	after := strings.Repeat("console.log('after!');\n", 100)
+	write("index.html", "index.html")
+	write("app.js", before)
+
+	const ttl = time.Minute
+	h, err := New(os.DirFS(dir), WithPreencodeGzip(), WithContentHashETags(), WithFileCacheTTL(ttl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitPreencoded(t, h)
+	clock := newFakeClock()
+	clock.setOn(h.current.Load())
+
+	get := func() (body, etag string) {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com/app.js", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		zr, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b), w.Header().Get("Etag")
+	}
+
+	body, etag := get()
+	if body != before {
+		t.Fatalf("body expected to be the original file")
+	}
+
+	write("app.js", after)
+	if body, cached := get(); body != before || cached != etag {
+		t.Errorf("expected the cached file within the TTL")
+	}
+
+	clock.advance(ttl + time.Second)
+	if body, fresh := get(); body != after || fresh == etag {
+		t.Errorf("expected the changed file after the TTL")
+	}
+
+	// The expired file is encoded again once, in the background
+	gz := h.current.Load().gzipped
+	gz.refreshes.Wait()
+	if f, ok := gz.files.Load("app.js"); !ok || expired(f.(*gzipFile).stored, clock.now(), ttl) {
+		t.Errorf("expected app.js to be encoded again")
+	}
+	if body, _ := get(); body != after {
+		t.Errorf("expected the changed file once encoded again")
+	}
+}
+
+// fakeClock is a clock for the per-file caches that only moves when
+// advanced.
+type fakeClock struct{ ns atomic.Int64 }
+
+func newFakeClock() *fakeClock {
+	c := &fakeClock{}
+	c.ns.Store(time.Now().UnixNano())
+	return c
+}
+
+func (c *fakeClock) now() time.Time          { return time.Unix(0, c.ns.Load()) }
+func (c *fakeClock) advance(d time.Duration) { c.ns.Add(int64(d)) }
+
+// setOn makes c the clock of the caches of s, which must not be serving
+// requests.
+func (c *fakeClock) setOn(s *site) {
+	s.etags.now = c.now
+	s.digests.now = c.now
+	s.gunzipped.now = c.now
+	if s.gzipped != nil {
+		s.gzipped.now = c.now
+	}
+}
+
+func TestReloadStopsPreencoding(t *testing.T) {
+	fsys := fstest.MapFS{"index.html": {Data: []byte("index.html")}}
+	for i := range 50 {
+		fsys[fmt.Sprintf("js/%d.js", i)] = &fstest.MapFile{Data: []byte(strings.Repeat("console.log('hello');\n", 100))}
+	}
+	h, err := New(fsys, WithPreencodeGzip(), WithPreencodeWorkers(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := h.current.Load()
+
+	if err := h.Reload(fsys); err != nil {
+		t.Fatal(err)
+	}
+	if old.gzipped.ctx.Err() == nil {
+		t.Error("expected the replaced site's encoding to be cancelled")
+	}
+	select {
+	case <-old.gzipped.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pre-encoding of the replaced site did not stop")
+	}
+	waitPreencoded(t, h)
+}
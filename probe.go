@@ -0,0 +1,79 @@
+package spaserver
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+)
+
+// WithKubeProbes answers Kubernetes liveness, readiness and startup probes
+// at the given paths, e.g. "/livez", "/readyz" and "/healthz". An empty
+// path disables that probe. Probe requests bypass all SPA handling.
+//
+//   - liveness always responds 200 while the process is serving requests.
+//   - readiness responds 200 when index.html is accessible, 503 otherwise.
+//   - startup responds 200 when index.html was readable and all state
+//     derived from the filesystem (webpack manifest, preloaded index)
+//     loaded when the filesystem was last loaded by New, Serve or Reload,
+//     503 otherwise. The probe reports that result and never reloads.
+//
+// When the handler is mounted under a sub-path (for example with
+// http.StripPrefix), use Handler.RegisterProbes to also answer the probes
+// at their bare paths on the outer mux.
+func WithKubeProbes(liveness, readiness, startup string) Option {
+	return func(c *Config) {
+		c.LivenessPath = liveness
+		c.ReadinessPath = readiness
+		c.StartupPath = startup
+	}
+}
+
+// RegisterProbes registers the configured probe paths on mux, or on
+// http.DefaultServeMux when mux is nil.
+func (h *Handler) RegisterProbes(mux *http.ServeMux) {
+	if mux == nil {
+		mux = http.DefaultServeMux
+	}
+	for _, p := range []string{h.cfg.LivenessPath, h.cfg.ReadinessPath, h.cfg.StartupPath} {
+		if p != "" {
+			mux.Handle(p, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				h.serveProbe(w, r, p)
+			}))
+		}
+	}
+}
+
+// isProbe reports whether upath is one of the configured probe paths.
+func (h *Handler) isProbe(upath string) bool {
+	switch upath {
+	case "":
+		return false
+	case h.cfg.LivenessPath, h.cfg.ReadinessPath, h.cfg.StartupPath:
+		return true
+	}
+	return false
+}
+
+// serveProbe answers the probe configured at upath.
+func (h *Handler) serveProbe(w http.ResponseWriter, r *http.Request, upath string) {
+	s := h.current.Load()
+
+	var err error
+	switch upath {
+	case h.cfg.LivenessPath:
+	case h.cfg.ReadinessPath:
+		_, err = fs.Stat(s.fsys, indexPage)
+	case h.cfg.StartupPath:
+		err = s.loadErr
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if err != nil {
+		h.cfg.Logger.Warn("spaserver: probe failed", "path", upath, "error", err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		io.WriteString(w, "not ok")
+		return
+	}
+	io.WriteString(w, "ok")
+}
@@ -0,0 +1,95 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeWithKubeProbes(t *testing.T) {
+	healthy := fstest.MapFS{"index.html": {Data: []byte("index.html")}}
+	broken := fstest.MapFS{"app.js": {Data: []byte("app")}}
+
+	tt := []struct {
+		name       string
+		url        string
+		broken     bool
+		statusCode int
+		body       string
+	}{
+		{name: "liveness", url: "http://www.example.com/livez", statusCode: 200, body: "ok"},
+		{name: "readiness", url: "http://www.example.com/readyz", statusCode: 200, body: "ok"},
+		{name: "startup", url: "http://www.example.com/healthz", statusCode: 200, body: "ok"},
+		{name: "liveness without index", url: "http://www.example.com/livez", broken: true, statusCode: 200, body: "ok"},
+		{name: "readiness without index", url: "http://www.example.com/readyz", broken: true, statusCode: 503, body: "not ok"},
+		{name: "startup without index", url: "http://www.example.com/healthz", broken: true, statusCode: 503, body: "not ok"},
+		{name: "other paths serve the SPA", url: "http://www.example.com/healthz/more", statusCode: 200, body: "index.html"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			fsys := healthy
+			if tc.broken {
+				fsys = broken
+			}
+			h := Serve(fsys, WithKubeProbes("/livez", "/readyz", "/healthz"))
+
+			r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.statusCode {
+				t.Errorf("statusCode expected: %d, got: %d", tc.statusCode, w.Code)
+			}
+			if body := w.Body.String(); body != tc.body {
+				t.Errorf("body expected: %q, got: %q", tc.body, body)
+			}
+			if tc.body != "index.html" {
+				if got := w.Header().Get("Content-Type"); got != "text/plain; charset=utf-8" {
+					t.Errorf("Content-Type expected: text/plain; charset=utf-8, got: %q", got)
+				}
+				if got := w.Header().Get("Content-Security-Policy"); got != "" {
+					t.Errorf("expected no SPA headers on probe, got Content-Security-Policy: %q", got)
+				}
+			}
+		})
+	}
+}
+
+func TestStartupProbeDoesNotReload(t *testing.T) {
+	fsys := &countFS{FS: fstest.MapFS{"index.html": {Data: []byte("index.html")}}}
+	h := Serve(fsys, WithKubeProbes("", "", "/healthz"), WithIndexPreload())
+	opens := fsys.opens.Load()
+
+	for range 3 {
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com/healthz", nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("statusCode expected: %d, got: %d", http.StatusOK, w.Code)
+		}
+	}
+	if got := fsys.opens.Load(); got != opens {
+		t.Errorf("opens expected: %d, got: %d", opens, got)
+	}
+}
+
+func TestHandlerRegisterProbes(t *testing.T) {
+	h := Serve(fstest.MapFS{"index.html": {Data: []byte("index.html")}}, WithKubeProbes("/livez", "/readyz", ""))
+
+	mux := http.NewServeMux()
+	mux.Handle("/admin/", http.StripPrefix("/admin", h))
+	h.RegisterProbes(mux)
+
+	for _, url := range []string{"http://www.example.com/livez", "http://www.example.com/readyz", "http://www.example.com/admin/readyz"} {
+		r := httptest.NewRequest(http.MethodGet, url, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		if w.Code != 200 || w.Body.String() != "ok" {
+			t.Errorf("%s expected: 200 ok, got: %d %q", url, w.Code, w.Body.String())
+		}
+	}
+}
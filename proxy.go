@@ -0,0 +1,212 @@
+package spaserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// ProxyConfig describes a reverse proxy added by WithProxy.
+type ProxyConfig struct {
+	Pattern        string
+	Target         *url.URL
+	Headers        http.Header       // set on every proxied request
+	Transport      http.RoundTripper // nil uses http.DefaultTransport
+	RequestTimeout time.Duration     // zero means no timeout
+}
+
+// ProxyOption configures a reverse proxy added by WithProxy.
+type ProxyOption func(*ProxyConfig)
+
+// ProxyWithHeader sets the header key to val on every proxied request,
+// e.g. an API key the browser must not see.
+func ProxyWithHeader(key, val string) ProxyOption {
+	return func(p *ProxyConfig) {
+		if p.Headers == nil {
+			p.Headers = make(http.Header)
+		}
+		p.Headers.Set(key, val)
+	}
+}
+
+// ProxyWithTransport sends proxied requests with rt instead of
+// http.DefaultTransport.
+func ProxyWithTransport(rt http.RoundTripper) ProxyOption {
+	return func(p *ProxyConfig) {
+		p.Transport = rt
+	}
+}
+
+// ProxyWithRequestTimeout cancels proxied requests that take longer than d,
+// responding 504 Gateway Timeout if nothing was sent yet.
+func ProxyWithRequestTimeout(d time.Duration) ProxyOption {
+	return func(p *ProxyConfig) {
+		p.RequestTimeout = d
+	}
+}
+
+// WithProxy reverse-proxies requests to target when their URL path matches
+// the path.Match glob pattern or lies below a path that does: "/api/*"
+// matches /api/users and /api/v1/users. The literal prefix of the pattern
+// is stripped from the forwarded path, so with a target of
+// http://localhost:8080, /api/v1/users is forwarded as /v1/users. The path
+// is matched and forwarded with dot and empty segments removed.
+//
+// The proxy sets X-Forwarded-For, X-Forwarded-Host and X-Forwarded-Proto.
+// X-Forwarded-For extends the incoming header only for requests from
+// WithTrustedProxies. Proxied requests are subject to the IP filter and
+// basic auth but otherwise bypass all SPA handling. Multiple calls
+// accumulate; the first matching proxy is used.
+func WithProxy(pattern string, target *url.URL, opts ...ProxyOption) Option {
+	p := ProxyConfig{Pattern: pattern, Target: target}
+	for _, opt := range opts {
+		opt(&p)
+	}
+	return func(c *Config) {
+		c.Proxies = append(c.Proxies, p)
+	}
+}
+
+// proxyRoute is a configured reverse proxy.
+type proxyRoute struct {
+	pattern string
+	prefix  string // stripped from forwarded paths
+	handler http.Handler
+}
+
+// newProxyRoutes returns the reverse proxies configured for h. Proxies
+// without a target are skipped; Config.Validate reports them.
+func (h *Handler) newProxyRoutes() []proxyRoute {
+	var routes []proxyRoute
+	for _, p := range h.cfg.Proxies {
+		if p.Target == nil {
+			continue
+		}
+		prefix := p.Pattern
+		if i := strings.IndexAny(prefix, `*?[\`); i >= 0 {
+			prefix = prefix[:i]
+		}
+		prefix = strings.TrimSuffix(prefix, "/")
+		routes = append(routes, proxyRoute{
+			pattern: p.Pattern,
+			prefix:  prefix,
+			handler: h.newProxy(p, prefix),
+		})
+	}
+	return routes
+}
+
+// newProxy returns the reverse proxy handler for p.
+func (h *Handler) newProxy(p ProxyConfig, prefix string) http.Handler {
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			// Forward the path the route matched, so that dot segments
+			// cannot reach the target outside of the stripped prefix. A
+			// RawPath that no longer encodes Path is ignored by the URL.
+			out := pr.Out.URL
+			out.Path = stripProxyPrefix(out.Path, prefix)
+			if out.RawPath != "" {
+				out.RawPath = stripProxyPrefix(out.RawPath, prefix)
+			}
+			pr.SetURL(p.Target)
+
+			// Only a trusted proxy's view of earlier hops is worth passing on
+			if addr, ok := remoteAddr(pr.In); ok && containsAddr(h.trustedProxies, addr) {
+				pr.Out.Header["X-Forwarded-For"] = pr.In.Header["X-Forwarded-For"]
+			}
+			pr.SetXForwarded()
+
+			for k, vs := range p.Headers {
+				pr.Out.Header[http.CanonicalHeaderKey(k)] = vs
+			}
+		},
+		Transport: p.Transport,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			h.cfg.Logger.Warn("spaserver: proxy request failed", "path", r.URL.Path, "target", p.Target.Redacted(), "error", err)
+			if errors.Is(err, context.DeadlineExceeded) {
+				http.Error(w, "504 Gateway Timeout", http.StatusGatewayTimeout)
+				return
+			}
+			http.Error(w, "502 Bad Gateway", http.StatusBadGateway)
+		},
+	}
+
+	if p.RequestTimeout <= 0 {
+		return proxy
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), p.RequestTimeout)
+		defer cancel()
+		proxy.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// stripProxyPrefix returns the URL path p cleaned as proxyHandler matches
+// it, keeping a trailing slash, with prefix removed.
+func stripProxyPrefix(p, prefix string) string {
+	clean := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && clean != "/" {
+		clean += "/"
+	}
+	return ensureLeadingSlash(strings.TrimPrefix(clean, prefix))
+}
+
+// ensureLeadingSlash returns p with a leading slash.
+func ensureLeadingSlash(p string) string {
+	if !strings.HasPrefix(p, "/") {
+		return "/" + p
+	}
+	return p
+}
+
+// proxyHandler returns the handler of the first proxy matching r, or nil.
+func proxyHandler(routes []proxyRoute, r *http.Request) http.Handler {
+	if len(routes) == 0 {
+		return nil
+	}
+	upath := path.Clean("/" + r.URL.Path)
+	for _, route := range routes {
+		for p := upath; ; p = path.Dir(p) {
+			if ok, _ := path.Match(route.pattern, p); ok {
+				return route.handler
+			}
+			if p == "/" {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// validateProxies reports invalid proxy patterns, targets and options.
+func validateProxies(proxies []ProxyConfig) []error {
+	var errs []error
+	for _, p := range proxies {
+		if _, err := path.Match(p.Pattern, ""); err != nil || !strings.HasPrefix(p.Pattern, "/") {
+			errs = append(errs, fmt.Errorf("proxies: invalid pattern %q", p.Pattern))
+		}
+		if p.Target == nil || (p.Target.Scheme != "http" && p.Target.Scheme != "https") || p.Target.Host == "" {
+			errs = append(errs, fmt.Errorf("proxies: %q: target must be an absolute http or https URL", p.Pattern))
+		}
+		if p.RequestTimeout < 0 {
+			errs = append(errs, fmt.Errorf("proxies: %q: request timeout must not be negative", p.Pattern))
+		}
+		for k, vs := range p.Headers {
+			if !validHeaderName(k) {
+				errs = append(errs, fmt.Errorf("proxies: %q: invalid header name %q", p.Pattern, k))
+			}
+			for _, v := range vs {
+				if strings.ContainsAny(v, "\r\n\x00") {
+					errs = append(errs, fmt.Errorf("proxies: %q: header %q: value must not contain CR, LF or NUL characters", p.Pattern, k))
+				}
+			}
+		}
+	}
+	return errs
+}
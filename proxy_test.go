@@ -0,0 +1,181 @@
+package spaserver
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// roundTripFunc is an http.RoundTripper calling itself.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestServeWithProxy(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/base/slow" {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+		}
+		fmt.Fprintf(w, "path=%s xff=%s host=%s key=%s", r.URL.RequestURI(), r.Header.Get("X-Forwarded-For"), r.Header.Get("X-Forwarded-Host"), r.Header.Get("X-Api-Key"))
+	}))
+	defer backend.Close()
+	target, _ := url.Parse(backend.URL + "/base")
+
+	fsys := fstest.MapFS{"index.html": {Data: []byte("index.html")}}
+	h := Serve(fsys,
+		WithProxy("/api/*", target, ProxyWithHeader("X-Api-Key", "secret"), ProxyWithRequestTimeout(50*time.Millisecond)),
+		WithTrustedProxies("10.0.0.0/8"),
+	)
+
+	tt := []struct {
+		name       string
+		url        string
+		remoteAddr string
+		xff        string
+		status     int
+		body       string
+	}{
+		{
+			name: "stripped prefix", url: "http://www.example.com/api/v1/users?page=2", remoteAddr: "203.0.113.7:1234",
+			status: http.StatusOK, body: "path=/base/v1/users?page=2 xff=203.0.113.7 host=www.example.com key=secret",
+		},
+		{
+			name: "untrusted forwarded for", url: "http://www.example.com/api/users", remoteAddr: "203.0.113.7:1234", xff: "198.51.100.1",
+			status: http.StatusOK, body: "path=/base/users xff=203.0.113.7 host=www.example.com key=secret",
+		},
+		{
+			name: "trusted forwarded for", url: "http://www.example.com/api/users", remoteAddr: "10.0.0.2:1234", xff: "198.51.100.1",
+			status: http.StatusOK, body: "path=/base/users xff=198.51.100.1, 10.0.0.2 host=www.example.com key=secret",
+		},
+		{
+			name: "dot segments", url: "http://www.example.com/api/v1/../users", remoteAddr: "203.0.113.7:1234",
+			status: http.StatusOK, body: "path=/base/users xff=203.0.113.7 host=www.example.com key=secret",
+		},
+		{
+			name: "empty segments", url: "http://www.example.com/api//users/", remoteAddr: "203.0.113.7:1234",
+			status: http.StatusOK, body: "path=/base/users/ xff=203.0.113.7 host=www.example.com key=secret",
+		},
+		{
+			name: "escaped slash", url: "http://www.example.com/api/files/a%2Fb", remoteAddr: "203.0.113.7:1234",
+			status: http.StatusOK, body: "path=/base/files/a%2Fb xff=203.0.113.7 host=www.example.com key=secret",
+		},
+		{name: "dot segments out of prefix", url: "http://www.example.com/api/../secret", remoteAddr: "203.0.113.7:1234", status: http.StatusOK, body: "index.html"},
+		{name: "timeout", url: "http://www.example.com/api/slow", remoteAddr: "203.0.113.7:1234", status: http.StatusGatewayTimeout},
+		{name: "not proxied", url: "http://www.example.com/apiary", remoteAddr: "203.0.113.7:1234", status: http.StatusOK, body: "index.html"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			r.RemoteAddr = tc.remoteAddr
+			if tc.xff != "" {
+				r.Header.Set("X-Forwarded-For", tc.xff)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("status expected: %d, got: %d", tc.status, w.Code)
+			}
+			if tc.body != "" && w.Body.String() != tc.body {
+				t.Errorf("body expected: %q, got: %q", tc.body, w.Body.String())
+			}
+			if tc.body != "index.html" && w.Header().Get("Content-Security-Policy") != "" {
+				t.Errorf("Content-Security-Policy expected to be absent, got: %q", w.Header().Get("Content-Security-Policy"))
+			}
+		})
+	}
+}
+
+func TestProxyWithTransport(t *testing.T) {
+	var got *http.Request
+	rt := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		got = r
+		return &http.Response{StatusCode: http.StatusTeapot, Header: http.Header{}, Body: http.NoBody, Request: r}, nil
+	})
+	target, _ := url.Parse("http://backend.internal")
+	h := Serve(fstest.MapFS{"index.html": {Data: []byte("index.html")}}, WithProxy("/api/*", target, ProxyWithTransport(rt)))
+
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/api/users", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusTeapot {
+		t.Errorf("status expected: %d, got: %d", http.StatusTeapot, w.Code)
+	}
+	if got == nil || got.URL.String() != "http://backend.internal/users" {
+		t.Errorf("proxied URL expected: %q, got: %v", "http://backend.internal/users", got)
+	}
+}
+
+func TestProxyAccessControl(t *testing.T) {
+	rt := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: r}, nil
+	})
+	target, _ := url.Parse("http://backend.internal")
+	h := Serve(fstest.MapFS{"index.html": {Data: []byte("index.html")}},
+		WithProxy("/api/*", target, ProxyWithTransport(rt)),
+		WithIPBlocklist("192.0.2.0/24"),
+		WithBasicAuthFunc("api", func(user, pass string) (bool, error) { return user == "admin" && pass == "s3cret", nil }),
+	)
+
+	tt := []struct {
+		name       string
+		remoteAddr string
+		auth       bool
+		status     int
+	}{
+		{name: "authorized", remoteAddr: "203.0.113.7:1234", auth: true, status: http.StatusOK},
+		{name: "blocked", remoteAddr: "192.0.2.1:1234", auth: true, status: http.StatusForbidden},
+		{name: "unauthenticated", remoteAddr: "203.0.113.7:1234", status: http.StatusUnauthorized},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com/api/x", nil)
+			r.RemoteAddr = tc.remoteAddr
+			if tc.auth {
+				r.SetBasicAuth("admin", "s3cret")
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("status expected: %d, got: %d", tc.status, w.Code)
+			}
+		})
+	}
+}
+
+func TestProxyValidation(t *testing.T) {
+	fsys := fstest.MapFS{"index.html": {Data: []byte("index.html")}}
+	relative, _ := url.Parse("/backend")
+
+	tt := []struct {
+		name string
+		opt  Option
+		want string
+	}{
+		{name: "nil target", opt: WithProxy("/api/*", nil), want: `proxies: "/api/*": target must be an absolute http or https URL`},
+		{name: "relative target", opt: WithProxy("/api/*", relative), want: `proxies: "/api/*": target must be an absolute http or https URL`},
+		{name: "invalid pattern", opt: WithProxy("/api/[", &url.URL{Scheme: "http", Host: "backend"}), want: `proxies: invalid pattern "/api/["`},
+		{name: "invalid header", opt: WithProxy("/api/*", &url.URL{Scheme: "http", Host: "backend"}, ProxyWithHeader("X-Key", "a\r\nb")), want: `header "X-Key": value must not contain CR, LF or NUL characters`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(fsys, tc.opt)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("error expected to contain: %q, got: %v", tc.want, err)
+			}
+		})
+	}
+}
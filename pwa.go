@@ -0,0 +1,109 @@
+package spaserver
+
+import (
+	"fmt"
+	"html"
+	"io/fs"
+	"regexp"
+	"strings"
+)
+
+// PWAConfig configures the Progressive Web App tags injected into the
+// index page by WithPWA.
+type PWAConfig struct {
+	// Manifest is the URL path of the web app manifest, e.g.
+	// "/manifest.json". It must exist in the filesystem.
+	Manifest string `json:"manifest" yaml:"manifest"`
+	// ThemeColor is the theme-color meta tag content, e.g. "#317EFB".
+	ThemeColor string `json:"theme_color,omitempty" yaml:"theme_color,omitempty"`
+	// AppleStatusBarStyle, when set, adds the apple-mobile-web-app-capable
+	// and apple-mobile-web-app-status-bar-style meta tags. It is one of
+	// "default", "black" or "black-translucent".
+	AppleStatusBarStyle string `json:"apple_status_bar_style,omitempty" yaml:"apple_status_bar_style,omitempty"`
+}
+
+// PWAOption configures optional WithPWA tags.
+type PWAOption func(*PWAConfig)
+
+// PWAWithAppleMeta adds the apple-mobile-web-app-capable meta tag and an
+// apple-mobile-web-app-status-bar-style meta tag with statusBarStyle, one
+// of "default", "black" or "black-translucent".
+func PWAWithAppleMeta(statusBarStyle string) PWAOption {
+	return func(p *PWAConfig) {
+		p.AppleStatusBarStyle = statusBarStyle
+	}
+}
+
+// WithPWA injects a <link rel="manifest"> tag for manifestPath and a
+// <meta name="theme-color"> tag for themeColor into the <head> of every
+// index response. Tags already present in the HTML are not duplicated.
+// The manifest must exist in the filesystem; New reports it otherwise.
+func WithPWA(manifestPath, themeColor string, opts ...PWAOption) Option {
+	return func(c *Config) {
+		p := &PWAConfig{Manifest: manifestPath, ThemeColor: themeColor}
+		for _, opt := range opts {
+			opt(p)
+		}
+		c.PWA = p
+	}
+}
+
+// appleStatusBarStyles are the valid apple-mobile-web-app-status-bar-style
+// values.
+var appleStatusBarStyles = map[string]bool{"default": true, "black": true, "black-translucent": true}
+
+var manifestLinkTag = regexp.MustCompile(`(?i)<link\b[^>]*\brel\s*=\s*["']?manifest\b`)
+
+// metaTag returns a regular expression matching a meta tag with name.
+func metaTag(name string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)<meta\b[^>]*\bname\s*=\s*["']?` + regexp.QuoteMeta(name) + `["'\s/>]`)
+}
+
+var (
+	themeColorTag          = metaTag("theme-color")
+	appleCapableTag        = metaTag("apple-mobile-web-app-capable")
+	appleStatusBarStyleTag = metaTag("apple-mobile-web-app-status-bar-style")
+)
+
+// pwaTags returns the PWA tags configured by p that page does not already
+// contain.
+func pwaTags(page []byte, p *PWAConfig) string {
+	var tags strings.Builder
+	if !manifestLinkTag.Match(page) {
+		fmt.Fprintf(&tags, `<link rel="manifest" href="%s">`, html.EscapeString(p.Manifest))
+	}
+	if p.ThemeColor != "" && !themeColorTag.Match(page) {
+		fmt.Fprintf(&tags, `<meta name="theme-color" content="%s">`, html.EscapeString(p.ThemeColor))
+	}
+	if p.AppleStatusBarStyle != "" {
+		if !appleCapableTag.Match(page) {
+			tags.WriteString(`<meta name="apple-mobile-web-app-capable" content="yes">`)
+		}
+		if !appleStatusBarStyleTag.Match(page) {
+			fmt.Fprintf(&tags, `<meta name="apple-mobile-web-app-status-bar-style" content="%s">`, html.EscapeString(p.AppleStatusBarStyle))
+		}
+	}
+	return tags.String()
+}
+
+// checkPWAManifest reports an error if the manifest of p is missing from
+// fsys.
+func checkPWAManifest(fsys fs.FS, p *PWAConfig) error {
+	name := strings.TrimPrefix(p.Manifest, "/")
+	if _, err := fs.Stat(fsys, name); err != nil {
+		return fmt.Errorf("PWA manifest %s not found: %w", name, err)
+	}
+	return nil
+}
+
+// validatePWA reports invalid PWA settings.
+func validatePWA(p *PWAConfig) []error {
+	var errs []error
+	if p.Manifest == "" || !fs.ValidPath(strings.TrimPrefix(p.Manifest, "/")) {
+		errs = append(errs, fmt.Errorf("pwa: invalid manifest path %q", p.Manifest))
+	}
+	if p.AppleStatusBarStyle != "" && !appleStatusBarStyles[p.AppleStatusBarStyle] {
+		errs = append(errs, fmt.Errorf("pwa: invalid apple_status_bar_style %q", p.AppleStatusBarStyle))
+	}
+	return errs
+}
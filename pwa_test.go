@@ -0,0 +1,104 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeWithPWA(t *testing.T) {
+	tt := []struct {
+		name  string
+		index string
+		opts  []PWAOption
+		want  string
+	}{
+		{
+			name:  "tags injected",
+			index: "<html><head><title>App</title></head><body></body></html>",
+			want:  `<html><head><title>App</title><link rel="manifest" href="/manifest.json"><meta name="theme-color" content="#317EFB"></head><body></body></html>`,
+		},
+		{
+			name:  "apple tags injected",
+			index: "<html><head></HEAD></html>",
+			opts:  []PWAOption{PWAWithAppleMeta("black-translucent")},
+			want:  `<html><head><link rel="manifest" href="/manifest.json"><meta name="theme-color" content="#317EFB"><meta name="apple-mobile-web-app-capable" content="yes"><meta name="apple-mobile-web-app-status-bar-style" content="black-translucent"></HEAD></html>`,
+		},
+		{
+			name:  "existing tags kept",
+			index: `<html><head><link rel="manifest" href="/app.webmanifest"><META name="theme-color" content="#000"></head></html>`,
+			want:  `<html><head><link rel="manifest" href="/app.webmanifest"><META name="theme-color" content="#000"></head></html>`,
+		},
+		{
+			name:  "no head",
+			index: "<p>app</p>",
+			want:  "<p>app</p>",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			fsys := fstest.MapFS{
+				"index.html":    {Data: []byte(tc.index)},
+				"manifest.json": {Data: []byte(`{"name":"app"}`)},
+			}
+			h, err := New(fsys, WithPWA("/manifest.json", "#317EFB", tc.opts...))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, url := range []string{"http://www.example.com/", "http://www.example.com/settings"} {
+				r := httptest.NewRequest(http.MethodGet, url, nil)
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+
+				if got := w.Body.String(); got != tc.want {
+					t.Errorf("%s body expected: %q, got: %q", url, tc.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestServeWithPWAIdempotent(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":    {Data: []byte("<head></head>")},
+		"manifest.json": {Data: []byte(`{"name":"app"}`)},
+	}
+	h := Serve(fsys, WithIndexPreload(), WithPWA("/manifest.json", "#fff"))
+
+	for i := 0; i < 2; i++ {
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if n := strings.Count(w.Body.String(), `rel="manifest"`); n != 1 {
+			t.Errorf("request %d: manifest links expected: 1, got: %d", i, n)
+		}
+	}
+}
+
+func TestNewWithPWAInvalid(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":    {Data: []byte("<head></head>")},
+		"manifest.json": {Data: []byte(`{"name":"app"}`)},
+	}
+
+	tt := []struct {
+		name string
+		opt  Option
+	}{
+		{name: "missing manifest", opt: WithPWA("/site.webmanifest", "#fff")},
+		{name: "invalid status bar style", opt: WithPWA("/manifest.json", "#fff", PWAWithAppleMeta("blue"))},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := New(fsys, tc.opt); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
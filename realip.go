@@ -0,0 +1,201 @@
+package spaserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// WithTrustedProxies trusts the client address reported by proxies whose
+// IP address is within one of cidrs. For requests arriving from a trusted
+// proxy, the real client IP is taken from the Forwarded (RFC 7239),
+// X-Forwarded-For or X-Real-IP header, in that order of preference.
+// Multiple calls accumulate.
+//
+// Without trusted proxies r.RemoteAddr is always used, as the headers can
+// be set by any client. The client IP is used by the IP allowlist and
+// blocklist and is available to downstream code through RealIPFromContext.
+func WithTrustedProxies(cidrs ...string) Option {
+	return func(c *Config) {
+		c.TrustedProxies = append(c.TrustedProxies, cidrs...)
+	}
+}
+
+// realIPKey is the context key of the client IP address.
+type realIPKey struct{}
+
+// RealIPFromContext returns the client IP address resolved for the request
+// by the Handler, or "" if ctx does not carry one.
+func RealIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(realIPKey{}).(string)
+	return ip
+}
+
+// clientIP returns the IP address of the client of r, following the
+// forwarding headers of trusted proxies.
+func (h *Handler) clientIP(r *http.Request) (netip.Addr, bool) {
+	addr, ok := remoteAddr(r)
+	if !ok || !containsAddr(h.trustedProxies, addr) {
+		return addr, ok
+	}
+
+	// Hops are parsed like ParseForwarded, so both agree on every header
+	var hops []netip.Addr
+	switch {
+	case r.Header.Get("Forwarded") != "", r.Header.Get("X-Forwarded-For") != "":
+		for _, hop := range forwardedHops(r) {
+			hops = append(hops, parseForwardedNode(hop.For))
+		}
+	case r.Header.Get("X-Real-IP") != "":
+		if ip, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+			hops = []netip.Addr{ip.Unmap()}
+		}
+	}
+
+	// Walk the chain from the nearest hop; the first address that is not a
+	// trusted proxy is the client. Hops further away are client controlled.
+	for i := len(hops) - 1; i >= 0; i-- {
+		if !hops[i].IsValid() {
+			break
+		}
+		addr = hops[i]
+		if !containsAddr(h.trustedProxies, addr) {
+			break
+		}
+	}
+	return addr, true
+}
+
+// parseForwardedNode parses the address of a node reported by
+// ParseForwarded, such as 192.0.2.60, 192.0.2.60:4711 or
+// [2001:db8::1]:4711. Obfuscated and "unknown" nodes are returned as the
+// zero Addr.
+func parseForwardedNode(node string) netip.Addr {
+	if ap, err := netip.ParseAddrPort(node); err == nil {
+		return ap.Addr().Unmap()
+	}
+	ip, _ := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(node, "["), "]"))
+	return ip.Unmap()
+}
+
+// ForwardedInfo is the forwarding information a proxy reported about the
+// original request. Fields are empty when not reported.
+type ForwardedInfo struct {
+	// For is the client that made the original request, such as
+	// "192.0.2.60" or "[2001:db8::1]:4711".
+	For string
+	// By is the proxy interface that received the original request.
+	By string
+	// Host is the Host header of the original request.
+	Host string
+	// Proto is the scheme of the original request, such as "https".
+	Proto string
+}
+
+// ParseForwarded returns the forwarding information of the first hop
+// recorded in r's Forwarded header (RFC 7239) or, when r has none, its
+// legacy X-Forwarded-For, X-Forwarded-Host and X-Forwarded-Proto headers.
+//
+// The headers can be set by any client. Only rely on the result for
+// requests that arrived from a trusted proxy; the Handler itself resolves
+// the client IP this way only for WithTrustedProxies.
+func ParseForwarded(r *http.Request) ForwardedInfo {
+	return forwardedHops(r)[0]
+}
+
+// forwardedHops returns the forwarding information of every hop recorded
+// in r's Forwarded header, ordered from the client to the nearest proxy,
+// or, when r has none, of the hops of its X-Forwarded-For header, the
+// first of which carries X-Forwarded-Host and X-Forwarded-Proto. It
+// always returns at least one hop.
+func forwardedHops(r *http.Request) []ForwardedInfo {
+	if values := r.Header.Values("Forwarded"); len(values) > 0 {
+		var hops []ForwardedInfo
+		for _, element := range splitQuoted(strings.Join(values, ","), ',') {
+			var info ForwardedInfo
+			for _, pair := range splitQuoted(element, ';') {
+				k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok {
+					continue
+				}
+				v = unquote(strings.TrimSpace(v))
+				switch strings.ToLower(strings.TrimSpace(k)) {
+				case "for":
+					info.For = v
+				case "by":
+					info.By = v
+				case "host":
+					info.Host = v
+				case "proto":
+					info.Proto = strings.ToLower(v)
+				}
+			}
+			hops = append(hops, info)
+		}
+		return hops
+	}
+
+	hops := []ForwardedInfo{{}}
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops = hops[:0]
+		for _, node := range strings.Split(strings.Join(xff, ","), ",") {
+			hops = append(hops, ForwardedInfo{For: strings.TrimSpace(node)})
+		}
+	}
+	hops[0].Host = strings.TrimSpace(r.Header.Get("X-Forwarded-Host"))
+	hops[0].Proto = strings.ToLower(strings.TrimSpace(r.Header.Get("X-Forwarded-Proto")))
+	return hops
+}
+
+// splitQuoted splits s at each sep outside of a quoted string (RFC 9110,
+// section 5.6.4).
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted, start := false, 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quoted && s[i] == '\\':
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case !quoted && s[i] == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unquote returns the content of the quoted string v with its escapes
+// removed, or v itself if it is a token.
+func unquote(v string) string {
+	if len(v) < 2 || v[0] != '"' || v[len(v)-1] != '"' {
+		return v
+	}
+	var b strings.Builder
+	for i := 1; i < len(v)-1; i++ {
+		if v[i] == '\\' && i+1 < len(v)-1 {
+			i++
+		}
+		b.WriteByte(v[i])
+	}
+	return b.String()
+}
+
+// ForwardedFor returns the client IP address reported by ParseForwarded,
+// or nil if none was reported or the client is obfuscated or "unknown".
+func ForwardedFor(r *http.Request) net.IP {
+	addr := parseForwardedNode(ParseForwarded(r).For)
+	if !addr.IsValid() {
+		return nil
+	}
+	return net.IP(addr.AsSlice())
+}
+
+// ForwardedProto returns the lowercase scheme reported by ParseForwarded,
+// such as "https", or "" if none was reported.
+func ForwardedProto(r *http.Request) string {
+	return ParseForwarded(r).Proto
+}
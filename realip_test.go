@@ -0,0 +1,180 @@
+package spaserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandlerClientIP(t *testing.T) {
+	tt := []struct {
+		name       string
+		trusted    []string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{name: "no trusted proxies", remoteAddr: "10.0.0.1:1234", headers: map[string]string{"X-Forwarded-For": "203.0.113.7"}, want: "10.0.0.1"},
+		{name: "untrusted proxy", trusted: []string{"10.0.0.0/8"}, remoteAddr: "192.0.2.1:1234", headers: map[string]string{"X-Forwarded-For": "203.0.113.7"}, want: "192.0.2.1"},
+		{name: "x-forwarded-for", trusted: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:1234", headers: map[string]string{"X-Forwarded-For": "203.0.113.7"}, want: "203.0.113.7"},
+		{name: "x-forwarded-for chain", trusted: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:1234", headers: map[string]string{"X-Forwarded-For": "198.51.100.9, 203.0.113.7, 10.0.0.2"}, want: "203.0.113.7"},
+		{name: "x-forwarded-for garbage", trusted: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:1234", headers: map[string]string{"X-Forwarded-For": "not-an-ip"}, want: "10.0.0.1"},
+		{name: "x-real-ip", trusted: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:1234", headers: map[string]string{"X-Real-IP": "203.0.113.7"}, want: "203.0.113.7"},
+		{name: "forwarded", trusted: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:1234", headers: map[string]string{"Forwarded": `for=198.51.100.9, for="[2001:db8::1]:4711";proto=https`}, want: "2001:db8::1"},
+		{name: "forwarded preferred", trusted: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:1234", headers: map[string]string{"Forwarded": "for=192.0.2.60;proto=http", "X-Forwarded-For": "203.0.113.7"}, want: "192.0.2.60"},
+		{name: "forwarded unknown", trusted: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:1234", headers: map[string]string{"Forwarded": "for=unknown"}, want: "10.0.0.1"},
+		{name: "forwarded quoted separators", trusted: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:1234", headers: map[string]string{"Forwarded": `for=198.51.100.9;host="a,b;c", for=203.0.113.7`}, want: "203.0.113.7"},
+		{name: "ipv6 proxy", trusted: []string{"fd00::/8"}, remoteAddr: "[fd00::1]:1234", headers: map[string]string{"X-Forwarded-For": "203.0.113.7"}, want: "203.0.113.7"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Serve(headerTestFS, WithTrustedProxies(tc.trusted...))
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+			r.RemoteAddr = tc.remoteAddr
+			for k, v := range tc.headers {
+				r.Header.Set(k, v)
+			}
+			var got string
+			if addr, ok := h.clientIP(r); ok {
+				got = addr.String()
+			}
+			if got != tc.want {
+				t.Errorf("real IP expected: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRealIPFromContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), realIPKey{}, "203.0.113.7")
+	if got := RealIPFromContext(ctx); got != "203.0.113.7" {
+		t.Errorf("real IP expected: %q, got: %q", "203.0.113.7", got)
+	}
+	if got := RealIPFromContext(context.Background()); got != "" {
+		t.Errorf("real IP expected: %q, got: %q", "", got)
+	}
+}
+
+func TestServeWithTrustedProxiesIPAllowlist(t *testing.T) {
+	h := Serve(headerTestFS, WithTrustedProxies("10.0.0.0/8"), WithIPAllowlist("203.0.113.0/24"))
+
+	tt := []struct {
+		name         string
+		forwardedFor string
+		status       int
+	}{
+		{name: "allowed client", forwardedFor: "203.0.113.7", status: http.StatusOK},
+		{name: "denied client", forwardedFor: "198.51.100.9", status: http.StatusForbidden},
+		{name: "spoofed hop", forwardedFor: "203.0.113.7, 198.51.100.9", status: http.StatusForbidden},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+			r.RemoteAddr = "10.0.0.1:1234"
+			r.Header.Set("X-Forwarded-For", tc.forwardedFor)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("status expected: %d, got: %d", tc.status, w.Code)
+			}
+		})
+	}
+}
+
+func TestNewWithTrustedProxiesInvalid(t *testing.T) {
+	if _, err := New(headerTestFS, WithTrustedProxies("not-a-cidr")); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestParseForwarded(t *testing.T) {
+	tt := []struct {
+		name    string
+		headers map[string][]string
+		want    ForwardedInfo
+	}{
+		{name: "none"},
+		{
+			name:    "forwarded",
+			headers: map[string][]string{"Forwarded": {`for="[2001:db8::1]:4711";by=203.0.113.43;host=example.com;proto=HTTPS`}},
+			want:    ForwardedInfo{For: "[2001:db8::1]:4711", By: "203.0.113.43", Host: "example.com", Proto: "https"},
+		},
+		{
+			name:    "forwarded first hop",
+			headers: map[string][]string{"Forwarded": {"for=192.0.2.60;proto=https, for=198.51.100.17;proto=http"}},
+			want:    ForwardedInfo{For: "192.0.2.60", Proto: "https"},
+		},
+		{
+			name:    "forwarded multiple headers",
+			headers: map[string][]string{"Forwarded": {"For=192.0.2.60", "for=198.51.100.17"}},
+			want:    ForwardedInfo{For: "192.0.2.60"},
+		},
+		{
+			name:    "x-forwarded",
+			headers: map[string][]string{"X-Forwarded-For": {"203.0.113.7, 10.0.0.2"}, "X-Forwarded-Host": {"example.com"}, "X-Forwarded-Proto": {"https"}},
+			want:    ForwardedInfo{For: "203.0.113.7", Host: "example.com", Proto: "https"},
+		},
+		{
+			name:    "forwarded preferred",
+			headers: map[string][]string{"Forwarded": {"for=192.0.2.60;proto=http"}, "X-Forwarded-For": {"203.0.113.7"}, "X-Forwarded-Proto": {"https"}},
+			want:    ForwardedInfo{For: "192.0.2.60", Proto: "http"},
+		},
+		{
+			name:    "forwarded quoted separators",
+			headers: map[string][]string{"Forwarded": {`for=192.0.2.60;host="a,b;c\"d", for=198.51.100.17`}},
+			want:    ForwardedInfo{For: "192.0.2.60", Host: `a,b;c"d`},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+			for k, vs := range tc.headers {
+				r.Header[k] = vs
+			}
+			if got := ParseForwarded(r); got != tc.want {
+				t.Errorf("forwarded info expected: %+v, got: %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestForwardedFor(t *testing.T) {
+	tt := []struct {
+		name    string
+		headers map[string]string
+		want    net.IP
+	}{
+		{name: "none"},
+		{name: "forwarded ipv4", headers: map[string]string{"Forwarded": "for=192.0.2.60"}, want: net.ParseIP("192.0.2.60")},
+		{name: "forwarded ipv6 port", headers: map[string]string{"Forwarded": `for="[2001:db8::1]:4711"`}, want: net.ParseIP("2001:db8::1")},
+		{name: "forwarded obfuscated", headers: map[string]string{"Forwarded": "for=_hidden"}},
+		{name: "forwarded unknown", headers: map[string]string{"Forwarded": "for=unknown"}},
+		{name: "x-forwarded-for", headers: map[string]string{"X-Forwarded-For": "203.0.113.7, 10.0.0.2"}, want: net.ParseIP("203.0.113.7")},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+			for k, v := range tc.headers {
+				r.Header.Set(k, v)
+			}
+			if got := ForwardedFor(r); !got.Equal(tc.want) {
+				t.Errorf("forwarded for expected: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestForwardedProto(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+	r.Header.Set("X-Forwarded-Proto", "HTTPS")
+	if got := ForwardedProto(r); got != "https" {
+		t.Errorf("forwarded proto expected: %q, got: %q", "https", got)
+	}
+}
@@ -0,0 +1,103 @@
+package spaserver
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// responseRecorder records the status code and body size of a response for
+// logging and metrics. Unlike a plain wrapper, it keeps the optional
+// interfaces of the wrapped ResponseWriter: http.Flusher, http.Hijacker,
+// io.ReaderFrom and http.Pusher are delegated when the wrapped writer
+// implements them.
+type responseRecorder struct {
+	http.ResponseWriter
+	status  int
+	written int64
+}
+
+// newResponseRecorder wraps w.
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w}
+}
+
+// Status returns the status code sent, http.StatusOK if the handler wrote
+// a body without one, or 0 if nothing was written.
+func (rr *responseRecorder) Status() int {
+	return rr.status
+}
+
+// Written returns the number of body bytes written.
+func (rr *responseRecorder) Written() int64 {
+	return rr.written
+}
+
+func (rr *responseRecorder) WriteHeader(code int) {
+	// Informational responses are followed by the final one
+	if rr.status == 0 && (code >= 200 || code == http.StatusSwitchingProtocols) {
+		rr.status = code
+	}
+	rr.ResponseWriter.WriteHeader(code)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	n, err := rr.ResponseWriter.Write(b)
+	rr.written += int64(n)
+	return n, err
+}
+
+// ReadFrom copies from src with the wrapped writer's ReadFrom, such as the
+// sendfile path of net/http, if it has one.
+func (rr *responseRecorder) ReadFrom(src io.Reader) (int64, error) {
+	rf, ok := rr.ResponseWriter.(io.ReaderFrom)
+	if !ok {
+		// Hide ReadFrom from io.Copy, which would call it again
+		return io.Copy(struct{ io.Writer }{rr}, src)
+	}
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	n, err := rf.ReadFrom(src)
+	rr.written += n
+	return n, err
+}
+
+// Flush sends buffered data to the client, if the wrapped writer supports
+// it.
+func (rr *responseRecorder) Flush() {
+	if f, ok := rr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack takes over the connection from the wrapped writer.
+func (rr *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rr.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil && rr.status == 0 {
+		rr.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Push initiates an HTTP/2 server push through the wrapped writer.
+func (rr *responseRecorder) Push(target string, opts *http.PushOptions) error {
+	p, ok := rr.ResponseWriter.(http.Pusher)
+	if !ok {
+		return http.ErrNotSupported
+	}
+	return p.Push(target, opts)
+}
+
+// Unwrap returns the wrapped ResponseWriter for http.ResponseController.
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
@@ -0,0 +1,124 @@
+package spaserver
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fullWriter implements every optional ResponseWriter interface and
+// records their use.
+type fullWriter struct {
+	*httptest.ResponseRecorder
+	readFrom bool
+	hijacked bool
+	pushed   []string
+}
+
+func (fw *fullWriter) ReadFrom(src io.Reader) (int64, error) {
+	fw.readFrom = true
+	return io.Copy(fw.ResponseRecorder, src)
+}
+
+func (fw *fullWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	fw.hijacked = true
+	server, client := net.Pipe()
+	client.Close()
+	return server, bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server)), nil
+}
+
+func (fw *fullWriter) Push(target string, opts *http.PushOptions) error {
+	fw.pushed = append(fw.pushed, target)
+	return nil
+}
+
+// plainWriter implements only http.ResponseWriter.
+type plainWriter struct {
+	header http.Header
+	body   strings.Builder
+}
+
+func (pw *plainWriter) Header() http.Header         { return pw.header }
+func (pw *plainWriter) Write(b []byte) (int, error) { return pw.body.Write(b) }
+func (pw *plainWriter) WriteHeader(int)             {}
+
+func TestResponseRecorderDelegates(t *testing.T) {
+	fw := &fullWriter{ResponseRecorder: httptest.NewRecorder()}
+	rr := newResponseRecorder(fw)
+
+	rr.WriteHeader(http.StatusNotFound)
+	rr.Write([]byte("not "))
+	// Hide WriteTo, which io.Copy would prefer over ReadFrom
+	if _, err := io.Copy(rr, struct{ io.Reader }{strings.NewReader("found")}); err != nil {
+		t.Fatal(err)
+	}
+	rr.Flush()
+	if err := rr.Push("/main.js", nil); err != nil {
+		t.Errorf("Push expected no error, got: %v", err)
+	}
+	conn, _, err := http.NewResponseController(rr).Hijack()
+	if err != nil {
+		t.Fatalf("Hijack expected no error, got: %v", err)
+	}
+	conn.Close()
+
+	if rr.Status() != http.StatusNotFound {
+		t.Errorf("status expected: %d, got: %d", http.StatusNotFound, rr.Status())
+	}
+	if rr.Written() != int64(len("not found")) {
+		t.Errorf("written expected: %d, got: %d", len("not found"), rr.Written())
+	}
+	if got := fw.Body.String(); got != "not found" {
+		t.Errorf("body expected: %q, got: %q", "not found", got)
+	}
+	if !fw.readFrom {
+		t.Error("ReadFrom expected to be delegated")
+	}
+	if !fw.Flushed {
+		t.Error("Flush expected to be delegated")
+	}
+	if len(fw.pushed) != 1 || fw.pushed[0] != "/main.js" {
+		t.Errorf("pushed expected: [/main.js], got: %q", fw.pushed)
+	}
+	if !fw.hijacked {
+		t.Error("Hijack expected to be delegated")
+	}
+}
+
+func TestResponseRecorderWithoutOptionalInterfaces(t *testing.T) {
+	pw := &plainWriter{header: make(http.Header)}
+	rr := newResponseRecorder(pw)
+
+	if _, err := io.Copy(rr, strings.NewReader("body")); err != nil {
+		t.Fatal(err)
+	}
+	rr.Flush()
+
+	if rr.Status() != http.StatusOK {
+		t.Errorf("status expected: %d, got: %d", http.StatusOK, rr.Status())
+	}
+	if rr.Written() != 4 || pw.body.String() != "body" {
+		t.Errorf("body expected: %q, got: %q (%d bytes recorded)", "body", pw.body.String(), rr.Written())
+	}
+	if _, _, err := rr.Hijack(); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("Hijack expected: %v, got: %v", http.ErrNotSupported, err)
+	}
+	if err := rr.Push("/main.js", nil); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("Push expected: %v, got: %v", http.ErrNotSupported, err)
+	}
+}
+
+func TestResponseRecorderIgnoresInformationalStatus(t *testing.T) {
+	rr := newResponseRecorder(httptest.NewRecorder())
+	rr.WriteHeader(http.StatusEarlyHints)
+	rr.WriteHeader(http.StatusOK)
+
+	if rr.Status() != http.StatusOK {
+		t.Errorf("status expected: %d, got: %d", http.StatusOK, rr.Status())
+	}
+}
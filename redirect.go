@@ -0,0 +1,255 @@
+package spaserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// RedirectRule redirects requests whose clean URL path matches the
+// path.Match glob From to To, in which $1 to $N are replaced by the
+// path-escaped text matched by the glob's Nth wildcard: "*", "?" or a
+// character class.
+type RedirectRule struct {
+	From   string `json:"from" yaml:"from"`
+	To     string `json:"to" yaml:"to"`
+	Status int    `json:"status" yaml:"status"`
+}
+
+// WithPermanentRedirect redirects requests matching the glob from to to
+// with 301 Moved Permanently, e.g. WithPermanentRedirect("/blog/*",
+// "/articles/$1") for a migrated section. to may be a path or an absolute
+// URL. The query string is preserved unless to has its own. Rules are
+// evaluated in the order given, before the filesystem is accessed, and the
+// first match wins. Multiple calls accumulate.
+func WithPermanentRedirect(from, to string) Option {
+	return func(c *Config) {
+		c.Redirects = append(c.Redirects, RedirectRule{From: from, To: to, Status: http.StatusMovedPermanently})
+	}
+}
+
+// WithTemporaryRedirect is WithPermanentRedirect with 302 Found, for
+// redirects that caches and browsers must not remember.
+func WithTemporaryRedirect(from, to string) Option {
+	return func(c *Config) {
+		c.Redirects = append(c.Redirects, RedirectRule{From: from, To: to, Status: http.StatusFound})
+	}
+}
+
+// redirectRule is a RedirectRule with its glob compiled.
+type redirectRule struct {
+	RedirectRule
+	re *regexp.Regexp
+}
+
+// newRedirectRules compiles rules. Invalid rules are skipped;
+// Config.Validate reports them.
+func newRedirectRules(rules []RedirectRule) []redirectRule {
+	var compiled []redirectRule
+	for _, rule := range rules {
+		re, err := globRegexp(rule.From)
+		if err != nil {
+			continue
+		}
+		compiled = append(compiled, redirectRule{RedirectRule: rule, re: re})
+	}
+	return compiled
+}
+
+// globRegexp translates the path.Match glob into an anchored regular
+// expression with one capturing group per wildcard.
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	if _, err := path.Match(glob, ""); err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			b.WriteString("([^/]*)")
+		case '?':
+			b.WriteString("([^/])")
+		case '[':
+			j := i + 1
+			if j < len(glob) && glob[j] == '^' {
+				j++
+			}
+			for j < len(glob) && glob[j] != ']' {
+				if glob[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			class := glob[i+1 : j]
+			if negated, ok := strings.CutPrefix(class, "^"); ok {
+				class = "^/" + negated
+			}
+			b.WriteString("([" + class + "])")
+			i = j
+		case '\\':
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// expandCaptures replaces $1 to $N in to with captures[1] to captures[N],
+// path-escaped so that a capture cannot change the meaning of the target,
+// such as %5C decoding to a backslash that browsers read as a slash.
+// References without a capture expand to "".
+func expandCaptures(to string, captures []string) string {
+	var b strings.Builder
+	for i := 0; i < len(to); i++ {
+		j := i + 1
+		for j < len(to) && to[j] >= '0' && to[j] <= '9' {
+			j++
+		}
+		if to[i] != '$' || j == i+1 {
+			b.WriteByte(to[i])
+			continue
+		}
+		if n, _ := strconv.Atoi(to[i+1 : j]); n > 0 && n < len(captures) {
+			b.WriteString(url.PathEscape(captures[n]))
+		}
+		i = j - 1
+	}
+	return b.String()
+}
+
+// maxCapture returns the highest $N reference in to.
+func maxCapture(to string) int {
+	highest := 0
+	for _, ref := range captureRef.FindAllString(to, -1) {
+		if n, err := strconv.Atoi(ref[1:]); err == nil {
+			highest = max(highest, n)
+		}
+	}
+	return highest
+}
+
+var captureRef = regexp.MustCompile(`\$[0-9]+`)
+
+// matchRedirect returns the redirect target of the first rule matching upath.
+// A rule whose local target expands to a protocol-relative URL, such as
+// "//evil.com" from "/$1/$2" with an empty $1, is skipped rather than
+// sending the client off-site.
+func matchRedirect(rules []redirectRule, upath string) (to string, status int, ok bool) {
+	for _, rule := range rules {
+		m := rule.re.FindStringSubmatch(upath)
+		if m == nil {
+			continue
+		}
+		to := expandCaptures(rule.To, m)
+		if offSite(to) && !offSite(rule.To) {
+			continue
+		}
+		return to, rule.Status, true
+	}
+	return "", 0, false
+}
+
+// offSite reports whether the redirect target to is protocol-relative,
+// which browsers resolve against another host.
+func offSite(to string) bool {
+	return strings.HasPrefix(to, "//") || strings.HasPrefix(to, "/\\")
+}
+
+// serveRedirect redirects a request for the clean URL path upath if a rule
+// matches it, and reports whether it did.
+func serveRedirect(rules []redirectRule, w http.ResponseWriter, r *http.Request, upath string) bool {
+	to, status, ok := matchRedirect(rules, upath)
+	if !ok {
+		return false
+	}
+	if q := r.URL.RawQuery; q != "" && !strings.Contains(to, "?") {
+		to += "?" + q
+	}
+	w.Header().Set("Location", to)
+	w.WriteHeader(status)
+	return true
+}
+
+// validateRedirects reports invalid rules and redirect loops. A loop is
+// found by following each rule's target through the first rule matching
+// it, with every capture reference replaced by a sample path segment.
+func validateRedirects(rules []RedirectRule) []error {
+	var errs []error
+	for _, rule := range rules {
+		re, err := globRegexp(rule.From)
+		if err != nil || !strings.HasPrefix(rule.From, "/") {
+			errs = append(errs, fmt.Errorf("redirects: invalid pattern %q", rule.From))
+			continue
+		}
+		if rule.To == "" || strings.ContainsAny(rule.To, "\r\n") {
+			errs = append(errs, fmt.Errorf("redirects: %q: invalid target %q", rule.From, rule.To))
+		}
+		if n := maxCapture(rule.To); n > re.NumSubexp() {
+			errs = append(errs, fmt.Errorf("redirects: %q: target references $%d, but the pattern captures %d", rule.From, n, re.NumSubexp()))
+		}
+		if rule.Status != http.StatusMovedPermanently && rule.Status != http.StatusFound {
+			errs = append(errs, fmt.Errorf("redirects: %q: status must be 301 or 302", rule.From))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	compiled := newRedirectRules(rules)
+	// next[i] is the rule the target of rule i redirects through, or -1
+	next := make([]int, len(compiled))
+	for i, rule := range compiled {
+		next[i] = -1
+		target, _, _ := strings.Cut(rule.To, "?")
+		if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") {
+			continue
+		}
+		target = path.Clean(captureRef.ReplaceAllString(target, "_"))
+		for j, other := range compiled {
+			if other.re.MatchString(target) {
+				next[i] = j
+				break
+			}
+		}
+	}
+
+	// Each rule has at most one successor, so a walk that revisits a rule
+	// has found a cycle
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(compiled))
+	for start := range compiled {
+		var walk []int
+		i := start
+		for i >= 0 && state[i] == unvisited {
+			state[i] = visiting
+			walk = append(walk, i)
+			i = next[i]
+		}
+		if i >= 0 && state[i] == visiting {
+			var loop []string
+			for _, j := range walk[slices.Index(walk, i):] {
+				loop = append(loop, compiled[j].From)
+			}
+			loop = append(loop, compiled[i].From)
+			errs = append(errs, errors.New("redirects: loop: "+strings.Join(loop, " -> ")))
+		}
+		for i := start; i >= 0 && state[i] == visiting; i = next[i] {
+			state[i] = done
+		}
+	}
+	return errs
+}
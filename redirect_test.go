@@ -0,0 +1,130 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeWithRedirects(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":   {Data: []byte("index.html")},
+		"css/main.css": {Data: []byte("body {}")},
+	}
+	h, err := New(fsys,
+		WithPermanentRedirect("/blog/*/*", "/articles/$1/$2"),
+		WithPermanentRedirect("/blog/*", "/articles/$1"),
+		WithTemporaryRedirect("/promo", "/sale?ref=promo"),
+		WithPermanentRedirect("/docs/v?/*", "https://docs.example.com/v$1/$2"),
+		WithPermanentRedirect("/img/[a-c]*.png", "/images/$1$2.png"),
+		WithPermanentRedirect("/css/*", "/styles/$1"),
+		WithPermanentRedirect("/go/*", "/$1"),
+		WithPermanentRedirect("/to*/*", "/$1/$2"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		name     string
+		url      string
+		status   int
+		location string
+	}{
+		{name: "capture", url: "/blog/hello", status: http.StatusMovedPermanently, location: "/articles/hello"},
+		{name: "first match wins", url: "/blog/2024/hello", status: http.StatusMovedPermanently, location: "/articles/2024/hello"},
+		{name: "trailing slash", url: "/blog/hello/", status: http.StatusMovedPermanently, location: "/articles/hello"},
+		{name: "query preserved", url: "/blog/hello?utm=1", status: http.StatusMovedPermanently, location: "/articles/hello?utm=1"},
+		{name: "temporary", url: "/promo", status: http.StatusFound, location: "/sale?ref=promo"},
+		{name: "target query kept", url: "/promo?utm=1", status: http.StatusFound, location: "/sale?ref=promo"},
+		{name: "absolute target", url: "/docs/v2/intro", status: http.StatusMovedPermanently, location: "https://docs.example.com/v2/intro"},
+		{name: "character class", url: "/img/banner.png", status: http.StatusMovedPermanently, location: "/images/banner.png"},
+		{name: "class not matched", url: "/img/zebra.png", status: http.StatusOK},
+		{name: "before filesystem", url: "/css/main.css", status: http.StatusMovedPermanently, location: "/styles/main.css"},
+		{name: "star stops at slash", url: "/blog/a/b/c", status: http.StatusOK},
+		{name: "escaped capture", url: "/go/a%20b", status: http.StatusMovedPermanently, location: "/a%20b"},
+		{name: "backslash capture", url: "/go/%5Cevil.com", status: http.StatusMovedPermanently, location: "/%5Cevil.com"},
+		{name: "protocol relative expansion", url: "/to/evil.com", status: http.StatusOK},
+		{name: "no match", url: "/about", status: http.StatusOK},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+tc.url, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("status expected: %d, got: %d", tc.status, w.Code)
+			}
+			if got := w.Header().Get("Location"); got != tc.location {
+				t.Errorf("Location expected: %q, got: %q", tc.location, got)
+			}
+		})
+	}
+}
+
+func TestExpandCaptures(t *testing.T) {
+	captures := []string{"/a/b", "a", "b"}
+
+	tt := []struct {
+		to   string
+		want string
+	}{
+		{to: "/x/$1/$2", want: "/x/a/b"},
+		{to: "/x/$2$1", want: "/x/ba"},
+		{to: "/x/$1-v2", want: "/x/a-v2"},
+		{to: "/x/$3", want: "/x/"},
+		{to: "/x/$", want: "/x/$"},
+		{to: "/price$/$1", want: "/price$/a"},
+	}
+
+	for _, tc := range tt {
+		if got := expandCaptures(tc.to, captures); got != tc.want {
+			t.Errorf("expandCaptures(%q) expected: %q, got: %q", tc.to, tc.want, got)
+		}
+	}
+
+	if got, want := expandCaptures("/$1", []string{`/\evil.com`, `\evil.com`}), "/%5Cevil.com"; got != want {
+		t.Errorf("expandCaptures expected to escape captures: %q, got: %q", want, got)
+	}
+}
+
+func TestValidateRedirects(t *testing.T) {
+	tt := []struct {
+		name string
+		opts []Option
+		err  string
+	}{
+		{name: "chain", opts: []Option{WithPermanentRedirect("/a", "/b"), WithPermanentRedirect("/b", "/c")}},
+		{name: "external target", opts: []Option{WithPermanentRedirect("/*", "https://example.com/$1")}},
+		{name: "two rule loop", opts: []Option{WithPermanentRedirect("/a", "/b"), WithTemporaryRedirect("/b", "/a")}, err: "redirects: loop: /a -> /b -> /a"},
+		{name: "self loop", opts: []Option{WithPermanentRedirect("/old/*", "/old/$1")}, err: "redirects: loop: /old/* -> /old/*"},
+		{
+			name: "loop after chain",
+			opts: []Option{WithPermanentRedirect("/start", "/x/1"), WithPermanentRedirect("/x/*", "/y/$1"), WithPermanentRedirect("/y/*", "/x/$1")},
+			err:  "redirects: loop: /x/* -> /y/* -> /x/*",
+		},
+		{name: "relative pattern", opts: []Option{WithPermanentRedirect("blog/*", "/articles/$1")}, err: `redirects: invalid pattern "blog/*"`},
+		{name: "malformed pattern", opts: []Option{WithPermanentRedirect("/blog/[", "/articles")}, err: `redirects: invalid pattern "/blog/["`},
+		{name: "missing capture", opts: []Option{WithPermanentRedirect("/blog/*", "/articles/$2")}, err: "references $2, but the pattern captures 1"},
+		{name: "empty target", opts: []Option{WithPermanentRedirect("/blog", "")}, err: `redirects: "/blog": invalid target ""`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(fstest.MapFS{"index.html": {Data: []byte("index.html")}}, tc.opts...)
+			if tc.err == "" {
+				if err != nil {
+					t.Errorf("expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("error expected to contain %q, got: %v", tc.err, err)
+			}
+		})
+	}
+}
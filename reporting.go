@@ -0,0 +1,110 @@
+package spaserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ReportToGroup is an endpoint group of the Report-To header, to which
+// browsers deliver CSP violation, deprecation and network error reports.
+type ReportToGroup struct {
+	Group     string   `json:"group" yaml:"group"`
+	MaxAge    int      `json:"max_age" yaml:"max_age"`
+	Endpoints []string `json:"endpoints" yaml:"endpoints"`
+}
+
+// NELPolicy is the Network Error Logging policy sent in the NEL header.
+type NELPolicy struct {
+	ReportTo          string `json:"report_to" yaml:"report_to"`
+	MaxAge            int    `json:"max_age" yaml:"max_age"`
+	IncludeSubdomains bool   `json:"include_subdomains,omitempty" yaml:"include_subdomains,omitempty"`
+}
+
+// WithReportTo sends a Report-To header defining the given reporting
+// endpoint groups on every response.
+func WithReportTo(groups []ReportToGroup) Option {
+	return func(c *Config) {
+		c.ReportTo = groups
+	}
+}
+
+// WithNEL sends a Network Error Logging (NEL) header on every response,
+// asking browsers to report network failures to the Report-To group named
+// group for maxAge seconds. The group must be defined with WithReportTo.
+func WithNEL(group string, maxAge int, includeSubdomains bool) Option {
+	return func(c *Config) {
+		c.NEL = &NELPolicy{ReportTo: group, MaxAge: maxAge, IncludeSubdomains: includeSubdomains}
+	}
+}
+
+// reportToHeader returns the Report-To header value for groups: one
+// single-line JSON object per group, separated by commas.
+func reportToHeader(groups []ReportToGroup) (string, error) {
+	type endpoint struct {
+		URL string `json:"url"`
+	}
+	type group struct {
+		Group     string     `json:"group"`
+		MaxAge    int        `json:"max_age"`
+		Endpoints []endpoint `json:"endpoints"`
+	}
+
+	values := make([]string, 0, len(groups))
+	for _, g := range groups {
+		v := group{Group: g.Group, MaxAge: g.MaxAge, Endpoints: []endpoint{}}
+		for _, url := range g.Endpoints {
+			v.Endpoints = append(v.Endpoints, endpoint{URL: url})
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		values = append(values, string(b))
+	}
+
+	return strings.Join(values, ", "), nil
+}
+
+// nelHeader returns the NEL header value for policy.
+func nelHeader(policy NELPolicy) (string, error) {
+	b, err := json.Marshal(policy)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// validateReporting checks the Report-To groups and that the NEL policy
+// refers to one of them.
+func validateReporting(groups []ReportToGroup, nel *NELPolicy) []error {
+	var errs []error
+
+	names := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		if g.Group == "" {
+			errs = append(errs, fmt.Errorf("report_to: group name is required"))
+		}
+		if names[g.Group] {
+			errs = append(errs, fmt.Errorf("report_to: duplicate group %q", g.Group))
+		}
+		names[g.Group] = true
+		if g.MaxAge < 0 {
+			errs = append(errs, fmt.Errorf("report_to: group %q: max_age must not be negative", g.Group))
+		}
+		if len(g.Endpoints) == 0 {
+			errs = append(errs, fmt.Errorf("report_to: group %q has no endpoints", g.Group))
+		}
+	}
+
+	if nel != nil {
+		if !names[nel.ReportTo] {
+			errs = append(errs, fmt.Errorf("nel: report_to group %q is not defined in report_to", nel.ReportTo))
+		}
+		if nel.MaxAge < 0 {
+			errs = append(errs, fmt.Errorf("nel: max_age must not be negative"))
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,76 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeWithReportToAndNEL(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":   {Data: []byte("index.html")},
+		"css/main.css": {Data: []byte("body {}")},
+	}
+	h, err := New(fsys,
+		WithReportTo([]ReportToGroup{
+			{Group: "csp", MaxAge: 10886400, Endpoints: []string{"https://reports.example.com/csp"}},
+			{Group: "network-errors", MaxAge: 2592000, Endpoints: []string{"https://reports.example.com/nel", "https://backup.example.com/nel"}},
+		}),
+		WithNEL("network-errors", 2592000, true),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantReportTo := `{"group":"csp","max_age":10886400,"endpoints":[{"url":"https://reports.example.com/csp"}]}, ` +
+		`{"group":"network-errors","max_age":2592000,"endpoints":[{"url":"https://reports.example.com/nel"},{"url":"https://backup.example.com/nel"}]}`
+	wantNEL := `{"report_to":"network-errors","max_age":2592000,"include_subdomains":true}`
+
+	for _, url := range []string{"http://www.example.com/", "http://www.example.com/css/main.css"} {
+		r := httptest.NewRequest(http.MethodGet, url, nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if got := w.Header().Get("Report-To"); got != wantReportTo {
+			t.Errorf("%s Report-To expected: %s, got: %s", url, wantReportTo, got)
+		}
+		if got := w.Header().Get("NEL"); got != wantNEL {
+			t.Errorf("%s NEL expected: %s, got: %s", url, wantNEL, got)
+		}
+		if strings.ContainsAny(w.Header().Get("Report-To"), "\r\n") {
+			t.Errorf("Report-To must be a single line")
+		}
+	}
+}
+
+func TestValidateReporting(t *testing.T) {
+	fsys := fstest.MapFS{"index.html": {Data: []byte("index.html")}}
+
+	tt := []struct {
+		name string
+		opts []Option
+		err  string
+	}{
+		{
+			name: "NEL group not defined",
+			opts: []Option{WithNEL("network-errors", 60, false)},
+			err:  `nel: report_to group "network-errors" is not defined`,
+		},
+		{
+			name: "group without endpoints",
+			opts: []Option{WithReportTo([]ReportToGroup{{Group: "csp", MaxAge: 60}})},
+			err:  `group "csp" has no endpoints`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(fsys, tc.opts...)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("error expected to contain %q, got: %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package spaserver
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// robotsPath is the URL path of the robots exclusion file.
+const robotsPath = "/robots.txt"
+
+// WithRobotsTxt serves content for /robots.txt, taking priority over any
+// robots.txt file in the filesystem. This lets a staging deployment serve
+// "User-agent: *\nDisallow: /" without modifying the build output.
+func WithRobotsTxt(content string) Option {
+	return func(c *Config) {
+		c.RobotsTxt = content
+	}
+}
+
+// serveRobotsTxt serves the configured robots.txt content.
+func serveRobotsTxt(cfg Config, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	http.ServeContent(w, r, robotsPath, time.Time{}, strings.NewReader(cfg.RobotsTxt))
+}
@@ -0,0 +1,49 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeWithRobotsTxt(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("index.html")},
+		"robots.txt": {Data: []byte("User-agent: *\nAllow: /\n")},
+	}
+	staging := "User-agent: *\nDisallow: /\n"
+
+	tt := []struct {
+		name         string
+		opts         []Option
+		body         string
+		contentType  string
+		cacheControl string
+	}{
+		{name: "configured", opts: []Option{WithRobotsTxt(staging)}, body: staging, contentType: "text/plain; charset=utf-8", cacheControl: "public, max-age=86400"},
+		{name: "from filesystem", body: "User-agent: *\nAllow: /\n", contentType: "text/plain; charset=utf-8"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Serve(fsys, tc.opts...)
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com/robots.txt", nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status expected: %d, got: %d", http.StatusOK, w.Code)
+			}
+			if got := w.Body.String(); got != tc.body {
+				t.Errorf("body expected: %q, got: %q", tc.body, got)
+			}
+			if got := w.Header().Get("Content-Type"); got != tc.contentType {
+				t.Errorf("Content-Type expected: %q, got: %q", tc.contentType, got)
+			}
+			if got := w.Header().Get("Cache-Control"); got != tc.cacheControl {
+				t.Errorf("Cache-Control expected: %q, got: %q", tc.cacheControl, got)
+			}
+		})
+	}
+}
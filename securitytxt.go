@@ -0,0 +1,116 @@
+package spaserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// securityTxtPath is the URL path of security.txt (RFC 9116), and
+// securityTxtRootPath the legacy location at the root.
+const (
+	securityTxtPath     = "/.well-known/security.txt"
+	securityTxtRootPath = "/security.txt"
+)
+
+// SecurityTxtOptions are the fields of a security.txt file (RFC 9116).
+// Every value except Expires is a URI, e.g. "mailto:security@example.com"
+// or "https://example.com/security-policy".
+type SecurityTxtOptions struct {
+	// Contact lists where to report vulnerabilities, in order of
+	// preference. Required.
+	Contact []string `json:"contact" yaml:"contact"`
+	// Expires is when the file should be considered stale. Required, and
+	// must be in the future.
+	Expires         time.Time `json:"expires" yaml:"expires"`
+	Encryption      []string  `json:"encryption,omitempty" yaml:"encryption,omitempty"`
+	Acknowledgments string    `json:"acknowledgments,omitempty" yaml:"acknowledgments,omitempty"`
+	Canonical       []string  `json:"canonical,omitempty" yaml:"canonical,omitempty"`
+	Policy          string    `json:"policy,omitempty" yaml:"policy,omitempty"`
+	Hiring          string    `json:"hiring,omitempty" yaml:"hiring,omitempty"`
+	// ServeRoot also serves the file at the legacy /security.txt path.
+	ServeRoot bool `json:"serve_root,omitempty" yaml:"serve_root,omitempty"`
+}
+
+// WithSecurityTxt serves a security.txt file generated from opts at
+// /.well-known/security.txt, taking priority over any such file in the
+// filesystem, and also at /security.txt if opts.ServeRoot is set. New
+// reports an error if Contact is empty or Expires is not in the future.
+func WithSecurityTxt(opts SecurityTxtOptions) Option {
+	return func(c *Config) {
+		c.SecurityTxt = &opts
+	}
+}
+
+// isSecurityTxt reports whether the clean URL path upath is served the
+// generated security.txt.
+func isSecurityTxt(cfg Config, upath string) bool {
+	if cfg.SecurityTxt == nil {
+		return false
+	}
+	return upath == securityTxtPath || cfg.SecurityTxt.ServeRoot && upath == securityTxtRootPath
+}
+
+// securityTxt formats opts as a security.txt file.
+func securityTxt(opts SecurityTxtOptions) string {
+	var b strings.Builder
+	field := func(name string, values ...string) {
+		for _, v := range values {
+			if v != "" {
+				b.WriteString(name + ": " + v + "\n")
+			}
+		}
+	}
+	field("Contact", opts.Contact...)
+	field("Expires", opts.Expires.UTC().Format(time.RFC3339))
+	field("Encryption", opts.Encryption...)
+	field("Acknowledgments", opts.Acknowledgments)
+	field("Canonical", opts.Canonical...)
+	field("Policy", opts.Policy)
+	field("Hiring", opts.Hiring)
+	return b.String()
+}
+
+// serveSecurityTxt serves the generated security.txt.
+func serveSecurityTxt(cfg Config, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	http.ServeContent(w, r, "security.txt", time.Time{}, strings.NewReader(securityTxt(*cfg.SecurityTxt)))
+}
+
+// validateSecurityTxt reports missing required fields and values that are
+// not absolute URIs.
+func validateSecurityTxt(opts *SecurityTxtOptions) []error {
+	if opts == nil {
+		return nil
+	}
+	var errs []error
+	if len(opts.Contact) == 0 {
+		errs = append(errs, errors.New("security_txt: contact is required"))
+	}
+	if opts.Expires.IsZero() {
+		errs = append(errs, errors.New("security_txt: expires is required"))
+	} else if !opts.Expires.After(time.Now()) {
+		errs = append(errs, fmt.Errorf("security_txt: expires %s is not in the future", opts.Expires.UTC().Format(time.RFC3339)))
+	}
+
+	check := func(name string, values ...string) {
+		for _, v := range values {
+			if u, err := url.Parse(v); err != nil || u.Scheme == "" || strings.ContainsAny(v, " \t\r\n") {
+				errs = append(errs, fmt.Errorf("security_txt: %s: %q is not an absolute URI", name, v))
+			}
+		}
+	}
+	check("contact", opts.Contact...)
+	check("encryption", opts.Encryption...)
+	check("canonical", opts.Canonical...)
+	for name, v := range map[string]string{"acknowledgments": opts.Acknowledgments, "policy": opts.Policy, "hiring": opts.Hiring} {
+		if v != "" {
+			check(name, v)
+		}
+	}
+	return errs
+}
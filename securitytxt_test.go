@@ -0,0 +1,105 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestServeWithSecurityTxt(t *testing.T) {
+	expires := time.Now().Add(180 * 24 * time.Hour).UTC().Truncate(time.Second)
+	opts := SecurityTxtOptions{
+		Contact:         []string{"mailto:security@example.com", "https://example.com/report"},
+		Expires:         expires,
+		Encryption:      []string{"https://example.com/pgp-key.txt"},
+		Acknowledgments: "https://example.com/hall-of-fame",
+		Canonical:       []string{"https://example.com/.well-known/security.txt"},
+		Policy:          "https://example.com/security-policy",
+		Hiring:          "https://example.com/jobs",
+	}
+	want := "Contact: mailto:security@example.com\n" +
+		"Contact: https://example.com/report\n" +
+		"Expires: " + expires.Format(time.RFC3339) + "\n" +
+		"Encryption: https://example.com/pgp-key.txt\n" +
+		"Acknowledgments: https://example.com/hall-of-fame\n" +
+		"Canonical: https://example.com/.well-known/security.txt\n" +
+		"Policy: https://example.com/security-policy\n" +
+		"Hiring: https://example.com/jobs\n"
+
+	fsys := fstest.MapFS{
+		"index.html":               {Data: []byte("index.html")},
+		".well-known/security.txt": {Data: []byte("stale")},
+	}
+
+	tt := []struct {
+		name      string
+		serveRoot bool
+		url       string
+		body      string
+	}{
+		{name: "well-known", url: "/.well-known/security.txt", body: want},
+		{name: "root not served", url: "/security.txt", body: "index.html"},
+		{name: "root served", serveRoot: true, url: "/security.txt", body: want},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := opts
+			opts.ServeRoot = tc.serveRoot
+			h, err := New(fsys, WithSecurityTxt(opts))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+tc.url, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("status expected: 200, got: %d", w.Code)
+			}
+			if body := w.Body.String(); body != tc.body {
+				t.Errorf("body expected: %q, got: %q", tc.body, body)
+			}
+			if tc.body == want {
+				if got := w.Header().Get("Content-Type"); got != "text/plain; charset=utf-8" {
+					t.Errorf("Content-Type expected: text/plain; charset=utf-8, got: %q", got)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateSecurityTxt(t *testing.T) {
+	future := time.Now().Add(24 * time.Hour)
+
+	tt := []struct {
+		name string
+		opts SecurityTxtOptions
+		err  string
+	}{
+		{name: "minimal", opts: SecurityTxtOptions{Contact: []string{"mailto:security@example.com"}, Expires: future}},
+		{name: "no contact", opts: SecurityTxtOptions{Expires: future}, err: "security_txt: contact is required"},
+		{name: "no expires", opts: SecurityTxtOptions{Contact: []string{"tel:+1-201-555-0123"}}, err: "security_txt: expires is required"},
+		{name: "expired", opts: SecurityTxtOptions{Contact: []string{"tel:+1-201-555-0123"}, Expires: time.Now().Add(-time.Hour)}, err: "is not in the future"},
+		{name: "relative contact", opts: SecurityTxtOptions{Contact: []string{"security@example.com"}, Expires: future}, err: `security_txt: contact: "security@example.com" is not an absolute URI`},
+		{name: "line break", opts: SecurityTxtOptions{Contact: []string{"mailto:a@example.com"}, Policy: "https://example.com/\nHiring: x", Expires: future}, err: "security_txt: policy:"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(fstest.MapFS{"index.html": {Data: []byte("index.html")}}, WithSecurityTxt(tc.opts))
+			if tc.err == "" {
+				if err != nil {
+					t.Errorf("expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("error expected to contain %q, got: %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package spaserver
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"time"
+)
+
+// Default time allowed for in-flight requests to complete on shutdown.
+const defaultShutdownTimeout = 30 * time.Second
+
+// WithShutdownTimeout sets how long ListenAndServe waits for in-flight
+// requests to complete after its context is cancelled. Defaults to 30s.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.ShutdownTimeout = Duration(d)
+	}
+}
+
+// ListenAndServe serves the SPA in fsys on addr until ctx is cancelled, then
+// shuts the server down gracefully, waiting up to the shutdown timeout for
+// in-flight requests to complete. It returns ctx.Err() after a clean
+// shutdown, or the error that stopped the server otherwise. The handler is
+// created with New, so configuration errors are returned before listening.
+//
+// Combined with signal.NotifyContext it replaces the usual shutdown
+// boilerplate:
+//
+//	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+//	defer stop()
+//	err := spaserver.ListenAndServe(ctx, ":8080", os.DirFS("dist"))
+func ListenAndServe(ctx context.Context, addr string, fsys fs.FS, opts ...Option) error {
+	h, err := New(fsys, opts...)
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: h,
+		// Guard against clients that never finish sending headers
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Duration(h.cfg.ShutdownTimeout))
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,82 @@
+package spaserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+func TestListenAndServe(t *testing.T) {
+	addr := freeAddr(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- ListenAndServe(ctx, addr, os.DirFS("testdata"), WithShutdownTimeout(time.Second))
+	}()
+
+	var res *http.Response
+	var err error
+	for i := 0; i < 50; i++ {
+		res, err = http.Get("http://" + addr + "/")
+		if err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "index.html\n" {
+		t.Errorf("body expected: %q, got: %q", "index.html\n", body)
+	}
+
+	cancel()
+	select {
+	case err := <-errc:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ListenAndServe did not return after cancellation")
+	}
+}
+
+func TestListenAndServeListenError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	err = ListenAndServe(context.Background(), l.Addr().String(), os.DirFS("testdata"))
+	if err == nil || errors.Is(err, context.Canceled) {
+		t.Errorf("expected listen error, got: %v", err)
+	}
+}
+
+func TestListenAndServeInvalidFS(t *testing.T) {
+	err := ListenAndServe(context.Background(), "127.0.0.1:0", os.DirFS("testdata/css"))
+	if err == nil {
+		t.Error("expected error for filesystem without index.html")
+	}
+}
@@ -0,0 +1,124 @@
+package spaserver
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithServerTiming sends a Server-Timing header, shown by browser developer
+// tools, with the time spent on each stage of serving the response, e.g.
+//
+//	Server-Timing: fs;dur=1.204, cache;dur=0.081, prep;dur=0.312
+//
+// fs is the time spent opening, reading and stating files, cache the time
+// spent in the ETag, pre-encoded gzip and preloaded index caches, and prep
+// the time spent preparing the response body, such as injecting scripts
+// into the index page. Durations are in milliseconds, and stages that did
+// not run are omitted.
+func WithServerTiming() Option {
+	return func(c *Config) {
+		c.ServerTiming = true
+	}
+}
+
+// timingMetric is a stage of serving a response reported by Server-Timing.
+type timingMetric int
+
+const (
+	timingFS timingMetric = iota
+	timingCache
+	timingPrep
+	numTimingMetrics
+)
+
+// timingMetricNames are the Server-Timing names of the metrics.
+var timingMetricNames = [numTimingMetrics]string{"fs", "cache", "prep"}
+
+// serverTiming accumulates the durations of a single request's stages. A
+// nil *serverTiming discards them.
+type serverTiming struct {
+	dur      [numTimingMetrics]time.Duration
+	recorded [numTimingMetrics]bool
+}
+
+// serverTimingKey is the context key of a request's *serverTiming.
+type serverTimingKey struct{}
+
+// requestTiming returns the timings of r, or nil if they are not recorded.
+func requestTiming(r *http.Request) *serverTiming {
+	t, _ := r.Context().Value(serverTimingKey{}).(*serverTiming)
+	return t
+}
+
+// add records the time since start against metric.
+func (t *serverTiming) add(metric timingMetric, start time.Time) {
+	if t == nil {
+		return
+	}
+	t.dur[metric] += time.Since(start)
+	t.recorded[metric] = true
+}
+
+// header returns the Server-Timing header value, or "" if nothing was
+// recorded.
+func (t *serverTiming) header() string {
+	var metrics []string
+	for m, name := range timingMetricNames {
+		if !t.recorded[m] {
+			continue
+		}
+		ms := float64(t.dur[m]) / float64(time.Millisecond)
+		metrics = append(metrics, name+";dur="+strconv.FormatFloat(ms, 'f', 3, 64))
+	}
+	return strings.Join(metrics, ", ")
+}
+
+// withServerTiming returns r and w set up to record the request's timings
+// and send them when the response header is written.
+func withServerTiming(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, *http.Request) {
+	t := new(serverTiming)
+	r = r.WithContext(context.WithValue(r.Context(), serverTimingKey{}, t))
+	return &serverTimingWriter{ResponseWriter: w, timing: t}, r
+}
+
+// serverTimingWriter sets the Server-Timing header just before the header
+// is written, after the timed stages have run.
+type serverTimingWriter struct {
+	http.ResponseWriter
+	timing      *serverTiming
+	wroteHeader bool
+}
+
+func (tw *serverTimingWriter) WriteHeader(code int) {
+	if !tw.wroteHeader {
+		tw.wroteHeader = true
+		if v := tw.timing.header(); v != "" {
+			tw.Header().Set("Server-Timing", v)
+		}
+	}
+	tw.ResponseWriter.WriteHeader(code)
+}
+
+func (tw *serverTimingWriter) Write(b []byte) (int, error) {
+	if !tw.wroteHeader {
+		tw.WriteHeader(http.StatusOK)
+	}
+	return tw.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the wrapped ResponseWriter for http.ResponseController.
+func (tw *serverTimingWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+func (tw *serverTimingWriter) Flush() {
+	if !tw.wroteHeader {
+		tw.WriteHeader(http.StatusOK)
+	}
+	if f, ok := tw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
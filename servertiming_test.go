@@ -0,0 +1,44 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeWithServerTiming(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":  {Data: []byte("<html><head></head><body></body></html>")},
+		"css/app.css": {Data: []byte("body {}")},
+	}
+
+	tt := []struct {
+		name string
+		opts []Option
+		url  string
+		want string
+	}{
+		{name: "static file", opts: []Option{WithServerTiming()}, url: "/css/app.css", want: `^fs;dur=\d+\.\d{3}$`},
+		{name: "static file etag", opts: []Option{WithServerTiming(), WithContentHashETags()}, url: "/css/app.css", want: `^fs;dur=\d+\.\d{3}, cache;dur=\d+\.\d{3}$`},
+		{name: "index", opts: []Option{WithServerTiming()}, url: "/", want: `^fs;dur=\d+\.\d{3}, prep;dur=\d+\.\d{3}$`},
+		{name: "preloaded index", opts: []Option{WithServerTiming(), WithIndexPreload()}, url: "/", want: `^cache;dur=\d+\.\d{3}, prep;dur=\d+\.\d{3}$`},
+		{name: "nothing timed", opts: []Option{WithServerTiming()}, url: "/index.html", want: `^$`},
+		{name: "no option", url: "/css/app.css", want: `^$`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Serve(fsys, tc.opts...)
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+tc.url, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			got := w.Header().Get("Server-Timing")
+			if !regexp.MustCompile(tc.want).MatchString(got) {
+				t.Errorf("Server-Timing expected to match %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
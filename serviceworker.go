@@ -0,0 +1,38 @@
+package spaserver
+
+import (
+	"net/http"
+	"path"
+)
+
+// defaultServiceWorkerPattern matches the base names of service worker
+// scripts when no pattern is configured.
+const defaultServiceWorkerPattern = "*service-worker*.js"
+
+// WithServiceWorkerAllowed sends Service-Worker-Allowed: scope with service
+// worker scripts, allowing them to register with a scope beyond their own
+// directory, e.g. "/" for a worker served from /static/js/.
+func WithServiceWorkerAllowed(scope string) Option {
+	return func(c *Config) {
+		c.ServiceWorkerAllowed = scope
+	}
+}
+
+// WithServiceWorkerPattern sets the path.Match glob identifying service
+// worker scripts by base name. Defaults to "*service-worker*.js".
+func WithServiceWorkerPattern(glob string) Option {
+	return func(c *Config) {
+		c.ServiceWorkerPattern = glob
+	}
+}
+
+// setServiceWorkerHeaders sets Service-Worker-Allowed when name is a
+// service worker script.
+func setServiceWorkerHeaders(cfg Config, w http.ResponseWriter, name string) {
+	if cfg.ServiceWorkerAllowed == "" {
+		return
+	}
+	if ok, _ := path.Match(cfg.ServiceWorkerPattern, path.Base(name)); ok {
+		w.Header().Set("Service-Worker-Allowed", cfg.ServiceWorkerAllowed)
+	}
+}
@@ -0,0 +1,52 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeWithServiceWorkerAllowed(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":                      {Data: []byte("index.html")},
+		"service-worker.js":               {Data: []byte("self.addEventListener('fetch', () => {})")},
+		"static/js/main.js":               {Data: []byte("main")},
+		"static/js/service-worker.abc.js": {Data: []byte("sw")},
+		"static/js/sw.js":                 {Data: []byte("sw")},
+	}
+
+	tt := []struct {
+		name string
+		opts []Option
+		url  string
+		want string
+	}{
+		{name: "service worker", opts: []Option{WithServiceWorkerAllowed("/")}, url: "/service-worker.js", want: "/"},
+		{name: "nested hashed service worker", opts: []Option{WithServiceWorkerAllowed("/")}, url: "/static/js/service-worker.abc.js", want: "/"},
+		{name: "other script", opts: []Option{WithServiceWorkerAllowed("/")}, url: "/static/js/main.js"},
+		{name: "index", opts: []Option{WithServiceWorkerAllowed("/")}, url: "/"},
+		{name: "custom pattern", opts: []Option{WithServiceWorkerAllowed("/app/"), WithServiceWorkerPattern("sw.js")}, url: "/static/js/sw.js", want: "/app/"},
+		{name: "custom pattern replaces default", opts: []Option{WithServiceWorkerAllowed("/app/"), WithServiceWorkerPattern("sw.js")}, url: "/service-worker.js"},
+		{name: "not configured", url: "/service-worker.js"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Serve(fsys, tc.opts...)
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+tc.url, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Service-Worker-Allowed"); got != tc.want {
+				t.Errorf("Service-Worker-Allowed expected: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNewWithServiceWorkerPatternInvalid(t *testing.T) {
+	if _, err := New(headerTestFS, WithServiceWorkerAllowed("/"), WithServiceWorkerPattern("[")); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
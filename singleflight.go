@@ -0,0 +1,81 @@
+package spaserver
+
+import (
+	"io"
+	"io/fs"
+)
+
+// WithSingleFlight coalesces concurrent reads of the same file: while a
+// file is being read, other requests for it wait for that read and share
+// its result instead of opening the file again. This protects slow or
+// remote filesystems from bursts of identical reads, such as a traffic
+// spike on index.html after a cold start. Static files are then read
+// whole into memory before being served.
+func WithSingleFlight() Option {
+	return func(c *Config) {
+		c.SingleFlight = true
+	}
+}
+
+// sharedFile is the result of a file read shared by concurrent requests.
+type sharedFile struct {
+	info fs.FileInfo
+	data []byte // nil for a directory
+}
+
+// readShared opens and reads name, sharing the result with concurrent
+// callers for the same name. It must only be used when s.reads is set.
+func (s *site) readShared(name string) (*sharedFile, error) {
+	ch := s.reads.DoChan(name, func() (any, error) {
+		file, err := s.fsys.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		info, err := file.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			return &sharedFile{info: info}, nil
+		}
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return nil, err
+		}
+		return &sharedFile{info: info, data: data}, nil
+	})
+	if testHookReadJoined != nil {
+		testHookReadJoined(name)
+	}
+	res := <-ch
+	if res.Err != nil {
+		return nil, res.Err
+	}
+	return res.Val.(*sharedFile), nil
+}
+
+// testHookReadJoined, if set, is called once a caller of readShared has
+// joined the read of name, so tests can tell when every request shares a
+// read in flight.
+var testHookReadJoined func(name string)
+
+// readFile returns the contents of name, coalescing concurrent reads when
+// single flight is enabled. Files outside the site's root are refused.
+func (s *site) readFile(name string) ([]byte, error) {
+	if err := s.checkContained("open", name); err != nil {
+		return nil, err
+	}
+	if s.reads == nil {
+		return fs.ReadFile(s.fsys, name)
+	}
+	f, err := s.readShared(name)
+	if err != nil {
+		return nil, err
+	}
+	if f.info.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+	return f.data, nil
+}
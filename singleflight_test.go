@@ -0,0 +1,93 @@
+package spaserver
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+)
+
+// holdFS counts opens of the named file and, once armed, holds them until
+// release is closed.
+type holdFS struct {
+	fs.FS
+	name    string
+	opens   atomic.Int64
+	armed   atomic.Bool
+	release chan struct{}
+}
+
+func (h *holdFS) Open(name string) (fs.File, error) {
+	if name == h.name {
+		h.opens.Add(1)
+		if h.armed.Load() {
+			<-h.release
+		}
+	}
+	return h.FS.Open(name)
+}
+
+func TestServeWithSingleFlight(t *testing.T) {
+	tt := []struct {
+		name string
+		file string
+		url  string
+		body string
+	}{
+		{name: "index", file: "index.html", url: "http://www.example.com/app/route", body: "index.html"},
+		{name: "static", file: "js/app.js", url: "http://www.example.com/js/app.js", body: "console.log('app')"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			fsys := &holdFS{
+				FS: fstest.MapFS{
+					"index.html": {Data: []byte("index.html")},
+					"js/app.js":  {Data: []byte("console.log('app')")},
+				},
+				name:    tc.file,
+				release: make(chan struct{}),
+			}
+			h := Serve(fsys, WithSingleFlight())
+			fsys.opens.Store(0)
+			fsys.armed.Store(true)
+
+			const n = 500
+			var joined, done sync.WaitGroup
+			joined.Add(n)
+			done.Add(n)
+			testHookReadJoined = func(name string) {
+				if name == tc.file {
+					joined.Done()
+				}
+			}
+			defer func() { testHookReadJoined = nil }()
+			bodies := make([]string, n)
+			for i := range n {
+				go func() {
+					defer done.Done()
+					r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+					w := httptest.NewRecorder()
+					h.ServeHTTP(w, r)
+					bodies[i] = w.Body.String()
+				}()
+			}
+			// Release the read once every request shares it
+			joined.Wait()
+			close(fsys.release)
+			done.Wait()
+
+			if got := fsys.opens.Load(); got != 1 {
+				t.Errorf("%s opens expected: 1, got: %d", tc.file, got)
+			}
+			for i, body := range bodies {
+				if body != tc.body {
+					t.Fatalf("request %d body expected: %q, got: %q", i, tc.body, body)
+				}
+			}
+		})
+	}
+}
@@ -2,15 +2,26 @@ package spaserver
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
+	"log/slog"
+	"mime"
 	"net/http"
+	"net/netip"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/sync/singleflight"
 )
 
 const indexPage = "index.html"
@@ -36,32 +47,36 @@ var etagHeaders = []string{
 }
 
 var securityHeaders = map[string]string{
-	"X-Content-Type-Options": "nosniff",
-	"X-Frame-Options":        "DENY",
+	"X-Frame-Options": "DENY",
 }
 
 const defaultCSP = "default-src 'self'"
 
-type config struct {
-	csp string
-}
-
-// Option configures the behavior of Serve.
-type Option func(*config)
+// Option configures the behavior of Serve by modifying its Config.
+type Option func(*Config)
 
 // WithCSP overrides the Content-Security-Policy header sent with index.html
 // responses. Pass an empty string to omit the header entirely.
 func WithCSP(policy string) Option {
-	return func(c *config) {
-		c.csp = policy
+	return func(c *Config) {
+		c.CSP = &policy
+	}
+}
+
+// WithLogger sets the logger used to report non-fatal problems such as
+// missing files referenced by configuration. Defaults to slog.Default().
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Config) {
+		c.Logger = logger
 	}
 }
 
 // Serve a single-page application from the filesystem.
 //
 // SECURITY NOTES:
-//   - When using os.DirFS: Symlinks are followed and may escape the root directory.
-//     For untrusted filesystems, consider using Go 1.24+ os.Root instead.
+//   - When using os.DirFS: Symlinks are followed and may escape the root directory,
+//     unless WithContainSymlinks is set. For untrusted filesystems, consider
+//     using Go 1.24+ os.Root instead.
 //   - When using embed.FS: Symlinks are not supported (build-time only).
 //   - All paths are cleaned using path.Clean to prevent basic traversal attacks.
 //   - Path validation using filepath.IsLocal prevents directory traversal attempts.
@@ -71,92 +86,676 @@ func WithCSP(policy string) Option {
 // - Requests for / or non-existent files serve index.html
 // - index.html responses include no-cache and security headers
 // - Other files are cached normally
-func Serve(fsys fs.FS, opts ...Option) http.Handler {
-	cfg := config{csp: defaultCSP}
+func Serve(fsys fs.FS, opts ...Option) *Handler {
+	var cfg Config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return ServeWithConfig(fsys, cfg)
+}
+
+// New is like Serve but verifies the configuration and filesystem up front.
+// It returns an error if the options are invalid, if index.html is missing
+// from the root of fsys or unreadable, or if any state derived from fsys
+// (such as a preloaded index or webpack manifest) cannot be loaded.
+func New(fsys fs.FS, opts ...Option) (*Handler, error) {
+	var cfg Config
 	for _, opt := range opts {
 		opt(&cfg)
 	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("spaserver: invalid configuration: %w", err)
+	}
+
+	h := newHandler(cfg)
+	s, err := h.load(fsys)
+	if err != nil {
+		s.close()
+		return nil, fmt.Errorf("spaserver: %w", err)
+	}
+	h.current.Store(s)
+
+	if h.ab != nil {
+		if err := h.ab.load(h); err != nil {
+			s.close()
+			return nil, fmt.Errorf("spaserver: %w", err)
+		}
+	}
+
+	return h, nil
+}
+
+// Validate reports the error New would return for fsys and opts, without
+// keeping the handler. It is intended for deploy pipelines that check a
+// build before it goes live.
+func Validate(fsys fs.FS, opts ...Option) error {
+	h, err := New(fsys, opts...)
+	if h != nil {
+		h.current.Load().close()
+	}
+	return err
+}
+
+// ServeWithConfig is like Serve but takes its configuration as a Config
+// value instead of functional options. cfg is not validated; call
+// Config.Validate first when it comes from an untrusted source.
+func ServeWithConfig(fsys fs.FS, cfg Config) *Handler {
+	h := newHandler(cfg)
+
+	s, err := h.load(fsys)
+	if err != nil {
+		h.cfg.Logger.Warn("spaserver: filesystem state not loaded", "error", err)
+	}
+	h.current.Store(s)
+
+	if h.ab != nil {
+		if err := h.ab.load(h); err != nil {
+			h.cfg.Logger.Warn("spaserver: a/b variant state not loaded", "error", err)
+		}
+	}
+
+	return h
+}
+
+// Handler serves a single-page application. It is created by Serve or
+// ServeWithConfig.
+type Handler struct {
+	cfg       Config
+	headers   http.Header // sent with every response
+	ipAllow   []netip.Prefix
+	ipBlock   []netip.Prefix
+	ipDenyAll bool
+	// trustedProxies may report the client address in forwarding headers
+	trustedProxies []netip.Prefix
+	dev            *devReloader // set by DevMode
+	ab             *abTest      // nil unless WithABVariant is used
+	tenants        *tenantSites // nil unless WithTenantRouter is used
+	metrics        *metrics     // nil unless WithMetricsEndpoint is used
+	proxies        []proxyRoute
+	redirects      []redirectRule
+	aliases        map[string]string
+	limit          *connLimiter // nil unless WithMaxConnections is used
+	current        atomic.Pointer[site]
+}
+
+// newHandler returns a Handler for cfg with no site loaded. Header values
+// derived from cfg that cannot be built are logged and omitted; New reports
+// them earlier through Config.Validate.
+func newHandler(cfg Config) *Handler {
+	h := &Handler{cfg: cfg.withDefaults(), headers: http.Header{}}
+
+	if len(h.cfg.ReportTo) > 0 {
+		if v, err := reportToHeader(h.cfg.ReportTo); err == nil {
+			h.headers.Set("Report-To", v)
+		} else {
+			h.cfg.Logger.Warn("spaserver: Report-To header not set", "error", err)
+		}
+	}
+	if h.cfg.NEL != nil {
+		if v, err := nelHeader(*h.cfg.NEL); err == nil {
+			h.headers.Set("NEL", v)
+		} else {
+			h.cfg.Logger.Warn("spaserver: NEL header not set", "error", err)
+		}
+	}
+
+	allow, aerr := parsePrefixes(h.cfg.IPAllowlist)
+	block, berr := parsePrefixes(h.cfg.IPBlocklist)
+	if err := errors.Join(aerr, berr); err != nil {
+		// Fail closed rather than admit clients the lists were meant to exclude
+		h.cfg.Logger.Error("spaserver: IP filter not parsed; denying all clients", "error", err)
+		h.ipDenyAll = true
+	}
+	h.ipAllow, h.ipBlock = allow, block
+
+	proxies, err := parsePrefixes(h.cfg.TrustedProxies)
+	if err != nil {
+		// Trust no proxy rather than accept spoofable headers
+		h.cfg.Logger.Error("spaserver: trusted proxies not parsed; using the remote address", "error", err)
+	}
+	h.trustedProxies = proxies
+
+	if l := h.cfg.ZstdLevel; l < zstd.SpeedFastest || l > zstd.SpeedBestCompression {
+		h.cfg.Logger.Warn("spaserver: invalid zstd level; using the default", "level", int(l))
+		h.cfg.ZstdLevel = zstd.SpeedDefault
+	}
+
+	if h.cfg.devReload {
+		h.dev = newDevReloader()
+	}
+	h.ab = newABTest(h.cfg)
+	h.tenants = newTenantSites(h.cfg)
+	h.proxies = h.newProxyRoutes()
+	h.redirects = newRedirectRules(h.cfg.Redirects)
+	h.aliases = newAliases(h.cfg.Aliases)
+	h.limit = newConnLimiter(h.cfg)
+	if h.cfg.MetricsPath != "" {
+		h.metrics = newMetrics()
+	}
+
+	return h
+}
+
+// site is the filesystem being served together with the state derived from
+// it. It is swapped as a unit by Reload, so a request always sees a
+// consistent view.
+type site struct {
+	fsys     fs.FS
+	manifest webpackManifest
+	index    []byte // preloaded index.html, nil unless IndexPreload is set
+	csp      string // effective Content-Security-Policy for HTML pages
+	etags    hashCache
+	digests  hashCache // Content-Digest sums of static files
+	// gunzipped holds the decompressed content of gzip files served
+	// without a compressed extension
+	gunzipped gunzipCache
+	// envScript defines window.__ENV__, read from the environment at load
+	envScript string
+	importMap string // import map script element
+	// speculationRules is the speculation rules script element
+	speculationRules string
+	// reads coalesces concurrent file reads, nil unless SingleFlight is set
+	reads *singleflight.Group
+	// gzipped holds pre-encoded files, nil unless PreencodeGzip is set
+	gzipped *gzipCache
+	// root is the resolved directory of an os.DirFS filesystem, empty
+	// unless ContainSymlinks is set
+	root string
+	// loadErr is the error of loading the site, reported by the startup
+	// probe
+	loadErr error
+}
+
+// close stops the background work of a site that is no longer served.
+func (s *site) close() {
+	if s.gzipped != nil {
+		s.gzipped.stop()
+	}
+}
+
+// readIndex returns the contents of the index file name, using the
+// preloaded copy of index.html when available.
+func (s *site) readIndex(name string) ([]byte, error) {
+	if name == indexPage && s.index != nil {
+		return s.index, nil
+	}
+	return s.readFile(name)
+}
+
+// load reads the state derived from fsys. The returned site is always
+// usable; a non-nil error describes state that could not be loaded.
+func (h *Handler) load(fsys fs.FS) (*site, error) {
+	s := &site{fsys: fsys}
+	s.etags.ttl, s.etags.now = time.Duration(h.cfg.FileCacheTTL), time.Now
+	s.digests.ttl, s.digests.now = time.Duration(h.cfg.FileCacheTTL), time.Now
+	s.gunzipped.ttl, s.gunzipped.now = time.Duration(h.cfg.FileCacheTTL), time.Now
+	if h.cfg.SingleFlight {
+		s.reads = new(singleflight.Group)
+	}
+	var errs []error
+
+	if err := checkIndex(fsys); err != nil {
+		errs = append(errs, err)
+	}
+
+	if h.cfg.WebpackManifest != "" {
+		m, err := loadWebpackManifest(fsys, h.cfg.WebpackManifest)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("webpack manifest %s not loaded: %w", h.cfg.WebpackManifest, err))
+		}
+		s.manifest = m
+	}
+
+	if h.cfg.IndexPreload {
+		b, err := fs.ReadFile(fsys, indexPage)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("preload %s: %w", indexPage, err))
+		}
+		s.index = b
+	}
+
+	script, err := envScript(h.cfg)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("environment variables: %w", err))
+	}
+	s.envScript = script
+
+	if h.cfg.ImportMap != nil {
+		s.importMap = importMapScript(h.cfg.ImportMap)
+	}
+
+	if h.cfg.SpeculationRules != nil {
+		s.speculationRules, err = speculationRulesScript(*h.cfg.SpeculationRules)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	s.csp = *h.cfg.CSP
+	if len(h.cfg.AutoCSPHashes) > 0 && s.csp != "" {
+		b, err := s.readIndex(indexPage)
+		if err == nil {
+			s.csp, err = autoHashCSP(s.csp, s.injectIndex(h.cfg, b), h.cfg.AutoCSPHashes)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("CSP hashes: %w", err))
+		}
+	}
+	if h.cfg.TrustedTypes != nil {
+		s.csp = appendTrustedTypes(s.csp, h.cfg.TrustedTypes)
+	}
 
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Normalize and clean the path
-		upath := r.URL.Path
-		if !strings.HasPrefix(upath, "/") {
-			upath = "/" + upath
-			r.URL.Path = upath
+	if h.dev != nil {
+		s.csp = appendCSPSources(s.csp, "script-src", []string{devReloadCSPSource()})
+	}
+
+	if h.cfg.PWA != nil {
+		if err := checkPWAManifest(fsys, h.cfg.PWA); err != nil {
+			errs = append(errs, err)
 		}
-		upath = path.Clean(upath)
+	}
+
+	if h.cfg.PerformanceBudget != nil {
+		if err := checkPerformanceBudget(fsys, h.cfg); err != nil {
+			if !h.cfg.PerformanceBudget.SoftLimit {
+				errs = append(errs, err)
+			} else {
+				h.cfg.Logger.Warn("spaserver: performance budget exceeded", "error", err)
+			}
+		}
+	}
+
+	if h.cfg.ContainSymlinks {
+		if dir, ok := dirFSRoot(fsys); ok {
+			root, err := resolveRoot(dir)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("resolve root directory: %w", err))
+			}
+			s.root = root
+		}
+	}
+
+	if h.cfg.PreencodeGzip {
+		s.preencode(h.cfg)
+	}
+
+	s.loadErr = errors.Join(errs...)
+	return s, s.loadErr
+}
+
+// Reload atomically replaces the filesystem being served, for example from a
+// deploy hook or SIGHUP handler. Requests already in flight complete against
+// the filesystem they started with. If state derived from newFSys cannot be
+// loaded, an error is returned and the current filesystem stays active.
+func (h *Handler) Reload(newFSys fs.FS) error {
+	if newFSys == nil {
+		return errors.New("spaserver: reload: nil filesystem")
+	}
+
+	s, err := h.load(newFSys)
+	if err != nil {
+		s.close()
+		return fmt.Errorf("spaserver: reload: %w", err)
+	}
+	h.current.Swap(s).close()
+
+	return nil
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cfg := h.cfg
+	s := h.current.Load()
+	fsys := s.fsys
 
-		// redirect .../index.html to .../
-		// can't use Redirect() because that would make the path absolute,
-		// which would be a problem running under StripPrefix
-		if strings.HasSuffix(r.URL.Path, "/"+indexPage) {
-			localRedirect(w, r, "./")
+	if cfg.DeploymentVersion != "" {
+		w.Header().Set("X-Deployment-Version", cfg.DeploymentVersion)
+	}
+	// Prevent MIME sniffing on every response, including metrics, errors
+	// and passthroughs: a sniffed image or stylesheet can otherwise be
+	// executed as script
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if h.metrics != nil {
+		if r.URL.Path == cfg.MetricsPath {
+			h.serveMetrics(w, r)
 			return
 		}
+		rec, start := h.metrics.start(w), time.Now()
+		defer func() { h.metrics.done(rec, time.Since(start)) }()
+		w = rec
+	}
+
+	// Refuse oversized paths before any work proportional to their length
+	if len(r.URL.Path) > cfg.MaxPathLength {
+		serveError(w, "400 Bad Request", http.StatusBadRequest)
+		return
+	}
 
-		// Serve index page on root path
-		if upath == "/" {
-			serveIndex(fsys, cfg, w, r)
+	if h.limit != nil {
+		if !h.limit.acquire() {
+			serveOverloaded(w)
 			return
 		}
+		defer h.limit.release()
+	}
+
+	ip, ipOK := h.clientIP(r)
+	if ipOK {
+		r = r.WithContext(context.WithValue(r.Context(), realIPKey{}, ip.String()))
+	}
+	r = enrichContext(cfg, r)
+
+	if cfg.WebSocketHandler != nil && isWebSocketUpgrade(r) && (h.dev == nil || r.URL.Path != devReloadPath) {
+		if h.checkAccess(w, r, ip, ipOK) {
+			cfg.WebSocketHandler.ServeHTTP(w, r)
+		}
+		return
+	}
+	if sse := sseHandler(cfg.SSERoutes, r); sse != nil {
+		if h.checkAccess(w, r, ip, ipOK) {
+			sse.ServeHTTP(w, r)
+		}
+		return
+	}
+	if cfg.GRPCWebHandler != nil && isGRPCWeb(r) {
+		if h.checkAccess(w, r, ip, ipOK) {
+			cfg.GRPCWebHandler.ServeHTTP(w, r)
+		}
+		return
+	}
+	if proxy := proxyHandler(h.proxies, r); proxy != nil {
+		if h.checkAccess(w, r, ip, ipOK) {
+			proxy.ServeHTTP(w, r)
+		}
+		return
+	}
+
+	if cfg.HideServerHeaders {
+		w = &hideHeadersWriter{ResponseWriter: w}
+	}
+	if cfg.ServerTiming {
+		w, r = withServerTiming(w, r)
+	}
+	timing := requestTiming(r)
+	if cfg.Gzip || cfg.Zstd {
+		cw := newCompressWriter(cfg, w, r)
+		defer cw.Close()
+		w = cw
+	}
+
+	for k, v := range h.headers {
+		w.Header()[k] = v
+	}
+
+	// Normalize and clean the path
+	upath := r.URL.Path
+	if !strings.HasPrefix(upath, "/") {
+		upath = "/" + upath
+		r.URL.Path = upath
+	}
+
+	cleanPath := path.Clean(upath)
+	setRuleHeaders(cfg.Headers, w, cleanPath)
+	setClearSiteData(cfg, w, cleanPath)
+
+	// Answer health probes before any SPA handling
+	if h.isProbe(r.URL.Path) {
+		h.serveProbe(w, r, r.URL.Path)
+		return
+	}
+
+	if acme := acmeHandler(cfg, r); acme != nil {
+		acme.ServeHTTP(w, r)
+		return
+	}
 
-		name := strings.TrimPrefix(upath, "/")
+	if !h.allowIP(ip, ipOK) {
+		serveError(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Browsers send reports without credentials
+	if cfg.CSPReportHandler != nil && r.URL.Path == cfg.CSPReportPath {
+		serveCSPReport(cfg, w, r)
+		return
+	}
+
+	if cfg.basicAuthEnabled() && !h.authorize(r) {
+		serveUnauthorized(cfg, w)
+		return
+	}
+
+	if h.dev != nil && r.URL.Path == devReloadPath {
+		h.dev.ServeHTTP(w, r)
+		return
+	}
 
-		// Validate the path is safe (prevents directory traversal)
-		if !filepath.IsLocal(name) {
-			serveError(w, "400 Bad Request", http.StatusBadRequest)
+	if handler, ok := cfg.MethodHandlers[r.Method]; ok {
+		handler.ServeHTTP(w, r)
+		return
+	}
+
+	if r.Method == http.MethodOptions {
+		serveOptions(cfg, w)
+		return
+	}
+
+	var tenant *site
+	if h.tenants != nil {
+		var err error
+		if tenant, err = h.tenants.site(h, r); err != nil {
+			serveTenantError(cfg, w, err)
 			return
 		}
+	}
+	if tenant != nil {
+		s = tenant
+		fsys = s.fsys
+	} else if h.ab != nil {
+		s = h.ab.site(w, r, s)
+		fsys = s.fsys
+	}
 
-		file, err := fsys.Open(name)
-		if err != nil {
-			if errors.Is(err, fs.ErrNotExist) {
-				serveIndex(fsys, cfg, w, r)
-				return
-			}
-			if errors.Is(err, fs.ErrPermission) {
-				serveError(w, "403 Forbidden", http.StatusForbidden)
+	upath = path.Clean(upath)
+	upath = resolveAlias(h.aliases, upath)
+
+	if cfg.BlockHiddenFiles && hidden(upath) {
+		serveError(w, "404 Page Not Found", http.StatusNotFound)
+		return
+	}
+
+	if serveRedirect(h.redirects, w, r, upath) {
+		return
+	}
+
+	if isSecurityTxt(cfg, upath) {
+		serveSecurityTxt(cfg, w, r)
+		return
+	}
+	if cfg.RobotsTxt != "" && upath == robotsPath {
+		serveRobotsTxt(cfg, w, r)
+		return
+	}
+
+	// redirect .../index.html to .../
+	// can't use Redirect() because that would make the path absolute,
+	// which would be a problem running under StripPrefix
+	if strings.HasSuffix(r.URL.Path, "/"+indexPage) {
+		localRedirect(w, r, "./")
+		return
+	}
+
+	// Serve index page on root path
+	if upath == "/" {
+		if languageRedirect(cfg, w, r, upath) {
+			return
+		}
+		serveIndex(s, cfg, w, r)
+		return
+	}
+
+	name := strings.TrimPrefix(upath, "/")
+
+	// Validate the path is safe (prevents directory traversal)
+	if !filepath.IsLocal(name) {
+		serveError(w, "400 Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	// Refuse blocked files before the filesystem can reveal their existence
+	if blocked(cfg, name) {
+		serveError(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Rewrite logical asset names to their content-hashed counterparts
+	immutable := false
+	if target, ok := s.manifest.lookup(name); ok {
+		if _, err := fs.Stat(fsys, target); err == nil {
+			name = target
+			immutable = true
+		} else {
+			cfg.Logger.Warn("spaserver: webpack manifest target not found", "path", name, "target", target)
+		}
+	}
+
+	start := time.Now()
+	fstat, seeker, closeFile, err := s.openStatic(name)
+	timing.add(timingFS, start)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			if cfg.WellKnownPassthrough && wellKnown(upath) {
+				serveError(w, "404 Page Not Found", http.StatusNotFound)
 				return
 			}
-			// Default:
-			serveError(w, "500 Internal Server Error", http.StatusInternalServerError)
+			serveNotFound(s, cfg, w, r)
 			return
 		}
-		defer file.Close()
-
-		fstat, err := file.Stat()
-		if err != nil {
-			serveError(w, "500 Internal Server Error", http.StatusInternalServerError)
+		if errors.Is(err, fs.ErrPermission) {
+			serveError(w, "403 Forbidden", http.StatusForbidden)
 			return
 		}
+		// Default:
+		serveError(w, "500 Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	defer closeFile()
 
-		// If the path is a directory, display the index html page instead
-		if fstat.IsDir() {
-			serveIndex(fsys, cfg, w, r)
+	// If the path is a directory, display the index html page instead,
+	// unless directory access is forbidden
+	if fstat.IsDir() {
+		if cfg.WellKnownPassthrough && wellKnown(upath) {
+			serveError(w, "404 Page Not Found", http.StatusNotFound)
+			return
+		}
+		if cfg.ForbidDirectoryAccess {
+			serveError(w, "403 Forbidden", http.StatusForbidden)
+			return
+		}
+		if cfg.MPAMode {
+			serveDirectoryIndex(s, cfg, w, r, name)
 			return
 		}
+		serveIndex(s, cfg, w, r)
+		return
+	}
 
-		seeker, err := fileToReadSeeker(file)
+	if cfg.ContentHashETags {
+		start := time.Now()
+		etag, err := s.contentETag(name, fstat.ModTime(), fstat.Size(), seeker)
+		timing.add(timingCache, start)
 		if err != nil {
 			serveError(w, "500 Internal Server Error", http.StatusInternalServerError)
 			return
 		}
+		w.Header().Set("ETag", etag)
+	}
+	if cfg.ContentDigest != "" {
+		start := time.Now()
+		err := setFileDigest(s, cfg, w, r, name, fstat, seeker)
+		timing.add(timingCache, start)
+		if err != nil {
+			serveError(w, "500 Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+	}
 
-		// Serve the content
-		http.ServeContent(w, r, path.Base(upath), fstat.ModTime(), seeker)
-	})
+	setStaticCacheControl(cfg, w, name, immutable)
+	setContentType(cfg, w, name)
+	setCDNHeaders(cfg, w, name)
+	setServiceWorkerHeaders(cfg, w, name)
+	setDownloadHeaders(cfg, w, upath)
+
+	// Serve the content
+	if servePreencoded(s, cfg, w, r, name, fstat) {
+		return
+	}
+	http.ServeContent(writerToResponse(w, seeker), r, path.Base(name), fstat.ModTime(), seeker)
+}
+
+// checkIndex reports whether fsys has a readable index.html at its root.
+func checkIndex(fsys fs.FS) error {
+	f, err := fsys.Open(indexPage)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s not found at the filesystem root: %w", indexPage, err)
+	}
+	if err != nil {
+		return fmt.Errorf("%s is not readable: %w", indexPage, err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("%s is not readable: %w", indexPage, err)
+	}
+	if fi.IsDir() {
+		return fmt.Errorf("%s at the filesystem root is a directory", indexPage)
+	}
+	return nil
 }
 
 // serveIndex sends the index.html file with no-cache and security headers.
 // This prevents caching of the SPA entry point, ensuring users always get
 // the latest version and route handling works correctly.
-func serveIndex(fsys fs.FS, cfg config, w http.ResponseWriter, r *http.Request) {
-	b, err := fs.ReadFile(fsys, indexPage)
+func serveIndex(s *site, cfg Config, w http.ResponseWriter, r *http.Request) {
+	name := indexPage
+	if len(cfg.Locales) > 0 {
+		name = localeIndexPage(s.fsys, cfg, r)
+		w.Header().Add("Vary", "Accept-Language")
+	}
+	if cfg.DarkModeIndex != "" {
+		name = colorSchemeIndexPage(s.fsys, cfg, w, r, name)
+	}
+	setCanonicalLink(cfg, w, r)
+
+	// A HEAD response only needs the size of an unmodified index file
+	if r.Method == http.MethodHead && s.index == nil && !s.modifiesIndex(cfg) && cfg.ContentDigest == "" && r.Header.Get("Range") == "" {
+		serveIndexHead(s, cfg, w, r, name)
+		return
+	}
+
+	timing := requestTiming(r)
+	start := time.Now()
+	b, err := s.readIndex(name)
+	if name == indexPage && s.index != nil {
+		timing.add(timingCache, start)
+	} else {
+		timing.add(timingFS, start)
+	}
 	if err != nil {
 		serveError(w, "404 Page Not Found", http.StatusNotFound)
 		return
 	}
 
+	start = time.Now()
+	b = s.injectIndex(cfg, b)
+	if cfg.devReload {
+		b = injectDevReload(b)
+	}
+	timing.add(timingPrep, start)
+	setBodyDigest(cfg, w, r, b)
+
 	seeker := bytes.NewReader(b)
 
 	// Delete any ETag headers that may have been set
@@ -166,6 +765,52 @@ func serveIndex(fsys fs.FS, cfg config, w http.ResponseWriter, r *http.Request)
 		}
 	}
 
+	setPageHeaders(s, cfg, w)
+	setContentType(cfg, w, name)
+	setCDNHeaders(cfg, w, "")
+
+	http.ServeContent(w, r, name, s.indexModTime(cfg, name), seeker)
+}
+
+// serveIndexHead answers a HEAD request for the index file name with the
+// headers serveIndex would send, taking its length from fs.Stat instead of
+// reading it.
+func serveIndexHead(s *site, cfg Config, w http.ResponseWriter, r *http.Request, name string) {
+	start := time.Now()
+	err := s.checkContained("stat", name)
+	var fi fs.FileInfo
+	if err == nil {
+		fi, err = fs.Stat(s.fsys, name)
+	}
+	requestTiming(r).add(timingFS, start)
+	if err != nil || fi.IsDir() {
+		serveError(w, "404 Page Not Found", http.StatusNotFound)
+		return
+	}
+
+	setPageHeaders(s, cfg, w)
+	setContentType(cfg, w, name)
+	setCDNHeaders(cfg, w, "")
+
+	// Match the type ServeContent would pick for the file
+	if w.Header().Get("Content-Type") == "" {
+		ctype := mime.TypeByExtension(path.Ext(name))
+		if ctype == "" {
+			ctype = "text/html; charset=utf-8"
+		}
+		w.Header().Set("Content-Type", ctype)
+	}
+	if mt := fi.ModTime(); cfg.IndexLastModified && !mt.IsZero() && !mt.Equal(time.Unix(0, 0)) {
+		w.Header().Set("Last-Modified", mt.UTC().Format(http.TimeFormat))
+	}
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("Content-Length", strconv.FormatInt(fi.Size(), 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+// setPageHeaders sets the no-cache and security headers sent with HTML
+// pages served in place of a requested path.
+func setPageHeaders(s *site, cfg Config, w http.ResponseWriter) {
 	// Set NoCache headers
 	for k, v := range noCacheHeaders {
 		w.Header().Set(k, v)
@@ -175,11 +820,23 @@ func serveIndex(fsys fs.FS, cfg config, w http.ResponseWriter, r *http.Request)
 	for k, v := range securityHeaders {
 		w.Header().Set(k, v)
 	}
-	if cfg.csp != "" {
-		w.Header().Set("Content-Security-Policy", cfg.csp)
+	if s.csp != "" {
+		w.Header().Set("Content-Security-Policy", s.csp)
+	}
+	if cfg.DocumentPolicy != "" {
+		w.Header().Set("Document-Policy", cfg.DocumentPolicy)
+	}
+	if cfg.OriginAgentCluster {
+		w.Header().Set("Origin-Agent-Cluster", "?1")
+	}
+	if cfg.XRobotsTag != "" {
+		w.Header().Set("X-Robots-Tag", cfg.XRobotsTag)
+	}
+	if cfg.DNSPrefetchControl != nil && *cfg.DNSPrefetchControl {
+		w.Header().Set("X-DNS-Prefetch-Control", "on")
+	} else {
+		w.Header().Set("X-DNS-Prefetch-Control", "off")
 	}
-
-	http.ServeContent(w, r, indexPage, time.Unix(0, 0), seeker)
 }
 
 // localRedirect gives a Moved Permanently response.
@@ -202,8 +859,12 @@ func serveError(w http.ResponseWriter, text string, code int) {
 	for _, k := range []string{
 		"Cache-Control",
 		"Content-Encoding",
+		"Content-Digest",
 		"Etag",
 		"Last-Modified",
+		"Surrogate-Control",
+		"Surrogate-Key",
+		"Cache-Tag",
 	} {
 		if _, hasKey := h[k]; !hasKey {
 			continue
@@ -214,10 +875,145 @@ func serveError(w http.ResponseWriter, text string, code int) {
 	http.Error(w, text, code)
 }
 
-// fileToReadSeeker converts an fs.File to io.ReadSeeker for http.ServeContent.
-// embed.FS and os.DirFS files implement io.ReadSeeker directly.
-// Custom fs.FS implementations may need buffering as a fallback.
-func fileToReadSeeker(file fs.File) (io.ReadSeeker, error) {
+// openStatic opens the static file name for serving. The returned seeker
+// is nil if name is a directory. Close must be called when done. Files
+// reached through symlinks that escape the root directory are refused
+// with an error wrapping fs.ErrPermission.
+func (s *site) openStatic(name string) (info fs.FileInfo, seeker io.ReadSeeker, close func() error, err error) {
+	if err := s.checkContained("open", name); err != nil {
+		return nil, nil, nil, err
+	}
+	if s.reads != nil {
+		f, err := s.readShared(name)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if f.info.IsDir() {
+			return f.info, nil, nopClose, nil
+		}
+		seeker, err := s.decodeContent(bytes.NewReader(f.data), name, f.info)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return f.info, seeker, nopClose, nil
+	}
+
+	file, err := s.fsys.Open(name)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	info, err = file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, nil, err
+	}
+	if info.IsDir() {
+		return info, nil, file.Close, nil
+	}
+	seeker, err = toReadSeeker(file)
+	if err == nil {
+		seeker, err = s.decodeContent(seeker, name, info)
+	}
+	if err != nil {
+		file.Close()
+		return nil, nil, nil, err
+	}
+	return info, seeker, file.Close, nil
+}
+
+// writerToResponse returns w wrapped so that, when it implements
+// io.ReaderFrom and content implements io.WriterTo, a body covering the
+// rest of content is handed to w's ReadFrom as content itself.
+// http.ServeContent copies the body through an io.LimitedReader, which
+// hides io.WriterTo and makes net/http copy it in 32 KB chunks; with the
+// io.WriterTo of a bytes.Reader, as served by WithSingleFlight, the body is
+// written at once, and an *os.File still reaches sendfile.
+func writerToResponse(w http.ResponseWriter, content io.ReadSeeker) http.ResponseWriter {
+	rf, ok := w.(io.ReaderFrom)
+	if !ok {
+		return w
+	}
+	if _, ok := content.(io.WriterTo); !ok {
+		return w
+	}
+	return &writerToWriter{ResponseWriter: w, rf: rf, content: content}
+}
+
+// writerToWriter is the ResponseWriter returned by writerToResponse.
+type writerToWriter struct {
+	http.ResponseWriter
+	rf      io.ReaderFrom
+	content io.ReadSeeker
+}
+
+func (w *writerToWriter) ReadFrom(src io.Reader) (int64, error) {
+	// Range requests copy part of the content
+	if lr, ok := src.(*io.LimitedReader); ok && lr.R == w.content && remaining(w.content) == lr.N {
+		return w.rf.ReadFrom(w.content)
+	}
+	return w.rf.ReadFrom(src)
+}
+
+// Unwrap returns the wrapped ResponseWriter for http.ResponseController.
+func (w *writerToWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// remaining returns the number of bytes of seeker after its current
+// offset, or -1 if it cannot seek.
+func remaining(seeker io.Seeker) int64 {
+	pos, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return -1
+	}
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return -1
+	}
+	if _, err := seeker.Seek(pos, io.SeekStart); err != nil {
+		return -1
+	}
+	return end - pos
+}
+
+// nopClose is the close function of content that holds no resources.
+func nopClose() error { return nil }
+
+// decodeContent returns the content of the file name held by seeker.
+// Gzip content in a file whose name does not suggest compression is
+// decompressed, as it would otherwise reach the browser without a
+// Content-Encoding. The result is cached per file, so each file is
+// decompressed once rather than on every request.
+func (s *site) decodeContent(seeker io.ReadSeeker, name string, info fs.FileInfo) (io.ReadSeeker, error) {
+	if compressedExts[strings.ToLower(path.Ext(name))] {
+		return seeker, nil
+	}
+	gz, err := isGzip(seeker)
+	if err != nil {
+		return nil, err
+	}
+	if !gz {
+		return seeker, nil
+	}
+
+	data, ok := s.gunzipped.get(name, info.ModTime(), info.Size())
+	if !ok {
+		data = gunzip(seeker)
+		s.gunzipped.put(name, info.ModTime(), info.Size(), data)
+	}
+	if data == nil {
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("seek failed: %w", err)
+		}
+		return seeker, nil
+	}
+	return bytes.NewReader(data), nil
+}
+
+// toReadSeeker returns file as an io.ReadSeeker positioned at its start.
+// embed.FS and os.DirFS files implement io.ReadSeeker directly; other
+// files are buffered.
+func toReadSeeker(file fs.File) (io.ReadSeeker, error) {
 	// Try to assert to io.ReadSeeker
 	// Both embed.FS and os.DirFS files implement this
 	if seeker, ok := file.(io.ReadSeeker); ok {
@@ -235,3 +1031,86 @@ func fileToReadSeeker(file fs.File) (io.ReadSeeker, error) {
 
 	return bytes.NewReader(data), nil
 }
+
+// gzipMagic are the first bytes of every gzip stream (RFC 1952).
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// compressedExts are extensions of files whose compressed bytes are the
+// content itself, and must be served as they are.
+var compressedExts = map[string]bool{
+	".gz":   true,
+	".gzip": true,
+	".tgz":  true,
+	".svgz": true,
+}
+
+// maxGunzipSize limits the decompressed size of a gzip file served by
+// decodeContent. Larger files are served as they are, so that a small file
+// cannot expand into an arbitrary amount of memory.
+const maxGunzipSize = 32 << 20
+
+// isGzip reports whether seeker starts with the gzip magic bytes, leaving
+// it positioned at its start.
+func isGzip(seeker io.ReadSeeker) (bool, error) {
+	magic := make([]byte, len(gzipMagic))
+	_, err := io.ReadFull(seeker, magic)
+	if _, serr := seeker.Seek(0, io.SeekStart); serr != nil {
+		return false, fmt.Errorf("seek failed: %w", serr)
+	}
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return false, fmt.Errorf("read failed: %w", err)
+	}
+	return err == nil && bytes.Equal(magic, gzipMagic), nil
+}
+
+// gunzip returns the decompressed content of the gzip stream r, or nil if
+// the stream is corrupt or decompresses to more than maxGunzipSize bytes.
+func gunzip(r io.Reader) []byte {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil
+	}
+	data, err := io.ReadAll(io.LimitReader(zr, maxGunzipSize+1))
+	if err != nil || len(data) > maxGunzipSize {
+		return nil
+	}
+	return data
+}
+
+// gunzipEntry is the cached decompressed content of a file.
+type gunzipEntry struct {
+	modTime time.Time
+	size    int64
+	data    []byte // nil if the file is served as it is
+	stored  time.Time
+}
+
+// gunzipCache caches the decompressed content of gzip files by name.
+type gunzipCache struct {
+	mu      sync.Mutex
+	entries map[string]gunzipEntry
+	ttl     time.Duration // zero keeps entries until the file changes
+	now     func() time.Time
+}
+
+// get returns the cached content of the file name with the given
+// modification time and size.
+func (c *gunzipCache) get(name string, modTime time.Time, size int64) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[name]
+	if !ok || !e.modTime.Equal(modTime) || e.size != size || expired(e.stored, c.now(), c.ttl) {
+		return nil, false
+	}
+	return e.data, true
+}
+
+// put caches the content of the file name.
+func (c *gunzipCache) put(name string, modTime time.Time, size int64, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]gunzipEntry)
+	}
+	c.entries[name] = gunzipEntry{modTime: modTime, size: size, data: data, stored: c.now()}
+}
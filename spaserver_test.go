@@ -1,15 +1,90 @@
 package spaserver
 
 import (
+	"bytes"
+	"compress/gzip"
+	"embed"
+	"io"
+	"io/fs"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"testing"
+	"testing/fstest"
 )
 
+//go:embed testdata
+var embeddedTestdata embed.FS
+
 func TestServe(t *testing.T) {
+	testServe(t, os.DirFS("testdata"))
+}
+
+// TestServeEmbed runs the TestServe cases against an embed.FS, whose files
+// seek and stat differently from os.DirFS ones.
+func TestServeEmbed(t *testing.T) {
+	fsys, err := fs.Sub(embeddedTestdata, "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testServe(t, fsys)
+}
+
+func TestServeSubFS(t *testing.T) {
+	root := fstest.MapFS{
+		"index.html":                  {Data: []byte("root index")},
+		"secret.txt":                  {Data: []byte("secret")},
+		"testdata/secret.txt":         {Data: []byte("secret")},
+		"testdata/subapp/index.html":  {Data: []byte("subapp index")},
+		"testdata/subapp/css/app.css": {Data: []byte("body {}")},
+	}
+	fsys, err := fs.Sub(root, "testdata/subapp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := New(fsys)
+	if err != nil {
+		t.Fatalf("expected index.html at the sub-FS root, got: %v", err)
+	}
+
+	tt := []struct {
+		name       string
+		url        string
+		statusCode int
+		body       string
+	}{
+		{name: "index at sub root", url: "/", statusCode: 200, body: "subapp index"},
+		{name: "route falls back to sub index", url: "/some/route", statusCode: 200, body: "subapp index"},
+		{name: "static file relative to sub root", url: "/css/app.css", statusCode: 200, body: "body {}"},
+		{name: "full path is not resolved", url: "/testdata/subapp/css/app.css", statusCode: 200, body: "subapp index"},
+		{name: "parent traversal", url: "/../secret.txt", statusCode: 200, body: "subapp index"},
+		{name: "double parent traversal", url: "/../../secret.txt", statusCode: 200, body: "subapp index"},
+		{name: "nested parent traversal", url: "/css/../../../secret.txt", statusCode: 200, body: "subapp index"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+			// Set the path directly, as the server would after decoding,
+			// since NewRequest resolves dot segments
+			r.URL.Path = tc.url
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.statusCode {
+				t.Errorf("statusCode expected: %d, got: %d", tc.statusCode, w.Code)
+			}
+			if body := w.Body.String(); body != tc.body {
+				t.Errorf("body expected: %q, got: %q", tc.body, body)
+			}
+		})
+	}
+}
+
+func testServe(t *testing.T, fsys fs.FS) {
 	tt := []struct {
 		name       string
 		url        string
@@ -22,88 +97,87 @@ func TestServe(t *testing.T) {
 			url:        "http://www.example.com",
 			statusCode: 200,
 			body:       "index.html",
-			headers:    "Accept-Ranges:bytes Cache-Control:no-cache, no-store, no-transform, must-revalidate, private, max-age=0 Content-Length:11 Content-Security-Policy:default-src 'self' Content-Type:text/html; charset=utf-8 Expires:Thu, 01 Jan 1970 00:00:00 GMT Pragma:no-cache X-Accel-Expires:0 X-Content-Type-Options:nosniff X-Frame-Options:DENY",
+			headers:    "Accept-Ranges:bytes Cache-Control:no-cache, no-store, no-transform, must-revalidate, private, max-age=0 Content-Length:11 Content-Security-Policy:default-src 'self' Content-Type:text/html; charset=utf-8 Expires:Thu, 01 Jan 1970 00:00:00 GMT Pragma:no-cache X-Accel-Expires:0 X-Content-Type-Options:nosniff X-Dns-Prefetch-Control:off X-Frame-Options:DENY",
 		},
 		{
 			name:       "slash path renders index.html",
 			url:        "http://www.example.com/",
 			statusCode: 200,
 			body:       "index.html",
-			headers:    "Accept-Ranges:bytes Cache-Control:no-cache, no-store, no-transform, must-revalidate, private, max-age=0 Content-Length:11 Content-Security-Policy:default-src 'self' Content-Type:text/html; charset=utf-8 Expires:Thu, 01 Jan 1970 00:00:00 GMT Pragma:no-cache X-Accel-Expires:0 X-Content-Type-Options:nosniff X-Frame-Options:DENY",
+			headers:    "Accept-Ranges:bytes Cache-Control:no-cache, no-store, no-transform, must-revalidate, private, max-age=0 Content-Length:11 Content-Security-Policy:default-src 'self' Content-Type:text/html; charset=utf-8 Expires:Thu, 01 Jan 1970 00:00:00 GMT Pragma:no-cache X-Accel-Expires:0 X-Content-Type-Options:nosniff X-Dns-Prefetch-Control:off X-Frame-Options:DENY",
 		}, {
 			name:       "redirects index.html to root",
 			url:        "http://www.example.com/index.html",
 			statusCode: 301,
 			body:       "",
-			headers:    "Location:./",
+			headers:    "Location:./ X-Content-Type-Options:nosniff",
 		}, {
 			name:       "redirects index.html to root with query string",
 			url:        "http://www.example.com/index.html?key1=value",
 			statusCode: 301,
 			body:       "",
-			headers:    "Location:./?key1=value",
+			headers:    "Location:./?key1=value X-Content-Type-Options:nosniff",
 		},
 		{
 			name:       "serve non index file",
 			url:        "http://www.example.com/css/main.css",
 			statusCode: 200,
 			body:       "body {\n\tdisplay: none;\n}",
-			headers:    "Accept-Ranges:bytes Content-Length:25 Content-Type:text/css; charset=utf-8",
+			headers:    "Accept-Ranges:bytes Content-Length:25 Content-Type:text/css; charset=utf-8 X-Content-Type-Options:nosniff",
 		},
 		{
 			name:       "serve root non-index file",
 			url:        "http://www.example.com/root-main.css",
 			statusCode: 200,
 			body:       "body {\n\tdisplay: none;\n}",
-			headers:    "Accept-Ranges:bytes Content-Length:25 Content-Type:text/css; charset=utf-8",
+			headers:    "Accept-Ranges:bytes Content-Length:25 Content-Type:text/css; charset=utf-8 X-Content-Type-Options:nosniff",
 		},
 		{
 			name:       "serves index on file not found",
 			url:        "http://www.example.com/doesnotexist.txt",
 			statusCode: 200,
 			body:       "index.html",
-			headers:    "Accept-Ranges:bytes Cache-Control:no-cache, no-store, no-transform, must-revalidate, private, max-age=0 Content-Length:11 Content-Security-Policy:default-src 'self' Content-Type:text/html; charset=utf-8 Expires:Thu, 01 Jan 1970 00:00:00 GMT Pragma:no-cache X-Accel-Expires:0 X-Content-Type-Options:nosniff X-Frame-Options:DENY",
+			headers:    "Accept-Ranges:bytes Cache-Control:no-cache, no-store, no-transform, must-revalidate, private, max-age=0 Content-Length:11 Content-Security-Policy:default-src 'self' Content-Type:text/html; charset=utf-8 Expires:Thu, 01 Jan 1970 00:00:00 GMT Pragma:no-cache X-Accel-Expires:0 X-Content-Type-Options:nosniff X-Dns-Prefetch-Control:off X-Frame-Options:DENY",
 		},
 		{
 			name:       "serves index on directory listing",
 			url:        "http://www.example.com/css/",
 			statusCode: 200,
 			body:       "index.html",
-			headers:    "Accept-Ranges:bytes Cache-Control:no-cache, no-store, no-transform, must-revalidate, private, max-age=0 Content-Length:11 Content-Security-Policy:default-src 'self' Content-Type:text/html; charset=utf-8 Expires:Thu, 01 Jan 1970 00:00:00 GMT Pragma:no-cache X-Accel-Expires:0 X-Content-Type-Options:nosniff X-Frame-Options:DENY",
+			headers:    "Accept-Ranges:bytes Cache-Control:no-cache, no-store, no-transform, must-revalidate, private, max-age=0 Content-Length:11 Content-Security-Policy:default-src 'self' Content-Type:text/html; charset=utf-8 Expires:Thu, 01 Jan 1970 00:00:00 GMT Pragma:no-cache X-Accel-Expires:0 X-Content-Type-Options:nosniff X-Dns-Prefetch-Control:off X-Frame-Options:DENY",
 		},
 		{
 			name:       "path traversal cleaned to safe path",
 			url:        "http://www.example.com/../../../etc/passwd",
 			statusCode: 200,
 			body:       "index.html",
-			headers:    "Accept-Ranges:bytes Cache-Control:no-cache, no-store, no-transform, must-revalidate, private, max-age=0 Content-Length:11 Content-Security-Policy:default-src 'self' Content-Type:text/html; charset=utf-8 Expires:Thu, 01 Jan 1970 00:00:00 GMT Pragma:no-cache X-Accel-Expires:0 X-Content-Type-Options:nosniff X-Frame-Options:DENY",
+			headers:    "Accept-Ranges:bytes Cache-Control:no-cache, no-store, no-transform, must-revalidate, private, max-age=0 Content-Length:11 Content-Security-Policy:default-src 'self' Content-Type:text/html; charset=utf-8 Expires:Thu, 01 Jan 1970 00:00:00 GMT Pragma:no-cache X-Accel-Expires:0 X-Content-Type-Options:nosniff X-Dns-Prefetch-Control:off X-Frame-Options:DENY",
 		},
 		{
 			name:       "relative traversal cleaned to safe path",
 			url:        "http://www.example.com/css/../../../etc/passwd",
 			statusCode: 200,
 			body:       "index.html",
-			headers:    "Accept-Ranges:bytes Cache-Control:no-cache, no-store, no-transform, must-revalidate, private, max-age=0 Content-Length:11 Content-Security-Policy:default-src 'self' Content-Type:text/html; charset=utf-8 Expires:Thu, 01 Jan 1970 00:00:00 GMT Pragma:no-cache X-Accel-Expires:0 X-Content-Type-Options:nosniff X-Frame-Options:DENY",
+			headers:    "Accept-Ranges:bytes Cache-Control:no-cache, no-store, no-transform, must-revalidate, private, max-age=0 Content-Length:11 Content-Security-Policy:default-src 'self' Content-Type:text/html; charset=utf-8 Expires:Thu, 01 Jan 1970 00:00:00 GMT Pragma:no-cache X-Accel-Expires:0 X-Content-Type-Options:nosniff X-Dns-Prefetch-Control:off X-Frame-Options:DENY",
 		},
 		{
 			name:       "handles double slashes in path",
 			url:        "http://www.example.com//css//main.css",
 			statusCode: 200,
 			body:       "body {\n\tdisplay: none;\n}",
-			headers:    "Accept-Ranges:bytes Content-Length:25 Content-Type:text/css; charset=utf-8",
+			headers:    "Accept-Ranges:bytes Content-Length:25 Content-Type:text/css; charset=utf-8 X-Content-Type-Options:nosniff",
 		},
 		{
 			name:       "index.html includes security headers",
 			url:        "http://www.example.com/",
 			statusCode: 200,
 			body:       "index.html",
-			headers:    "Accept-Ranges:bytes Cache-Control:no-cache, no-store, no-transform, must-revalidate, private, max-age=0 Content-Length:11 Content-Security-Policy:default-src 'self' Content-Type:text/html; charset=utf-8 Expires:Thu, 01 Jan 1970 00:00:00 GMT Pragma:no-cache X-Accel-Expires:0 X-Content-Type-Options:nosniff X-Frame-Options:DENY",
+			headers:    "Accept-Ranges:bytes Cache-Control:no-cache, no-store, no-transform, must-revalidate, private, max-age=0 Content-Length:11 Content-Security-Policy:default-src 'self' Content-Type:text/html; charset=utf-8 Expires:Thu, 01 Jan 1970 00:00:00 GMT Pragma:no-cache X-Accel-Expires:0 X-Content-Type-Options:nosniff X-Dns-Prefetch-Control:off X-Frame-Options:DENY",
 		},
 	}
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			fsys := os.DirFS("testdata")
 			h := Serve(fsys)
 
 			r, err := http.NewRequest(http.MethodGet, tc.url, nil)
@@ -216,6 +290,139 @@ func TestServeWithCSP(t *testing.T) {
 	}
 }
 
+func TestServeNoSniffOnAllResponses(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":   {Data: []byte("index.html")},
+		"css/main.css": {Data: []byte("body {}")},
+		"js/app.js":    {Data: []byte("console.log('app')")},
+		"img/logo.png": {Data: []byte("\x89PNG\r\n\x1a\n")},
+	}
+	h := Serve(fsys)
+
+	for _, url := range []string{
+		"http://www.example.com/",
+		"http://www.example.com/css/main.css",
+		"http://www.example.com/js/app.js",
+		"http://www.example.com/img/logo.png",
+		"http://www.example.com/index.html",
+	} {
+		r, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if got := w.Result().Header.Values("X-Content-Type-Options"); len(got) != 1 || got[0] != "nosniff" {
+			t.Errorf("%s X-Content-Type-Options expected: [nosniff], got: %q", url, got)
+		}
+	}
+}
+
+func TestServeDecompressesGzipFiles(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte("console.log('app')"))
+	zw.Close()
+	gz := bytes.Clone(buf.Bytes())
+
+	// A file inflating beyond maxGunzipSize is served as it is
+	buf.Reset()
+	zw = gzip.NewWriter(&buf)
+	zw.Write(make([]byte, maxGunzipSize+1))
+	zw.Close()
+	bomb := buf.Bytes()
+
+	fsys := fstest.MapFS{
+		"index.html":      {Data: []byte("index.html")},
+		"js/app.js":       {Data: gz},
+		"data/archive.gz": {Data: gz},
+		"img/short.bin":   {Data: []byte{0x1f}},
+		"img/corrupt.bin": {Data: []byte{0x1f, 0x8b, 0x00}},
+		"img/bomb.bin":    {Data: bomb},
+	}
+	h := Serve(fsys)
+
+	tt := []struct {
+		path        string
+		contentType string
+		body        []byte
+	}{
+		{"/js/app.js", "text/javascript; charset=utf-8", []byte("console.log('app')")},
+		{"/data/archive.gz", "application/gzip", gz},
+		{"/img/short.bin", "application/octet-stream", []byte{0x1f}},
+		{"/img/corrupt.bin", "application/octet-stream", []byte{0x1f, 0x8b, 0x00}},
+		{"/img/bomb.bin", "application/octet-stream", bomb},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.path, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+tc.path, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status expected: %d, got: %d", http.StatusOK, w.Code)
+			}
+			if got := w.Body.Bytes(); !bytes.Equal(got, tc.body) {
+				t.Errorf("body expected: %q, got: %q", tc.body, got)
+			}
+			if got := w.Header().Get("Content-Type"); got != tc.contentType {
+				t.Errorf("Content-Type expected: %q, got: %q", tc.contentType, got)
+			}
+			if got := w.Header().Get("Content-Encoding"); got != "" {
+				t.Errorf("Content-Encoding expected: %q, got: %q", "", got)
+			}
+		})
+	}
+
+	// Decompressed content is cached per file
+	if _, ok := h.current.Load().gunzipped.get("js/app.js", fsys["js/app.js"].ModTime, int64(len(gz))); !ok {
+		t.Error("decompressed js/app.js expected to be cached")
+	}
+}
+
+func TestServeIndexHEAD(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("<html><head></head><body>index.html</body></html>")},
+	}
+
+	tt := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "unmodified"},
+		{name: "injected", opts: []Option{WithHeadInjection(`<meta name="x" content="y">`)}},
+		{name: "preloaded", opts: []Option{WithIndexPreload()}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := Serve(fsys, tc.opts...)
+
+			get := httptest.NewRecorder()
+			h.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "http://www.example.com/app/route", nil))
+			head := httptest.NewRecorder()
+			h.ServeHTTP(head, httptest.NewRequest(http.MethodHead, "http://www.example.com/app/route", nil))
+
+			if head.Code != get.Code {
+				t.Errorf("status expected: %d, got: %d", get.Code, head.Code)
+			}
+			if head.Body.Len() != 0 {
+				t.Errorf("body expected empty, got: %q", head.Body.String())
+			}
+			if got, want := head.Header().Get("Content-Length"), strconv.Itoa(get.Body.Len()); got != want {
+				t.Errorf("Content-Length expected: %q, got: %q", want, got)
+			}
+			for k := range get.Header() {
+				if got, want := head.Header().Values(k), get.Header().Values(k); strings.Join(got, ",") != strings.Join(want, ",") {
+					t.Errorf("%s expected: %q, got: %q", k, want, got)
+				}
+			}
+		})
+	}
+}
+
 func BenchmarkServeStatic(b *testing.B) {
 	fsys := os.DirFS("testdata")
 	h := Serve(fsys)
@@ -252,3 +459,125 @@ func BenchmarkServeNotFound(b *testing.B) {
 	}
 }
 
+func BenchmarkServeIndexHEAD(b *testing.B) {
+	fsys := os.DirFS("testdata")
+	h := Serve(fsys)
+	r, _ := http.NewRequest(http.MethodHead, "http://www.example.com/", nil)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+	}
+}
+
+// discardWriter is a ResponseWriter that drops the body.
+type discardWriter struct{ h http.Header }
+
+func (w *discardWriter) Header() http.Header         { return w.h }
+func (w *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+// discardReaderFromWriter is a discardWriter that also implements
+// io.ReaderFrom, as the net/http server's response writer does.
+type discardReaderFromWriter struct{ discardWriter }
+
+func (w *discardReaderFromWriter) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(io.Discard, r)
+}
+
+// readFromWriter is a discardWriter whose ReadFrom records the body and
+// the reader it was given.
+type readFromWriter struct {
+	discardWriter
+	body bytes.Buffer
+	src  io.Reader
+}
+
+func (w *readFromWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.src = r
+	return w.body.ReadFrom(r)
+}
+
+func TestServeWriterTo(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+	h := Serve(fstest.MapFS{"index.html": {Data: []byte("index.html")}, "app.wasm": {Data: data}}, WithSingleFlight())
+
+	tt := []struct {
+		name     string
+		rangeHdr string
+		writerTo bool
+		body     []byte
+	}{
+		{name: "whole file", writerTo: true, body: data},
+		{name: "range", rangeHdr: "bytes=10-19", body: data[10:20]},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com/app.wasm", nil)
+			if tc.rangeHdr != "" {
+				r.Header.Set("Range", tc.rangeHdr)
+			}
+			w := &readFromWriter{discardWriter: discardWriter{http.Header{}}}
+			h.ServeHTTP(w, r)
+			if _, ok := w.src.(io.WriterTo); ok != tc.writerTo {
+				t.Errorf("io.WriterTo expected: %v, got: %T", tc.writerTo, w.src)
+			}
+			if !bytes.Equal(w.body.Bytes(), tc.body) {
+				t.Errorf("body expected: %d bytes, got: %d bytes", len(tc.body), w.body.Len())
+			}
+		})
+	}
+}
+
+// writerToFS is a MapFS whose files implement io.WriterTo, as the
+// in-memory content of WithSingleFlight does.
+type writerToFS struct{ fstest.MapFS }
+
+func (fsys writerToFS) Open(name string) (fs.File, error) {
+	f, err := fsys.MapFS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return f, err
+	}
+	f.Close()
+	return writerToFile{bytes.NewReader(fsys.MapFS[name].Data), info}, nil
+}
+
+type writerToFile struct {
+	*bytes.Reader
+	info fs.FileInfo
+}
+
+func (f writerToFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f writerToFile) Close() error               { return nil }
+
+// BenchmarkServeLargeFile serves a 1 MB file whose content implements
+// io.WriterTo, and one copied through the io.LimitedReader of
+// http.ServeContent.
+func BenchmarkServeLargeFile(b *testing.B) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("index.html")},
+		"app.wasm":   {Data: bytes.Repeat([]byte{1}, 1<<20)},
+	}
+	r, _ := http.NewRequest(http.MethodGet, "http://www.example.com/app.wasm", nil)
+
+	for _, bc := range []struct {
+		name string
+		h    *Handler
+	}{
+		{"WriterTo", Serve(writerToFS{fsys})},
+		{"Copy", Serve(fsys)},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.SetBytes(1 << 20)
+			for i := 0; i < b.N; i++ {
+				bc.h.ServeHTTP(&discardReaderFromWriter{discardWriter{http.Header{}}}, r)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package spaserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// SpeculationRules are the Speculation Rules injected by
+// WithSpeculationRules, asking the browser to prerender or prefetch likely
+// next navigations.
+type SpeculationRules struct {
+	Prerender []SpeculationRule `json:"prerender,omitempty" yaml:"prerender,omitempty"`
+	Prefetch  []SpeculationRule `json:"prefetch,omitempty" yaml:"prefetch,omitempty"`
+}
+
+// SpeculationRule is a list rule: the URLs to speculate on, and an
+// optional Score between 0 and 1 of how likely they are to be visited.
+type SpeculationRule struct {
+	URLs  []string `json:"urls" yaml:"urls"`
+	Score float64  `json:"score,omitempty" yaml:"score,omitempty"`
+}
+
+// WithSpeculationRules injects a <script type="speculationrules"> element
+// with rules into the <head> of index responses. Chrome uses it to
+// prerender or prefetch the listed URLs before the user navigates to them.
+// New reports rules that are invalid under the Speculation Rules syntax.
+func WithSpeculationRules(rules SpeculationRules) Option {
+	return func(c *Config) {
+		c.SpeculationRules = &rules
+	}
+}
+
+// speculationRulesScript returns the speculation rules <script> element.
+func speculationRulesScript(rules SpeculationRules) (string, error) {
+	if errs := validateSpeculationRules(rules); len(errs) > 0 {
+		return "", errors.Join(errs...)
+	}
+
+	type listRule struct {
+		Source string   `json:"source"`
+		URLs   []string `json:"urls"`
+		Score  float64  `json:"score,omitempty"`
+	}
+	list := func(rules []SpeculationRule) []listRule {
+		out := make([]listRule, 0, len(rules))
+		for _, r := range rules {
+			out = append(out, listRule{Source: "list", URLs: r.URLs, Score: r.Score})
+		}
+		return out
+	}
+
+	b, err := json.Marshal(struct {
+		Prerender []listRule `json:"prerender,omitempty"`
+		Prefetch  []listRule `json:"prefetch,omitempty"`
+	}{list(rules.Prerender), list(rules.Prefetch)})
+	if err != nil {
+		return "", err
+	}
+	return `<script type="speculationrules">` + string(b) + `</script>`, nil
+}
+
+// validateSpeculationRules reports rules that browsers would reject.
+func validateSpeculationRules(rules SpeculationRules) []error {
+	var errs []error
+	if len(rules.Prerender) == 0 && len(rules.Prefetch) == 0 {
+		errs = append(errs, errors.New("speculation_rules: no prerender or prefetch rules"))
+	}
+	actions := []struct {
+		name  string
+		rules []SpeculationRule
+	}{{"prerender", rules.Prerender}, {"prefetch", rules.Prefetch}}
+	for _, action := range actions {
+		for i, rule := range action.rules {
+			if len(rule.URLs) == 0 {
+				errs = append(errs, fmt.Errorf("speculation_rules: %s[%d]: no urls", action.name, i))
+			}
+			for _, u := range rule.URLs {
+				if _, err := url.Parse(u); u == "" || err != nil {
+					errs = append(errs, fmt.Errorf("speculation_rules: %s[%d]: invalid url %q", action.name, i, u))
+				}
+			}
+			if rule.Score < 0 || rule.Score > 1 {
+				errs = append(errs, fmt.Errorf("speculation_rules: %s[%d]: score %v not between 0 and 1", action.name, i, rule.Score))
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,91 @@
+package spaserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeWithSpeculationRules(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("<html><head><title>App</title></head><body></body></html>")},
+	}
+	h := Serve(fsys, WithSpeculationRules(SpeculationRules{
+		Prerender: []SpeculationRule{{URLs: []string{"/dashboard"}, Score: 0.8}},
+		Prefetch:  []SpeculationRule{{URLs: []string{"/settings", "/profile"}}},
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	want := `<script type="speculationrules">` +
+		`{"prerender":[{"source":"list","urls":["/dashboard"],"score":0.8}],` +
+		`"prefetch":[{"source":"list","urls":["/settings","/profile"]}]}` +
+		`</script></head>`
+	if !strings.Contains(w.Body.String(), want) {
+		t.Errorf("body expected to contain: %q, got: %q", want, w.Body.String())
+	}
+}
+
+func TestSpeculationRulesScriptJSON(t *testing.T) {
+	script, err := speculationRulesScript(SpeculationRules{
+		Prefetch: []SpeculationRule{{URLs: []string{"/a?x=</script>"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, ok := strings.CutPrefix(script, `<script type="speculationrules">`)
+	if !ok {
+		t.Fatalf("script expected speculationrules type, got: %q", script)
+	}
+	body, _ = strings.CutSuffix(body, "</script>")
+	if strings.Contains(body, "<") {
+		t.Errorf("script body expected escaped, got: %q", body)
+	}
+	if !json.Valid([]byte(body)) {
+		t.Errorf("script body expected valid JSON, got: %q", body)
+	}
+}
+
+func TestSpeculationRulesValidation(t *testing.T) {
+	tt := []struct {
+		name  string
+		rules SpeculationRules
+		want  string
+	}{
+		{
+			name:  "empty",
+			rules: SpeculationRules{},
+			want:  "speculation_rules: no prerender or prefetch rules",
+		},
+		{
+			name:  "no urls",
+			rules: SpeculationRules{Prerender: []SpeculationRule{{}}},
+			want:  "speculation_rules: prerender[0]: no urls",
+		},
+		{
+			name:  "empty url",
+			rules: SpeculationRules{Prefetch: []SpeculationRule{{URLs: []string{""}}}},
+			want:  `speculation_rules: prefetch[0]: invalid url ""`,
+		},
+		{
+			name:  "score out of range",
+			rules: SpeculationRules{Prefetch: []SpeculationRule{{URLs: []string{"/a"}, Score: 1.5}}},
+			want:  "speculation_rules: prefetch[0]: score 1.5 not between 0 and 1",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(fstest.MapFS{"index.html": {Data: []byte("index.html")}}, WithSpeculationRules(tc.rules))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("error expected to contain: %q, got: %v", tc.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package spaserver
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// sriHashes are the hash algorithms allowed by the Subresource Integrity
+// specification.
+var sriHashes = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// GenerateSRI returns the Subresource Integrity hash of the file name in
+// fsys, in the "sha384-<base64>" form used by the integrity HTML attribute.
+// algo selects the hash ("sha256", "sha384" or "sha512") and defaults to
+// sha384.
+func GenerateSRI(fsys fs.FS, name string, algo ...string) (string, error) {
+	alg := "sha384"
+	if len(algo) > 0 {
+		alg = strings.ToLower(algo[0])
+	}
+	newHash, ok := sriHashes[alg]
+	if !ok {
+		return "", fmt.Errorf("spaserver: unsupported SRI algorithm %q", alg)
+	}
+
+	f, err := fsys.Open(strings.TrimPrefix(name, "/"))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := newHash()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("spaserver: hash %s: %w", name, err)
+	}
+
+	return alg + "-" + base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// GenerateSRIManifest walks fsys and returns the sha384 SRI hash of every
+// .js and .css file, keyed by relative path. It can be used to fill in
+// integrity attributes in index.html templates without build-tool plugins.
+func GenerateSRIManifest(fsys fs.FS) (map[string]string, error) {
+	manifest := make(map[string]string)
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		switch strings.ToLower(path.Ext(name)) {
+		case ".js", ".css":
+		default:
+			return nil
+		}
+
+		sri, err := GenerateSRI(fsys, name)
+		if err != nil {
+			return err
+		}
+		manifest[name] = sri
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
@@ -0,0 +1,61 @@
+package spaserver
+
+import (
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+func TestGenerateSRI(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js": {Data: []byte("alert('Hello, world.');")},
+	}
+
+	// Expected values computed with:
+	// printf "alert('Hello, world.');" | openssl dgst -<algo> -binary | openssl base64 -A
+	tt := []struct {
+		algo []string
+		want string
+	}{
+		{algo: nil, want: "sha384-H8BRh8j48O9oYatfu5AZzq6A9RINhZO5H16dQZngK7T62em8MUt1FLm52t+eX6xO"},
+		{algo: []string{"sha256"}, want: "sha256-qznLcsROx4GACP2dm0UCKCzCG+HiZ1guq6ZZDob/Tng="},
+		{algo: []string{"sha512"}, want: "sha512-Q2bFTOhEALkN8hOms2FKTDLy7eugP2zFZ1T8LCvX42Fp3WoNr3bjZSAHeOsHrbV1Fu9/A0EzCinRE7Af1ofPrw=="},
+	}
+
+	for _, tc := range tt {
+		got, err := GenerateSRI(fsys, "app.js", tc.algo...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("GenerateSRI(%v) expected: %s, got: %s", tc.algo, tc.want, got)
+		}
+	}
+
+	if _, err := GenerateSRI(fsys, "app.js", "md5"); err == nil {
+		t.Error("expected error for unsupported algorithm")
+	}
+	if _, err := GenerateSRI(fsys, "missing.js"); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestGenerateSRIManifest(t *testing.T) {
+	manifest, err := GenerateSRIManifest(os.DirFS("testdata"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(manifest) != 2 {
+		t.Fatalf("expected 2 entries, got: %v", manifest)
+	}
+	for _, name := range []string{"css/main.css", "root-main.css"} {
+		want, err := GenerateSRI(os.DirFS("testdata"), name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if manifest[name] != want {
+			t.Errorf("%s expected: %s, got: %s", name, want, manifest[name])
+		}
+	}
+}
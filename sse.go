@@ -0,0 +1,54 @@
+package spaserver
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// SSERoute forwards Server-Sent Events requests whose URL path matches the
+// path.Match glob Pattern to Handler.
+type SSERoute struct {
+	Pattern string
+	Handler http.Handler
+}
+
+// WithSSEPassthrough forwards requests with Accept: text/event-stream whose
+// URL path matches the path.Match glob pattern, e.g. "/events/*", to
+// handler. Like WithWebSocketPassthrough, they are subject to the IP
+// filter and basic auth but otherwise bypass all SPA handling: the
+// response is neither compressed nor buffered, and carries no SPA headers.
+// Other requests to the same paths are served as usual. Multiple calls
+// accumulate; the first matching route is used.
+func WithSSEPassthrough(pattern string, handler http.Handler) Option {
+	return func(c *Config) {
+		c.SSERoutes = append(c.SSERoutes, SSERoute{Pattern: pattern, Handler: handler})
+	}
+}
+
+// sseHandler returns the handler of the first SSE route matching r, or nil.
+func sseHandler(routes []SSERoute, r *http.Request) http.Handler {
+	if len(routes) == 0 || !acceptsEventStream(r) {
+		return nil
+	}
+	upath := path.Clean("/" + r.URL.Path)
+	for _, route := range routes {
+		if ok, _ := path.Match(route.Pattern, upath); ok {
+			return route.Handler
+		}
+	}
+	return nil
+}
+
+// acceptsEventStream reports whether r accepts text/event-stream.
+func acceptsEventStream(r *http.Request) bool {
+	for _, v := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(v, ",") {
+			mediaType, _, _ := strings.Cut(part, ";")
+			if strings.EqualFold(strings.TrimSpace(mediaType), "text/event-stream") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package spaserver
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestServeWithSSEPassthrough(t *testing.T) {
+	fsys := fstest.MapFS{"index.html": {Data: []byte("<html>index</html>")}}
+	done := make(chan struct{})
+	events := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: hello\n\n")
+		w.(http.Flusher).Flush()
+		<-done
+	})
+	srv := httptest.NewServer(Serve(fsys, WithSSEPassthrough("/events/*", events), WithGzip(), WithStaticCacheMaxAge(3600)))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/events/feed", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	defer close(done)
+
+	for _, header := range []string{"Content-Encoding", "Cache-Control", "Content-Security-Policy", "X-Frame-Options"} {
+		if got := resp.Header.Get(header); got != "" {
+			t.Errorf("%s expected to be absent, got: %q", header, got)
+		}
+	}
+	// The event arrives while the handler is still running
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "data: hello\n" {
+		t.Errorf("event expected: %q, got: %q", "data: hello\n", line)
+	}
+}
+
+func TestSSEHandler(t *testing.T) {
+	events := http.NotFoundHandler()
+	routes := []SSERoute{{Pattern: "/events/*", Handler: events}}
+
+	tt := []struct {
+		url    string
+		accept string
+		want   bool
+	}{
+		{url: "http://www.example.com/events/feed", accept: "text/event-stream", want: true},
+		{url: "http://www.example.com/events/feed", accept: "text/html, Text/Event-Stream;q=0.9", want: true},
+		{url: "http://www.example.com/events/feed", accept: "text/html"},
+		{url: "http://www.example.com/other", accept: "text/event-stream"},
+	}
+
+	for _, tc := range tt {
+		r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+		r.Header.Set("Accept", tc.accept)
+		if got := sseHandler(routes, r) != nil; got != tc.want {
+			t.Errorf("%s with Accept %q forwarded expected: %t, got: %t", tc.url, tc.accept, tc.want, got)
+		}
+	}
+}
+
+func TestSSEPassthroughAccessControl(t *testing.T) {
+	events := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: hello\n\n")
+	})
+	h := Serve(fstest.MapFS{"index.html": {Data: []byte("index.html")}},
+		WithSSEPassthrough("/events/*", events),
+		WithIPBlocklist("192.0.2.0/24"),
+		WithBasicAuthFunc("events", func(user, pass string) (bool, error) { return user == "admin" && pass == "s3cret", nil }),
+	)
+
+	tt := []struct {
+		name       string
+		remoteAddr string
+		auth       bool
+		status     int
+	}{
+		{name: "authorized", remoteAddr: "203.0.113.7:1234", auth: true, status: http.StatusOK},
+		{name: "blocked", remoteAddr: "192.0.2.1:1234", auth: true, status: http.StatusForbidden},
+		{name: "unauthenticated", remoteAddr: "203.0.113.7:1234", status: http.StatusUnauthorized},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com/events/feed", nil)
+			r.RemoteAddr = tc.remoteAddr
+			r.Header.Set("Accept", "text/event-stream")
+			if tc.auth {
+				r.SetBasicAuth("admin", "s3cret")
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("status expected: %d, got: %d", tc.status, w.Code)
+			}
+			if got := w.Header().Get("Content-Type") == "text/event-stream"; got != (tc.status == http.StatusOK) {
+				t.Errorf("event stream expected: %v, got: %v", tc.status == http.StatusOK, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package spaserver
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"sync"
+	"testing/fstest"
+)
+
+// NewTarGzFS reads the gzip-compressed tar archive r, such as a build
+// downloaded from an artifact store, into memory and returns a filesystem
+// serving its files. Regular files and directories are kept, with their
+// modes and modification times; links and other special entries are
+// skipped. A leading "./" is stripped from entry names, and entries whose
+// names would escape the archive root are reported as an error.
+func NewTarGzFS(r io.Reader) (fs.FS, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("tar.gz: %w", err)
+	}
+	defer zr.Close()
+
+	fsys := fstest.MapFS{}
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("tar.gz: %w", err)
+		}
+
+		name := strings.TrimSuffix(strings.TrimPrefix(hdr.Name, "./"), "/")
+		if name == "" || name == "." {
+			continue
+		}
+		if !fs.ValidPath(name) {
+			return nil, fmt.Errorf("tar.gz: %q: insecure path", hdr.Name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			fsys[name] = &fstest.MapFile{Mode: hdr.FileInfo().Mode(), ModTime: hdr.ModTime}
+		case tar.TypeReg:
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, fmt.Errorf("tar.gz: %s: %w", name, err)
+			}
+			fsys[name] = &fstest.MapFile{Data: data, Mode: hdr.FileInfo().Mode(), ModTime: hdr.ModTime}
+		}
+	}
+	return fsys, nil
+}
+
+// NewLazyTarGzFS is like NewTarGzFS but defers opening and reading the
+// archive until the filesystem is first used, so that a handler can be
+// created before the build is available. opener is called again on the
+// next use if reading the archive fails; once it succeeds, the files are
+// kept in memory.
+func NewLazyTarGzFS(opener func() (io.ReadCloser, error)) (fs.FS, error) {
+	if opener == nil {
+		return nil, errors.New("tar.gz: nil opener")
+	}
+	return &lazyTarGzFS{opener: opener}, nil
+}
+
+// lazyTarGzFS is the filesystem of NewLazyTarGzFS.
+type lazyTarGzFS struct {
+	opener func() (io.ReadCloser, error)
+
+	mu   sync.Mutex
+	fsys fs.FS // nil until the archive is read
+}
+
+// load returns the filesystem of the archive, reading it on first use.
+func (l *lazyTarGzFS) load() (fs.FS, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.fsys != nil {
+		return l.fsys, nil
+	}
+
+	rc, err := l.opener()
+	if err != nil {
+		return nil, fmt.Errorf("tar.gz: open: %w", err)
+	}
+	defer rc.Close()
+
+	fsys, err := NewTarGzFS(rc)
+	if err != nil {
+		return nil, err
+	}
+	l.fsys = fsys
+	return fsys, nil
+}
+
+// Open implements fs.FS.
+func (l *lazyTarGzFS) Open(name string) (fs.File, error) {
+	fsys, err := l.load()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return fsys.Open(name)
+}
+
+// Stat implements fs.StatFS.
+func (l *lazyTarGzFS) Stat(name string) (fs.FileInfo, error) {
+	fsys, err := l.load()
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return fs.Stat(fsys, name)
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (l *lazyTarGzFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	fsys, err := l.load()
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	return fs.ReadDir(fsys, name)
+}
+
+// ReadFile implements fs.ReadFileFS.
+func (l *lazyTarGzFS) ReadFile(name string) ([]byte, error) {
+	fsys, err := l.load()
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return fs.ReadFile(fsys, name)
+}
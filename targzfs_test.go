@@ -0,0 +1,146 @@
+package spaserver
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// tarGz returns a gzip-compressed tar archive of entries, in order.
+func tarGz(t *testing.T, entries ...tar.Header) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for _, hdr := range entries {
+		data := hdr.Linkname
+		if hdr.Typeflag == tar.TypeReg {
+			hdr.Size = int64(len(data))
+			hdr.Linkname = ""
+		}
+		if err := tw.WriteHeader(&hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			if _, err := io.WriteString(tw, data); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// buildArchive is a typical `tar czf build.tgz -C dist .` archive. The
+// content of regular files is given as their Linkname.
+func buildArchive(t *testing.T) []byte {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return tarGz(t,
+		tar.Header{Name: "./", Typeflag: tar.TypeDir, Mode: 0o755, ModTime: modTime},
+		tar.Header{Name: "./index.html", Typeflag: tar.TypeReg, Mode: 0o644, ModTime: modTime, Linkname: "<html>index</html>"},
+		tar.Header{Name: "./css/", Typeflag: tar.TypeDir, Mode: 0o755, ModTime: modTime},
+		tar.Header{Name: "./css/main.css", Typeflag: tar.TypeReg, Mode: 0o644, ModTime: modTime, Linkname: "body {}"},
+		tar.Header{Name: "./latest.css", Typeflag: tar.TypeSymlink, Linkname: "css/main.css"},
+	)
+}
+
+func TestNewTarGzFS(t *testing.T) {
+	fsys, err := NewTarGzFS(bytes.NewReader(buildArchive(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(fsys, "index.html", "css/main.css"); err != nil {
+		t.Error(err)
+	}
+
+	h, err := New(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt := []struct {
+		url  string
+		body string
+	}{
+		{url: "/", body: "<html>index</html>"},
+		{url: "/css/main.css", body: "body {}"},
+		{url: "/latest.css", body: "<html>index</html>"},
+	}
+	for _, tc := range tt {
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+tc.url, nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if got := w.Body.String(); got != tc.body {
+			t.Errorf("%s: body expected: %q, got: %q", tc.url, tc.body, got)
+		}
+	}
+}
+
+func TestNewTarGzFSErrors(t *testing.T) {
+	tt := []struct {
+		name    string
+		archive []byte
+		err     string
+	}{
+		{name: "not gzip", archive: []byte("this is not a gzip stream"), err: "tar.gz: gzip: invalid header"},
+		{name: "parent path", archive: tarGz(t, tar.Header{Name: "../evil.js", Typeflag: tar.TypeReg, Mode: 0o644}), err: `tar.gz: "../evil.js": insecure path`},
+		{name: "absolute path", archive: tarGz(t, tar.Header{Name: "/etc/evil.js", Typeflag: tar.TypeReg, Mode: 0o644}), err: `tar.gz: "/etc/evil.js": insecure path`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewTarGzFS(bytes.NewReader(tc.archive))
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("error expected to contain %q, got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestNewLazyTarGzFS(t *testing.T) {
+	archive := buildArchive(t)
+	var opens int
+	fail := true
+	fsys, err := NewLazyTarGzFS(func() (io.ReadCloser, error) {
+		opens++
+		if fail {
+			return nil, errors.New("artifact store unavailable")
+		}
+		return io.NopCloser(bytes.NewReader(archive)), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opens != 0 {
+		t.Errorf("opens expected before first use: %d, got: %d", 0, opens)
+	}
+
+	if _, err := fsys.Open("index.html"); err == nil || !strings.Contains(err.Error(), "artifact store unavailable") {
+		t.Errorf("error expected to contain %q, got: %v", "artifact store unavailable", err)
+	}
+
+	fail = false
+	if err := fstest.TestFS(fsys, "index.html", "css/main.css"); err != nil {
+		t.Error(err)
+	}
+	if opens != 2 {
+		t.Errorf("opens expected: %d, got: %d", 2, opens)
+	}
+
+	if _, err := NewLazyTarGzFS(nil); err == nil {
+		t.Error("expected an error for a nil opener")
+	}
+}
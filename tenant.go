@@ -0,0 +1,287 @@
+package spaserver
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// TenantResolver selects the filesystem of the SPA to serve for a request,
+// based on its hostname, a cookie, a JWT claim or any other property.
+type TenantResolver interface {
+	// Resolve returns the tenant's filesystem, or nil to serve the
+	// handler's own. An error wrapping fs.ErrNotExist answers the request
+	// with 404 Not Found, fs.ErrPermission with 403 Forbidden and any
+	// other error with 500 Internal Server Error.
+	Resolve(r *http.Request) (fs.FS, error)
+}
+
+// TenantResolverFunc adapts a function to a TenantResolver.
+type TenantResolverFunc func(r *http.Request) (fs.FS, error)
+
+// Resolve returns f(r).
+func (f TenantResolverFunc) Resolve(r *http.Request) (fs.FS, error) {
+	return f(r)
+}
+
+// TenantKeyer is implemented by a TenantResolver that can identify a
+// request's tenant without resolving its filesystem. CachingTenantResolver
+// uses it as the cache key.
+type TenantKeyer interface {
+	TenantKey(r *http.Request) string
+}
+
+// WithTenantRouter serves each request from the filesystem returned by
+// resolver. Every filesystem is served as a separate SPA root with the
+// handler's options, and the state derived from it, such as a preloaded
+// index or webpack manifest, is loaded on its first request. Sites are kept
+// per tenant, identified by the TenantKey of a TenantKeyer resolver or the
+// request's hostname otherwise, and a site is dropped once its tenant
+// resolves to a different filesystem, so that a resolver refreshing a
+// tenant's build does not accumulate stale sites. A resolver should return
+// the same filesystem value for a tenant each time. Filesystems are told
+// apart by value if it is comparable, such as a pointer or an os.DirFS,
+// and by identity if it is a map or slice, such as fstest.MapFS; requests
+// resolving to any other filesystem, such as a struct holding a map by
+// value, are answered with 500 Internal Server Error. At most 4096
+// tenants are kept. A/B variants only apply to requests served from the
+// handler's own filesystem.
+func WithTenantRouter(resolver TenantResolver) Option {
+	return func(c *Config) {
+		c.TenantResolver = resolver
+	}
+}
+
+// maxTenants bounds the tenants kept by WithTenantRouter and
+// CachingTenantResolver, whose default key, the hostname, is chosen by the
+// client. Beyond it, an arbitrary tenant is evicted.
+const maxTenants = 4096
+
+// tenantSites holds the loaded sites of the tenant filesystems.
+type tenantSites struct {
+	resolver TenantResolver
+
+	mu    sync.Mutex
+	keys  map[string]any      // tenant key -> filesystem identity
+	sites map[any]*tenantSite // filesystem identity -> site
+}
+
+// tenantSite is a loaded tenant site and the number of tenant keys
+// resolving to it.
+type tenantSite struct {
+	site *site
+	refs int
+}
+
+// newTenantSites returns the tenant sites of cfg, or nil if no resolver is
+// configured.
+func newTenantSites(cfg Config) *tenantSites {
+	if cfg.TenantResolver == nil {
+		return nil
+	}
+	return &tenantSites{
+		resolver: cfg.TenantResolver,
+		keys:     make(map[string]any),
+		sites:    make(map[any]*tenantSite),
+	}
+}
+
+// site returns the site of r's tenant, or nil if the resolver selected the
+// handler's own filesystem.
+func (t *tenantSites) site(h *Handler, r *http.Request) (*site, error) {
+	fsys, err := t.resolver.Resolve(r)
+	if err != nil || fsys == nil {
+		return nil, err
+	}
+
+	id, ok := fsIdentity(fsys)
+	if !ok {
+		return nil, fmt.Errorf("tenant filesystem of type %T cannot be identified", fsys)
+	}
+	key := tenantKey(t.resolver, r)
+
+	t.mu.Lock()
+	if ts, ok := t.sites[id]; ok {
+		t.bind(key, id)
+		t.mu.Unlock()
+		return ts.site, nil
+	}
+	t.mu.Unlock()
+
+	s, err := h.load(fsys)
+	if err != nil {
+		h.cfg.Logger.Warn("spaserver: tenant filesystem state not loaded", "error", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// Keep the site stored first by concurrent requests
+	ts, ok := t.sites[id]
+	if ok {
+		s.close()
+	} else {
+		ts = &tenantSite{site: s}
+		t.sites[id] = ts
+	}
+	t.bind(key, id)
+	return ts.site, nil
+}
+
+// bind points the tenant key at the site of the filesystem identity id,
+// dropping the site it pointed at before if no other tenant uses it. t.mu
+// must be held.
+func (t *tenantSites) bind(key string, id any) {
+	old, bound := t.keys[key]
+	if bound && old == id {
+		return
+	}
+	t.sites[id].refs++
+	if bound {
+		t.release(old)
+	} else if len(t.keys) >= maxTenants {
+		for k, other := range t.keys {
+			delete(t.keys, k)
+			t.release(other)
+			break
+		}
+	}
+	t.keys[key] = id
+}
+
+// release drops a tenant key's reference to the site of id. t.mu must be
+// held.
+func (t *tenantSites) release(id any) {
+	ts := t.sites[id]
+	if ts.refs--; ts.refs <= 0 {
+		delete(t.sites, id)
+		ts.site.close()
+	}
+}
+
+// fsPointer identifies a filesystem of map or slice type, such as
+// fstest.MapFS, whose values cannot be compared.
+type fsPointer struct {
+	typ reflect.Type
+	ptr uintptr
+	len int
+}
+
+// fsIdentity returns a comparable value identifying fsys: fsys itself if
+// its dynamic value is comparable, or its type and data pointer if it is a
+// map or slice. It returns false for any other filesystem, such as a
+// struct, array or interface value holding a map, slice or function.
+func fsIdentity(fsys fs.FS) (any, bool) {
+	v := reflect.ValueOf(fsys)
+	if v.Comparable() {
+		return fsys, true
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		return fsPointer{typ: v.Type(), ptr: v.Pointer()}, true
+	case reflect.Slice:
+		return fsPointer{typ: v.Type(), ptr: v.Pointer(), len: v.Len()}, true
+	}
+	return nil, false
+}
+
+// tenantKey returns the key of r's tenant: the TenantKey of resolver if it
+// implements TenantKeyer, and the request's hostname otherwise.
+func tenantKey(resolver TenantResolver, r *http.Request) string {
+	if k, ok := resolver.(TenantKeyer); ok {
+		return k.TenantKey(r)
+	}
+	return normalizeHost(r.Host)
+}
+
+// serveTenantError answers a request whose tenant could not be resolved.
+func serveTenantError(cfg Config, w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		serveError(w, "404 Page Not Found", http.StatusNotFound)
+	case errors.Is(err, fs.ErrPermission):
+		serveError(w, "403 Forbidden", http.StatusForbidden)
+	default:
+		cfg.Logger.Error("spaserver: tenant not resolved", "error", err)
+		serveError(w, "500 Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// CachingTenantResolver returns a TenantResolver that caches the
+// filesystems returned by r for ttl, so that an expensive lookup, such as
+// a database query or an artifact download, runs once per tenant and ttl.
+// Filesystems are cached by tenant key: the TenantKey of r if it implements
+// TenantKeyer, and the request's hostname otherwise. Errors are not
+// cached. At most 4096 tenants are cached; expired entries are evicted
+// first.
+func CachingTenantResolver(r TenantResolver, ttl time.Duration) TenantResolver {
+	return &cachingTenantResolver{resolver: r, ttl: ttl, entries: make(map[string]tenantEntry)}
+}
+
+// tenantEntry is a cached tenant filesystem.
+type tenantEntry struct {
+	fsys    fs.FS
+	expires time.Time
+}
+
+// cachingTenantResolver is the TenantResolver of CachingTenantResolver.
+type cachingTenantResolver struct {
+	resolver TenantResolver
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	entries map[string]tenantEntry
+}
+
+// Resolve returns the cached filesystem of r's tenant, resolving it on a
+// miss or once it has expired.
+func (c *cachingTenantResolver) Resolve(r *http.Request) (fs.FS, error) {
+	key := c.TenantKey(r)
+
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && time.Now().Before(e.expires) {
+		return e.fsys, nil
+	}
+
+	fsys, err := c.resolver.Resolve(r)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= maxTenants {
+		c.evict(now)
+	}
+	c.entries[key] = tenantEntry{fsys: fsys, expires: now.Add(c.ttl)}
+	c.mu.Unlock()
+	return fsys, nil
+}
+
+// evict makes room for an entry by removing the expired entries, or an
+// arbitrary one if none has expired. c.mu must be held.
+func (c *cachingTenantResolver) evict(now time.Time) {
+	for key, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, key)
+		}
+	}
+	if len(c.entries) < maxTenants {
+		return
+	}
+	for key := range c.entries {
+		delete(c.entries, key)
+		return
+	}
+}
+
+// TenantKey implements TenantKeyer, so that WithTenantRouter keys its sites
+// like the cache.
+func (c *cachingTenantResolver) TenantKey(r *http.Request) string {
+	return tenantKey(c.resolver, r)
+}
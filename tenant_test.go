@@ -0,0 +1,267 @@
+package spaserver
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestServeWithTenantRouter(t *testing.T) {
+	primary := fstest.MapFS{"index.html": {Data: []byte("index primary")}}
+	tenants := map[string]fs.FS{
+		"a.example.com": &countFS{FS: fstest.MapFS{
+			"index.html": {Data: []byte("index a")},
+			"js/app.js":  {Data: []byte("app a")},
+		}},
+		"b.example.com": fstest.MapFS{"index.html": {Data: []byte("index b")}},
+	}
+	resolver := TenantResolverFunc(func(r *http.Request) (fs.FS, error) {
+		switch r.Host {
+		case "primary.example.com":
+			return nil, nil
+		case "denied.example.com":
+			return nil, fs.ErrPermission
+		case "broken.example.com":
+			return nil, errors.New("tenant store unavailable")
+		}
+		if fsys, ok := tenants[r.Host]; ok {
+			return fsys, nil
+		}
+		return nil, fs.ErrNotExist
+	})
+	h := Serve(primary, WithTenantRouter(resolver))
+
+	tt := []struct {
+		name   string
+		url    string
+		status int
+		body   string
+	}{
+		{name: "tenant index", url: "http://a.example.com/", status: http.StatusOK, body: "index a"},
+		{name: "tenant fallback", url: "http://a.example.com/app/route", status: http.StatusOK, body: "index a"},
+		{name: "tenant file", url: "http://a.example.com/js/app.js", status: http.StatusOK, body: "app a"},
+		{name: "uncomparable filesystem", url: "http://b.example.com/js/app.js", status: http.StatusOK, body: "index b"},
+		{name: "primary", url: "http://primary.example.com/", status: http.StatusOK, body: "index primary"},
+		{name: "unknown tenant", url: "http://c.example.com/", status: http.StatusNotFound},
+		{name: "forbidden tenant", url: "http://denied.example.com/", status: http.StatusForbidden},
+		{name: "resolver error", url: "http://broken.example.com/", status: http.StatusInternalServerError},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("status expected: %d, got: %d", tc.status, w.Code)
+			}
+			if tc.body != "" && w.Body.String() != tc.body {
+				t.Errorf("body expected: %q, got: %q", tc.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestServeWithTenantRouterLoadsOnce(t *testing.T) {
+	fsys := &countFS{FS: fstest.MapFS{"index.html": {Data: []byte("index a")}}}
+	resolver := TenantResolverFunc(func(r *http.Request) (fs.FS, error) {
+		return fsys, nil
+	})
+	h := Serve(fstest.MapFS{"index.html": {Data: []byte("index primary")}}, WithTenantRouter(resolver), WithIndexPreload())
+
+	for range 3 {
+		r := httptest.NewRequest(http.MethodGet, "http://a.example.com/", nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if got := w.Body.String(); got != "index a" {
+			t.Errorf("body expected: %q, got: %q", "index a", got)
+		}
+	}
+	// Loading the site checks and preloads index.html once
+	if got := fsys.opens.Load(); got != 2 {
+		t.Errorf("opens expected: %d, got: %d", 2, got)
+	}
+}
+
+func TestTenantRouterEvictsRefreshedSites(t *testing.T) {
+	resolver := TenantResolverFunc(func(r *http.Request) (fs.FS, error) {
+		// A new filesystem value for every request, like a refreshed build
+		return &countFS{FS: fstest.MapFS{"index.html": {Data: []byte("index " + r.Host)}}}, nil
+	})
+	h := Serve(fstest.MapFS{"index.html": {Data: []byte("index primary")}}, WithTenantRouter(resolver))
+
+	for _, host := range []string{"a.example.com", "a.example.com", "b.example.com", "a.example.com"} {
+		r := httptest.NewRequest(http.MethodGet, "http://"+host+"/", nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if want := "index " + host; w.Body.String() != want {
+			t.Errorf("body expected: %q, got: %q", want, w.Body.String())
+		}
+	}
+	if got := len(h.tenants.sites); got != 2 {
+		t.Errorf("sites expected: %d, got: %d", 2, got)
+	}
+}
+
+func TestTenantRouterCachesMapFS(t *testing.T) {
+	fsys := fstest.MapFS{"index.html": {Data: []byte("index a")}}
+	resolver := TenantResolverFunc(func(r *http.Request) (fs.FS, error) {
+		return fsys, nil
+	})
+	h := Serve(fstest.MapFS{"index.html": {Data: []byte("index primary")}}, WithTenantRouter(resolver), WithIndexPreload())
+
+	for range 2 {
+		r := httptest.NewRequest(http.MethodGet, "http://a.example.com/", nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if got := w.Body.String(); got != "index a" {
+			t.Errorf("body expected: %q, got: %q", "index a", got)
+		}
+		// A reload would preload the changed index
+		fsys["index.html"] = &fstest.MapFile{Data: []byte("index changed")}
+	}
+}
+
+// structFS is a filesystem whose values cannot be compared or identified.
+type structFS struct {
+	fstest.MapFS
+}
+
+func TestTenantRouterRejectsUnidentifiableFS(t *testing.T) {
+	resolver := TenantResolverFunc(func(r *http.Request) (fs.FS, error) {
+		return structFS{fstest.MapFS{"index.html": {Data: []byte("index a")}}}, nil
+	})
+	var logs bytes.Buffer
+	h := Serve(fstest.MapFS{"index.html": {Data: []byte("index primary")}},
+		WithTenantRouter(resolver), WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+
+	r := httptest.NewRequest(http.MethodGet, "http://a.example.com/", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status expected: %d, got: %d", http.StatusInternalServerError, w.Code)
+	}
+	if !strings.Contains(logs.String(), "cannot be identified") {
+		t.Errorf("logs expected to report the filesystem, got: %q", logs.String())
+	}
+}
+
+// keyedResolver resolves tenants by the X-Tenant header and counts calls.
+type keyedResolver struct {
+	calls atomic.Int64
+}
+
+func (k *keyedResolver) Resolve(r *http.Request) (fs.FS, error) {
+	k.calls.Add(1)
+	return fstest.MapFS{"index.html": {Data: []byte("index " + r.Header.Get("X-Tenant"))}}, nil
+}
+
+func (k *keyedResolver) TenantKey(r *http.Request) string {
+	return r.Header.Get("X-Tenant")
+}
+
+func TestCachingTenantResolver(t *testing.T) {
+	var calls atomic.Int64
+	hostResolver := TenantResolverFunc(func(r *http.Request) (fs.FS, error) {
+		calls.Add(1)
+		if r.Host == "missing.example.com" {
+			return nil, fs.ErrNotExist
+		}
+		return fstest.MapFS{"index.html": {Data: []byte("index " + r.Host)}}, nil
+	})
+	c := CachingTenantResolver(hostResolver, 50*time.Millisecond)
+
+	resolve := func(host string) error {
+		r := httptest.NewRequest(http.MethodGet, "http://"+host+"/", nil)
+		_, err := c.Resolve(r)
+		return err
+	}
+
+	for _, host := range []string{"a.example.com", "A.example.com:8080", "a.example.com"} {
+		if err := resolve(host); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("calls expected: %d, got: %d", 1, got)
+	}
+
+	if err := resolve("b.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("calls expected: %d, got: %d", 2, got)
+	}
+
+	for range 2 {
+		if err := resolve("missing.example.com"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("error expected: %v, got: %v", fs.ErrNotExist, err)
+		}
+	}
+	if got := calls.Load(); got != 4 {
+		t.Errorf("calls expected: %d, got: %d", 4, got)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if err := resolve("a.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if got := calls.Load(); got != 5 {
+		t.Errorf("calls expected: %d, got: %d", 5, got)
+	}
+}
+
+func TestCachingTenantResolverKeyer(t *testing.T) {
+	k := &keyedResolver{}
+	c := CachingTenantResolver(k, time.Minute)
+
+	for i := range 4 {
+		r := httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil)
+		r.Header.Set("X-Tenant", fmt.Sprint("t", i%2))
+		fsys, err := c.Resolve(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := fs.ReadFile(fsys, "index.html")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprint("index t", i%2); string(b) != want {
+			t.Errorf("index expected: %q, got: %q", want, b)
+		}
+	}
+	if got := k.calls.Load(); got != 2 {
+		t.Errorf("calls expected: %d, got: %d", 2, got)
+	}
+}
+
+func TestCachingTenantResolverBounded(t *testing.T) {
+	resolver := TenantResolverFunc(func(r *http.Request) (fs.FS, error) {
+		return fstest.MapFS{}, nil
+	})
+	c := CachingTenantResolver(resolver, time.Minute).(*cachingTenantResolver)
+
+	for i := range maxTenants + 10 {
+		r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("http://t%d.example.com/", i), nil)
+		if _, err := c.Resolve(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := len(c.entries); got > maxTenants {
+		t.Errorf("entries expected at most: %d, got: %d", maxTenants, got)
+	}
+}
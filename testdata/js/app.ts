@@ -0,0 +1 @@
+export const app: string = "app";
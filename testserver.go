@@ -0,0 +1,54 @@
+package spaserver
+
+import (
+	"io/fs"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// NewTestServer starts an httptest.Server serving an SPA built from files,
+// a map of relative paths to file contents. The server is closed when the
+// test finishes.
+func NewTestServer(t testing.TB, files map[string]string, opts ...Option) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(Serve(NewMockFS(files), opts...))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// NewMockFS returns an in-memory filesystem built from files, a map of paths
+// to file contents. Keys may use either slash direction and a leading slash;
+// they are normalised to fs.FS paths. Parent directories are created
+// automatically.
+func NewMockFS(files map[string]string) fs.FS {
+	fsys := make(fstest.MapFS, len(files))
+	for name, content := range files {
+		name = strings.ReplaceAll(name, "\\", "/")
+		name = strings.TrimPrefix(path.Clean("/"+name), "/")
+		if name == "" {
+			continue
+		}
+
+		fsys[name] = &fstest.MapFile{Data: []byte(content), Mode: 0o644}
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if _, ok := fsys[dir]; ok {
+				break
+			}
+			fsys[dir] = &fstest.MapFile{Mode: fs.ModeDir | 0o755}
+		}
+	}
+	return fsys
+}
+
+// NewMockFSWithIndex is like NewMockFS but always includes an index.html
+// with the given content. An index.html entry in extras is overridden.
+func NewMockFSWithIndex(indexContent string, extras map[string]string) fs.FS {
+	fsys := NewMockFS(extras).(fstest.MapFS)
+	fsys[indexPage] = &fstest.MapFile{Data: []byte(indexContent), Mode: 0o644}
+	return fsys
+}
@@ -0,0 +1,103 @@
+package spaserver
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestNewTestServer(t *testing.T) {
+	srv := NewTestServer(t, map[string]string{
+		"index.html":   "index.html",
+		"css/main.css": "body {}",
+	}, WithCSP("default-src 'none'"))
+
+	tt := []struct {
+		name string
+		path string
+		body string
+		csp  string
+	}{
+		{name: "root serves index", path: "/", body: "index.html", csp: "default-src 'none'"},
+		{name: "static file served", path: "/css/main.css", body: "body {}"},
+		{name: "unknown path serves index", path: "/about", body: "index.html", csp: "default-src 'none'"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := srv.Client().Get(srv.URL + tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.StatusCode != http.StatusOK {
+				t.Errorf("statusCode expected: 200, got: %d", res.StatusCode)
+			}
+			if string(body) != tc.body {
+				t.Errorf("body expected: %s, got: %s", tc.body, body)
+			}
+			if got := res.Header.Get("Content-Security-Policy"); got != tc.csp {
+				t.Errorf("Content-Security-Policy expected: %q, got: %q", tc.csp, got)
+			}
+		})
+	}
+}
+
+func TestNewMockFS(t *testing.T) {
+	fsys := NewMockFS(map[string]string{
+		"index.html":           "index.html",
+		"/css/main.css":        "body {}",
+		`assets\img\logo.svg`:  "<svg/>",
+		"js//vendor/../app.js": "app",
+	})
+
+	if err := fstest.TestFS(fsys, "index.html", "css/main.css", "assets/img/logo.svg", "js/app.js"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dir := range []string{"css", "assets", "assets/img", "js"} {
+		fi, err := fs.Stat(fsys, dir)
+		if err != nil {
+			t.Fatalf("expected directory %s: %v", dir, err)
+		}
+		if !fi.IsDir() {
+			t.Errorf("expected %s to be a directory", dir)
+		}
+	}
+}
+
+func TestNewMockFSWithIndex(t *testing.T) {
+	fsys := NewMockFSWithIndex("<h1>app</h1>", map[string]string{
+		"/index.html": "stale",
+		"js/app.js":   "app",
+	})
+
+	b, err := fs.ReadFile(fsys, "index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "<h1>app</h1>" {
+		t.Errorf("index.html expected: %q, got: %q", "<h1>app</h1>", b)
+	}
+
+	h := Serve(fsys)
+	r, err := http.NewRequest(http.MethodGet, "http://www.example.com/js/app.js", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Body.String() != "app" {
+		t.Errorf("body expected: app, got: %s", w.Body.String())
+	}
+}
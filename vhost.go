@@ -0,0 +1,109 @@
+package spaserver
+
+import (
+	"io/fs"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// VirtualHostMux dispatches requests to SPAs by the Host header, so one
+// server can serve a different SPA, with its own options, for each domain.
+// The zero value is an empty mux ready to use.
+type VirtualHostMux struct {
+	mu        sync.RWMutex
+	hosts     map[string]http.Handler
+	wildcards []wildcardHost
+	def       http.Handler
+}
+
+// wildcardHost is a "*.example.com" host pattern stored by its suffix,
+// ".example.com".
+type wildcardHost struct {
+	suffix  string
+	handler http.Handler
+}
+
+// NewVirtualHostMux returns an empty VirtualHostMux.
+func NewVirtualHostMux() *VirtualHostMux {
+	return &VirtualHostMux{}
+}
+
+// Mount serves the SPA in fsys for requests to hostname. A hostname of the
+// form "*.example.com" matches every subdomain of example.com, but not
+// example.com itself; exact hostnames and then longer wildcards take
+// precedence. Hostnames are matched case-insensitively, without the port.
+// Mounting a hostname again replaces it.
+func (m *VirtualHostMux) Mount(hostname string, fsys fs.FS, opts ...Option) *VirtualHostMux {
+	h := Serve(fsys, opts...)
+	hostname = normalizeHost(hostname)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if suffix, ok := strings.CutPrefix(hostname, "*"); ok {
+		m.wildcards = slices.DeleteFunc(m.wildcards, func(wh wildcardHost) bool {
+			return wh.suffix == suffix
+		})
+		m.wildcards = append(m.wildcards, wildcardHost{suffix: suffix, handler: h})
+		sort.SliceStable(m.wildcards, func(i, j int) bool {
+			return len(m.wildcards[i].suffix) > len(m.wildcards[j].suffix)
+		})
+		return m
+	}
+
+	if m.hosts == nil {
+		m.hosts = make(map[string]http.Handler)
+	}
+	m.hosts[hostname] = h
+	return m
+}
+
+// Default serves the SPA in fsys for requests that match no mounted host.
+// Without a default, such requests receive 404 Not Found.
+func (m *VirtualHostMux) Default(fsys fs.FS, opts ...Option) *VirtualHostMux {
+	h := Serve(fsys, opts...)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.def = h
+	return m
+}
+
+// ServeHTTP dispatches the request to the SPA mounted for its host.
+func (m *VirtualHostMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h := m.handler(r.Host); h != nil {
+		h.ServeHTTP(w, r)
+		return
+	}
+	serveError(w, "404 page not found", http.StatusNotFound)
+}
+
+// handler returns the handler for host, or nil.
+func (m *VirtualHostMux) handler(host string) http.Handler {
+	host = normalizeHost(host)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if h, ok := m.hosts[host]; ok {
+		return h
+	}
+	for _, wh := range m.wildcards {
+		if strings.HasSuffix(host, wh.suffix) {
+			return wh.handler
+		}
+	}
+	return m.def
+}
+
+// normalizeHost lowercases host and strips its port and trailing dot.
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
@@ -0,0 +1,60 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVirtualHostMux(t *testing.T) {
+	mux := NewVirtualHostMux().
+		Mount("app.example.com", NewMockFSWithIndex("app", nil)).
+		Mount("*.example.com", NewMockFSWithIndex("tenant", nil), WithCSP("default-src 'none'")).
+		Mount("*.eu.example.com", NewMockFSWithIndex("eu", nil)).
+		Mount("Admin.Example.com", NewMockFSWithIndex("admin", nil)).
+		Default(NewMockFSWithIndex("default", nil))
+
+	tt := []struct {
+		name string
+		host string
+		body string
+		csp  string
+	}{
+		{name: "exact host", host: "app.example.com", body: "app", csp: "default-src 'self'"},
+		{name: "host with port", host: "app.example.com:8080", body: "app", csp: "default-src 'self'"},
+		{name: "case insensitive", host: "ADMIN.example.com", body: "admin", csp: "default-src 'self'"},
+		{name: "wildcard host", host: "acme.example.com", body: "tenant", csp: "default-src 'none'"},
+		{name: "nested wildcard host", host: "a.b.example.com", body: "tenant", csp: "default-src 'none'"},
+		{name: "longest wildcard", host: "acme.eu.example.com", body: "eu", csp: "default-src 'self'"},
+		{name: "wildcard excludes apex", host: "example.com", body: "default", csp: "default-src 'self'"},
+		{name: "default host", host: "other.org", body: "default", csp: "default-src 'self'"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://"+tc.host+"/dashboard", nil)
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if got := w.Body.String(); got != tc.body {
+				t.Errorf("body expected: %q, got: %q", tc.body, got)
+			}
+			if got := w.Header().Get("Content-Security-Policy"); got != tc.csp {
+				t.Errorf("Content-Security-Policy expected: %q, got: %q", tc.csp, got)
+			}
+		})
+	}
+}
+
+func TestVirtualHostMuxNoDefault(t *testing.T) {
+	var mux VirtualHostMux
+	mux.Mount("app.example.com", NewMockFSWithIndex("app", nil))
+
+	r := httptest.NewRequest(http.MethodGet, "http://other.org/", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status expected: %d, got: %d", http.StatusNotFound, w.Code)
+	}
+}
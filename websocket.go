@@ -0,0 +1,21 @@
+package spaserver
+
+import "net/http"
+
+// WithWebSocketPassthrough forwards WebSocket upgrade requests, those with
+// Connection: Upgrade and Upgrade: websocket headers, to handler unchanged,
+// so a WebSocket backend sharing the server never receives the index page
+// in place of a handshake. They are subject to the IP filter and basic
+// auth but otherwise bypass all SPA handling. The DevMode live-reload
+// endpoint is not forwarded.
+func WithWebSocketPassthrough(handler http.Handler) Option {
+	return func(c *Config) {
+		c.WebSocketHandler = handler
+	}
+}
+
+// isWebSocketUpgrade reports whether r asks to upgrade to a WebSocket.
+func isWebSocketUpgrade(r *http.Request) bool {
+	return headerContainsToken(r.Header, "Connection", "upgrade") &&
+		headerContainsToken(r.Header, "Upgrade", "websocket")
+}
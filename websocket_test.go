@@ -0,0 +1,106 @@
+package spaserver
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"golang.org/x/net/websocket"
+)
+
+func TestServeWithWebSocketPassthrough(t *testing.T) {
+	fsys := fstest.MapFS{"index.html": {Data: []byte("<html>index</html>")}}
+	echo := websocket.Handler(func(conn *websocket.Conn) {
+		io.Copy(conn, conn)
+	})
+	srv := httptest.NewServer(Serve(fsys, WithWebSocketPassthrough(echo), WithGzip()))
+	defer srv.Close()
+
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/socket", "", srv.URL)
+	if err != nil {
+		t.Fatalf("handshake expected to succeed, got: %v", err)
+	}
+	defer conn.Close()
+
+	if err := websocket.Message.Send(conn, "ping"); err != nil {
+		t.Fatal(err)
+	}
+	var msg string
+	if err := websocket.Message.Receive(conn, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg != "ping" {
+		t.Errorf("message expected: %q, got: %q", "ping", msg)
+	}
+
+	// Plain requests to the same path still get the SPA
+	resp, err := http.Get(srv.URL + "/socket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "<html>index</html>" {
+		t.Errorf("body expected: %q, got: %q", "<html>index</html>", body)
+	}
+}
+
+func TestWebSocketPassthroughSkipsDevReload(t *testing.T) {
+	var called bool
+	ws := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+	h := DevMode(fstest.MapFS{"index.html": {Data: []byte("index.html")}}, WithWebSocketPassthrough(ws))
+
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+devReloadPath, nil)
+	r.Header.Set("Connection", "Upgrade")
+	r.Header.Set("Upgrade", "websocket")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	if called {
+		t.Error("live-reload request expected not to be forwarded")
+	}
+}
+
+func TestWebSocketPassthroughAccessControl(t *testing.T) {
+	ws := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusSwitchingProtocols)
+	})
+	h := Serve(fstest.MapFS{"index.html": {Data: []byte("index.html")}},
+		WithWebSocketPassthrough(ws),
+		WithIPBlocklist("192.0.2.0/24"),
+		WithBasicAuthFunc("chat", func(user, pass string) (bool, error) { return user == "admin" && pass == "s3cret", nil }),
+	)
+
+	tt := []struct {
+		name       string
+		remoteAddr string
+		auth       bool
+		status     int
+	}{
+		{name: "authorized", remoteAddr: "203.0.113.7:1234", auth: true, status: http.StatusSwitchingProtocols},
+		{name: "blocked", remoteAddr: "192.0.2.1:1234", auth: true, status: http.StatusForbidden},
+		{name: "unauthenticated", remoteAddr: "203.0.113.7:1234", status: http.StatusUnauthorized},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com/socket", nil)
+			r.RemoteAddr = tc.remoteAddr
+			r.Header.Set("Connection", "Upgrade")
+			r.Header.Set("Upgrade", "websocket")
+			if tc.auth {
+				r.SetBasicAuth("admin", "s3cret")
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("status expected: %d, got: %d", tc.status, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package spaserver
+
+import (
+	"archive/zip"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// zipFS serves the members of a zip archive.
+type zipFS struct {
+	ra    io.ReaderAt
+	zr    *zip.Reader
+	files map[string]*zip.File // regular files by name
+}
+
+// NewZipFS returns a filesystem serving the zip archive of size bytes read
+// from r, so that a build can be deployed as a single file. Members are
+// read from r on demand rather than extracted. Members stored without
+// compression are opened as io.ReadSeekers reading directly from r, which
+// lets range requests and content sniffing avoid buffering them; deflated
+// members are decompressed when opened. ZIP64 archives, needed beyond
+// 4 GB or 65535 members, are supported.
+//
+// The archive is typically an *os.File, or a *bytes.Reader over an
+// embedded zip:
+//
+//	f, err := os.Open("dist.zip")
+//	...
+//	fi, err := f.Stat()
+//	...
+//	fsys, err := spaserver.NewZipFS(f, fi.Size())
+func NewZipFS(r io.ReaderAt, size int64) (fs.FS, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	z := &zipFS{ra: r, zr: zr, files: make(map[string]*zip.File, len(zr.File))}
+	for _, f := range zr.File {
+		if strings.HasSuffix(f.Name, "/") || !fs.ValidPath(f.Name) {
+			continue
+		}
+		if _, ok := z.files[f.Name]; !ok {
+			z.files[f.Name] = f
+		}
+	}
+	return z, nil
+}
+
+// Open implements fs.FS.
+func (z *zipFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	f, ok := z.files[name]
+	if !ok || f.Method != zip.Store {
+		return z.zr.Open(name)
+	}
+	off, err := f.DataOffset()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &zipStoredFile{
+		SectionReader: io.NewSectionReader(z.ra, off, int64(f.UncompressedSize64)),
+		info:          f.FileInfo(),
+	}, nil
+}
+
+// Stat implements fs.StatFS.
+func (z *zipFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	if f, ok := z.files[name]; ok {
+		return f.FileInfo(), nil
+	}
+
+	// Directories, which may only be implied by the names of their members
+	f, err := z.zr.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Stat()
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (z *zipFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(z.zr, name)
+}
+
+// zipStoredFile is an uncompressed zip member read directly from the
+// archive.
+type zipStoredFile struct {
+	*io.SectionReader
+	info fs.FileInfo
+}
+
+// Stat implements fs.File.
+func (f *zipStoredFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+// Close implements fs.File.
+func (f *zipStoredFile) Close() error {
+	return nil
+}
@@ -0,0 +1,111 @@
+package spaserver
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+// zipArchive returns a zip archive of files, stored uncompressed when
+// method is zip.Store and deflated otherwise.
+func zipArchive(t *testing.T, files map[string]string, method uint16) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestNewZipFS(t *testing.T) {
+	files := map[string]string{
+		"index.html":   "<html>index</html>",
+		"css/main.css": "body {}",
+	}
+
+	for _, method := range []uint16{zip.Store, zip.Deflate} {
+		ra := zipArchive(t, files, method)
+		fsys, err := NewZipFS(ra, ra.Size())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := fstest.TestFS(fsys, "index.html", "css/main.css"); err != nil {
+			t.Errorf("method %d: %v", method, err)
+		}
+
+		h, err := New(fsys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for url, body := range map[string]string{"/": files["index.html"], "/css/main.css": files["css/main.css"], "/app/route": files["index.html"]} {
+			r := httptest.NewRequest(http.MethodGet, "http://www.example.com"+url, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("method %d: %s: status expected: %d, got: %d", method, url, http.StatusOK, w.Code)
+			}
+			if got := w.Body.String(); got != body {
+				t.Errorf("method %d: %s: body expected: %q, got: %q", method, url, body, got)
+			}
+		}
+	}
+}
+
+func TestNewZipFSStoredSeeker(t *testing.T) {
+	ra := zipArchive(t, map[string]string{"index.html": "index", "js/app.js": "0123456789"}, zip.Store)
+	fsys, err := NewZipFS(ra, ra.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := fsys.Open("js/app.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	seeker, ok := f.(io.ReadSeeker)
+	if !ok {
+		t.Fatalf("stored member expected to implement io.ReadSeeker, got: %T", f)
+	}
+	if _, err := seeker.Seek(4, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(seeker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "456789" {
+		t.Errorf("content expected: %q, got: %q", "456789", b)
+	}
+
+	h := Serve(fsys)
+	r := httptest.NewRequest(http.MethodGet, "http://www.example.com/js/app.js", nil)
+	r.Header.Set("Range", "bytes=2-4")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "234" {
+		t.Errorf("range expected: %d %q, got: %d %q", http.StatusPartialContent, "234", w.Code, w.Body.String())
+	}
+}
+
+func TestNewZipFSInvalid(t *testing.T) {
+	ra := bytes.NewReader([]byte("not a zip archive"))
+	if _, err := NewZipFS(ra, ra.Size()); err == nil {
+		t.Error("expected an error for an invalid archive")
+	}
+}